@@ -0,0 +1,288 @@
+package cache
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// Eviction policies of a ByteCache
+const (
+	PolicyLRU = "lru"
+	PolicyARC = "arc"
+)
+
+// ByteCache keeps encoded images in memory up to a budget of bytes. With PolicyLRU the least
+// recently used entries are evicted first. PolicyARC keeps recently and frequently used entries in
+// two lists and adapts their share of the budget to the requests, so that a scan over many
+// thumbnails does not push out the ones that are viewed again and again.
+type ByteCache struct {
+	mu     sync.Mutex
+	policy evictionPolicy
+	budget int64
+	group  singleflight.Group
+
+	hits      atomic.Int64
+	misses    atomic.Int64
+	evictions atomic.Int64
+}
+
+// CacheStats are the counters of a ByteCache
+type CacheStats struct {
+	Policy    string `json:"policy"`
+	Budget    int64  `json:"budget"`
+	Bytes     int64  `json:"bytes"`
+	Entries   int    `json:"entries"`
+	Hits      int64  `json:"hits"`
+	Misses    int64  `json:"misses"`
+	Evictions int64  `json:"evictions"`
+}
+
+// evictionPolicy stores the entries of a ByteCache, the cache locks around every call
+type evictionPolicy interface {
+	get(key string) ([]byte, bool)
+	// add stores an entry and returns the number of entries evicted to make room
+	add(key string, value []byte) int
+	bytes() int64
+	len() int
+	name() string
+}
+
+// NewByteCache returns a cache holding up to budget bytes, evicting with policy
+func NewByteCache(policy string, budget int64) (*ByteCache, error) {
+	switch policy {
+	case PolicyLRU:
+		return &ByteCache{policy: newLRU(budget), budget: budget}, nil
+	case PolicyARC:
+		return &ByteCache{policy: newARC(budget), budget: budget}, nil
+	default:
+		return nil, fmt.Errorf("unknown cache policy: %s", policy)
+	}
+}
+
+// Get returns the cached value of a key
+func (c *ByteCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	value, ok := c.policy.get(key)
+	c.mu.Unlock()
+
+	if ok {
+		c.hits.Add(1)
+	} else {
+		c.misses.Add(1)
+	}
+	return value, ok
+}
+
+// Add stores a value, values larger than the budget are not kept
+func (c *ByteCache) Add(key string, value []byte) {
+	c.mu.Lock()
+	evicted := c.policy.add(key, value)
+	c.mu.Unlock()
+
+	c.evictions.Add(int64(evicted))
+}
+
+// GetOrLoad returns the cached value of a key or loads and stores it. Concurrent calls for a key
+// that is being loaded wait for that load instead of starting their own.
+func (c *ByteCache) GetOrLoad(key string, load func() ([]byte, error)) ([]byte, error) {
+	if value, ok := c.Get(key); ok {
+		return value, nil
+	}
+
+	value, err, _ := c.group.Do(key, func() (interface{}, error) {
+		value, err := load()
+		if err != nil {
+			return nil, err
+		}
+		c.Add(key, value)
+		return value, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.([]byte), nil
+}
+
+// Stats returns the counters and the size of the cache
+func (c *ByteCache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return CacheStats{
+		Policy:    c.policy.name(),
+		Budget:    c.budget,
+		Bytes:     c.policy.bytes(),
+		Entries:   c.policy.len(),
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		Evictions: c.evictions.Load(),
+	}
+}
+
+type cacheEntry struct {
+	key   string
+	value []byte // nil for the ghost entries of ARC
+	size  int64
+}
+
+// byteList is a list of entries, most recently used first, with their total size
+type byteList struct {
+	order *list.List
+	items map[string]*list.Element
+	size  int64
+}
+
+func newByteList() *byteList {
+	return &byteList{order: list.New(), items: make(map[string]*list.Element)}
+}
+
+func (l *byteList) pushFront(entry *cacheEntry) {
+	l.items[entry.key] = l.order.PushFront(entry)
+	l.size += entry.size
+}
+
+func (l *byteList) remove(key string) *cacheEntry {
+	element, ok := l.items[key]
+	if !ok {
+		return nil
+	}
+	entry := l.order.Remove(element).(*cacheEntry)
+	delete(l.items, key)
+	l.size -= entry.size
+	return entry
+}
+
+func (l *byteList) removeBack() *cacheEntry {
+	element := l.order.Back()
+	if element == nil {
+		return nil
+	}
+	return l.remove(element.Value.(*cacheEntry).key)
+}
+
+type lruPolicy struct {
+	budget  int64
+	entries *byteList
+}
+
+func newLRU(budget int64) *lruPolicy {
+	return &lruPolicy{budget: budget, entries: newByteList()}
+}
+
+func (p *lruPolicy) get(key string) ([]byte, bool) {
+	element, ok := p.entries.items[key]
+	if !ok {
+		return nil, false
+	}
+	p.entries.order.MoveToFront(element)
+	return element.Value.(*cacheEntry).value, true
+}
+
+func (p *lruPolicy) add(key string, value []byte) int {
+	p.entries.remove(key)
+	size := int64(len(value))
+	if size > p.budget {
+		return 0
+	}
+
+	evicted := 0
+	for p.entries.size+size > p.budget {
+		p.entries.removeBack()
+		evicted++
+	}
+	p.entries.pushFront(&cacheEntry{key: key, value: value, size: size})
+	return evicted
+}
+
+func (p *lruPolicy) bytes() int64 { return p.entries.size }
+func (p *lruPolicy) len() int     { return p.entries.order.Len() }
+func (p *lruPolicy) name() string { return PolicyLRU }
+
+// arcPolicy is ARC weighted by bytes: t1 holds entries seen once, t2 entries seen again, b1 and
+// b2 remember the keys recently evicted from them. A miss found in b1 grows target, the share of
+// the budget for t1, one found in b2 shrinks it.
+type arcPolicy struct {
+	budget         int64
+	target         int64
+	t1, t2, b1, b2 *byteList
+}
+
+func newARC(budget int64) *arcPolicy {
+	return &arcPolicy{budget: budget, t1: newByteList(), t2: newByteList(), b1: newByteList(), b2: newByteList()}
+}
+
+func (p *arcPolicy) get(key string) ([]byte, bool) {
+	entry := p.t1.remove(key)
+	if entry == nil {
+		entry = p.t2.remove(key)
+	}
+	if entry == nil {
+		return nil, false
+	}
+	p.t2.pushFront(entry)
+	return entry.value, true
+}
+
+func (p *arcPolicy) add(key string, value []byte) int {
+	size := int64(len(value))
+	entry := &cacheEntry{key: key, value: value, size: size}
+
+	seen := p.t1.remove(key) != nil || p.t2.remove(key) != nil
+	if size > p.budget {
+		return 0
+	}
+
+	switch {
+	case seen:
+		evicted := p.replace(size, false)
+		p.t2.pushFront(entry)
+		return evicted
+	case p.b1.remove(key) != nil:
+		p.target = min(p.budget, p.target+max(size, size*p.b2.size/max(p.b1.size, 1)))
+		evicted := p.replace(size, false)
+		p.t2.pushFront(entry)
+		return evicted
+	case p.b2.remove(key) != nil:
+		p.target = max(0, p.target-max(size, size*p.b1.size/max(p.b2.size, 1)))
+		evicted := p.replace(size, true)
+		p.t2.pushFront(entry)
+		return evicted
+	}
+
+	evicted := p.replace(size, false)
+	p.t1.pushFront(entry)
+
+	// The ghosts remember at most the budget for each list
+	for p.t1.size+p.b1.size > p.budget && p.b1.order.Len() > 0 {
+		p.b1.removeBack()
+	}
+	for p.t1.size+p.t2.size+p.b1.size+p.b2.size > 2*p.budget && p.b2.order.Len() > 0 {
+		p.b2.removeBack()
+	}
+	return evicted
+}
+
+// replace evicts until an entry of size fits the budget, from t1 while it is over its target
+func (p *arcPolicy) replace(size int64, inB2 bool) int {
+	evicted := 0
+	for p.t1.size+p.t2.size+size > p.budget {
+		var entry *cacheEntry
+		if p.t1.order.Len() > 0 && (p.t1.size > p.target || (inB2 && p.t1.size == p.target) || p.t2.order.Len() == 0) {
+			entry = p.t1.removeBack()
+			p.b1.pushFront(&cacheEntry{key: entry.key, size: entry.size})
+		} else {
+			entry = p.t2.removeBack()
+			p.b2.pushFront(&cacheEntry{key: entry.key, size: entry.size})
+		}
+		evicted++
+	}
+	return evicted
+}
+
+func (p *arcPolicy) bytes() int64 { return p.t1.size + p.t2.size }
+func (p *arcPolicy) len() int     { return p.t1.order.Len() + p.t2.order.Len() }
+func (p *arcPolicy) name() string { return PolicyARC }
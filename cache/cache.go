@@ -14,129 +14,6 @@ import (
 	"sync"
 )
 
-var folders = []string{
-
-	"/var/cloud/applications/PhotoKit/Assets/1/",
-	"/var/cloud/applications/PhotoKit/Assets/1/thumbnail",
-
-	"/var/cloud/applications/PhotoKit/Assets/2/",
-	"/var/cloud/applications/PhotoKit/Assets/2/thumbnail",
-
-	"/var/cloud/family/",
-	"/var/cloud/family/thumbnail",
-
-	"/var/cloud/people/",
-	"/var/cloud/people/thumbnail",
-
-	"/var/cloud/00-all/",
-	"/var/cloud/00-all/thumbnail/",
-
-	"/var/cloud/1/",
-	"/var/cloud/1/thumbnail/",
-
-	"/var/cloud/4/",
-	"/var/cloud/4/thumbnail/",
-
-	"/var/cloud/6/",
-	"/var/cloud/6/thumbnail/",
-
-	"/var/cloud/12/",
-	"/var/cloud/12/thumbnail/",
-
-	"/var/cloud/all/",
-	"/var/cloud/all/thumbnail/",
-
-	"/var/cloud/music/albums/",
-	"/var/cloud/music/albums/thumbnail/",
-
-	"/var/cloud/paris/",
-	"/var/cloud/paris/thumbnail/",
-
-	"/var/cloud/reynardlowell/",
-	"/var/cloud/reynardlowell/thumbnail/",
-
-	"/var/cloud/ikea/",
-	"/var/cloud/ikea/thumbnail/",
-
-	"/var/cloud/wallpaper/",
-
-	"/var/cloud/id/me/",
-	"/var/cloud/id/me/thumbnail",
-
-	"/var/cloud/ik/",
-	"/var/cloud/ik/thumbnail",
-
-	"/var/cloud/id/ali/",
-	"/var/cloud/id/ali2/",
-	"/var/cloud/id/trip/",
-	"/var/cloud/id/go/",
-
-	"/var/cloud/chat/movie/movie/",
-
-	"/var/cloud/chat/movie/animation/",
-
-	"/var/cloud/chat/movie/movie/thumbnail/",
-	"/var/cloud/chat/movie/animation/thumbnail/",
-
-	"/var/cloud/camera-sequrity/",
-	"/var/cloud/camera-sequrity/thumbnail/",
-
-	"/var/cloud/chat/pdf/",
-	"/var/cloud/chat/electronic/",
-	"/var/cloud/chat/map/",
-	"/var/cloud/chat/voice/",
-
-	"/var/cloud/chat/pdf/thumbnail/",
-	"/var/cloud/chat/electronic/thumbnail/",
-	"/var/cloud/chat/map/thumbnail/",
-	"/var/cloud/chat/voice/thumbnail/",
-
-	"/var/cloud/call2/",
-	"/var/cloud/call2/thumbnail/",
-
-	"/var/cloud/id/ali/thumbnail/",
-	"/var/cloud/id/ali2/thumbnail/",
-	"/var/cloud/id/face/thumbnail/",
-	"/var/cloud/id/trip/thumbnail/",
-	"/var/cloud/id/go/thumbnail/",
-
-	"/var/cloud/chat_users/",
-	"/var/cloud/chat_users/thumbnail/",
-
-	"/var/cloud/tinyhome/new/",
-	"/var/cloud/tinyhome/new/thumbnail/",
-
-	"/var/cloud/tinyhome/06/",
-	"/var/cloud/tinyhome/06/thumbnail/",
-
-	"/var/cloud/video/",
-	"/var/cloud/video/thumbnail/",
-
-	"/var/cloud/elec/",
-	"/var/cloud/elec//thumbnail/",
-	//----------------------------------------------------
-	"/var/cloud/00-instagram/razzle-photo/",
-	"/var/cloud/00-instagram/razzle-photo/thumbnail/",
-
-	"/var/cloud/00-instagram/ashtonhall/",
-	"/var/cloud/00-instagram/ashtonhall/thumbnail/",
-
-	"/var/cloud/00-instagram/lucaspopan/",
-	"/var/cloud/00-instagram/lucaspopan/thumbnail/",
-
-	"/var/cloud/00-instagram/razzle/",
-	"/var/cloud/00-instagram/razzle/thumbnail/",
-
-	"/var/cloud/00-instagram/nickloveswildlife/",
-	"/var/cloud/00-instagram/nickloveswildlife/thumbnail/",
-
-	"/var/cloud/00-instagram/video/",
-	"/var/cloud/00-instagram/video/thumbnail/",
-
-	"/var/cloud/00-instagram/mysaaat/",
-	"/var/cloud/00-instagram/mysaaat/thumbnail/",
-}
-
 var iconFolder = "/var/cloud/icons/"
 
 //func ReadOfFile(folder string, file string) []models.UIImage {
@@ -163,7 +40,7 @@ var iconFolder = "/var/cloud/icons/"
 
 func ReadIcons() {
 	// Specify the directory you want to read
-	dir := "/var/cloud/icons" // Change this to your target directory
+	dir := iconsPath // Change this to your target directory
 
 	// Read the directory entries
 	entries, err := os.ReadDir(dir)
@@ -182,35 +59,62 @@ func ReadIcons() {
 		}
 	}
 
-	fmt.Println(len(iconCache.cache))
+	fmt.Println(iconCache.Stats().Entries)
 }
 
-type ImageCache struct {
-	sync.RWMutex
-	cache map[string][]byte
-}
+// Default budgets of the caches until Configure sets them
+const (
+	defaultThumbCacheBytes = 256 << 20
+	defaultIconCacheBytes  = 32 << 20
+)
 
-var thumbCache = ImageCache{cache: make(map[string][]byte)}
+var (
+	thumbCache, _ = NewByteCache(PolicyLRU, defaultThumbCacheBytes)
+	iconCache, _  = NewByteCache(PolicyLRU, defaultIconCacheBytes)
+)
 
-type IconCache struct {
-	sync.RWMutex
-	cache map[string][]byte
+var iconsPath = "/var/cloud/icons/"
+
+// Configure replaces the thumbnail and icon caches with empty ones of the given policy and budgets
+func Configure(policy string, thumbBytes, iconBytes int64) error {
+	thumbs, err := NewByteCache(policy, thumbBytes)
+	if err != nil {
+		return err
+	}
+	icons, err := NewByteCache(policy, iconBytes)
+	if err != nil {
+		return err
+	}
+
+	thumbCache, iconCache = thumbs, icons
+	return nil
 }
 
-var iconCache = IconCache{cache: make(map[string][]byte)}
+// Stats returns the counters of the thumbnail and icon caches
+func Stats() map[string]CacheStats {
+	return map[string]CacheStats{
+		"thumbnails": thumbCache.Stats(),
+		"icons":      iconCache.Stats(),
+	}
+}
 
 func GetThumbCash(filename string) ([]byte, bool) {
-	thumbCache.RLock()
-	imgData, exists := thumbCache.cache[filename]
-	thumbCache.RUnlock()
-	return imgData, exists
+	return thumbCache.Get(filename)
+}
+
+// LoadThumbCash returns a thumbnail from the cache, or loads it and caches it
+func LoadThumbCash(key string, load func() ([]byte, error)) ([]byte, error) {
+	return thumbCache.GetOrLoad(key, load)
 }
 
+// GetIconCash returns an icon from the cache, or reads it from the icons directory when it was
+// evicted or never loaded
 func GetIconCash(filename string) ([]byte, bool) {
-	iconCache.RLock()
-	imgData, exists := iconCache.cache[filename]
-	iconCache.RUnlock()
-	return imgData, exists
+	filename = filepath.Base(filename)
+	imgData, err := iconCache.GetOrLoad(filename, func() ([]byte, error) {
+		return os.ReadFile(iconsPath + filename)
+	})
+	return imgData, err == nil
 }
 
 // ConvertImageToBytes converts an image.Image to a byte slice.
@@ -235,60 +139,14 @@ func ConvertImageToBytes(img image.Image, format string) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
-func AddThumbCash(filepath string, filename string) {
-
-	originalImage, err := LoadImage(filepath)
-	if err != nil {
-		fmt.Println("addToCash Error loading image:", err)
-		return
-	}
-
-	imgBytes, err := ConvertImageToBytes(originalImage, "jpg") // Change to "png" for PNG format
-	if err != nil {
-		fmt.Println("Error ConvertImageToBytes: ", err)
-		return
-	}
-
-	thumbCache.Lock()
-	thumbCache.cache[filename] = imgBytes
-	thumbCache.Unlock()
-}
-
 func addIconCash(iconName string) {
-	icon, err := LoadImage("/var/cloud/icons/" + iconName) // Change path accordingly
-	if err != nil {
-		fmt.Println("Error loading image:", err)
-		return
-	}
-
-	imgBytes, err := ConvertImageToBytes(icon, "png") // Change to "png" for PNG format
+	imgBytes, err := os.ReadFile(iconsPath + iconName)
 	if err != nil {
-		fmt.Println("Error ConvertImageToBytes: ", err)
+		fmt.Println("Error loading icon:", err)
 		return
 	}
 
-	iconCache.Lock()
-	iconCache.cache[iconName] = imgBytes
-	iconCache.Unlock()
-}
-
-func SearchFile(filename string) (string, error) {
-	for _, folder := range folders {
-		// Construct the full path to the file
-		fullPath := filepath.Join(folder, filename)
-
-		// Check if the file exists
-		if _, err := os.Stat(fullPath); err == nil {
-			return fullPath, nil // File found
-		} else if os.IsNotExist(err) {
-			// File does not exist in this directory
-			continue
-		} else {
-			// Other error (e.g., permission issues)
-			return "", err
-		}
-	}
-	return "", fmt.Errorf("file %s not found in any of the specified folders", filename)
+	iconCache.Add(iconName, imgBytes)
 }
 
 // LoadImage loads an image from a file.
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"github.com/mahdi-cpp/PhotoKit/models"
+	"github.com/mahdi-cpp/PhotoKit/repositories"
+	"gorm.io/gorm"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// commands are the maintenance tasks that can be run instead of the server:
+//
+//	PhotoKit <command> <userId>
+var commands = map[string]func(db *gorm.DB, userId int) error{
+	"backfill-dates":        repositories.BackfillCreationDates,
+	"backfill-exif":         repositories.BackfillExif,
+	"backfill-faces":        repositories.BackfillFaces,
+	"backfill-hashes":       repositories.BackfillContentHashes,
+	"backfill-locations":    repositories.BackfillLocations,
+	"backfill-similarities": repositories.BackfillPerceptualHashes,
+	"detect-trips":          detectTrips,
+	"generate-memories":     generateMemories,
+}
+
+// runCommand runs the command given on the command line, it returns false when there is none
+func runCommand(db *gorm.DB, args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+
+	command, exists := commands[args[0]]
+	if !exists || len(args) != 2 {
+		log.Fatalf("Usage: PhotoKit <command> <userId>, commands: %s", commandNames())
+	}
+
+	userId, err := strconv.Atoi(args[1])
+	if err != nil {
+		log.Fatalf("Invalid user ID: %s", args[1])
+	}
+
+	// Auto migrate the models, commands may run before the server ever did
+	if err := db.AutoMigrate(&models.PHAsset{}, &models.Trip{}, &models.Memory{}, &models.FaceRegion{}); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := command(db, userId); err != nil {
+		log.Fatalf("%s failed: %v", args[0], err)
+	}
+
+	fmt.Printf("%s done\n", args[0])
+	return true
+}
+
+func detectTrips(db *gorm.DB, userId int) error {
+	trips, err := repositories.DetectTrips(db, userId)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%d trips suggested\n", len(trips))
+	return nil
+}
+
+func generateMemories(db *gorm.DB, userId int) error {
+	memories, err := repositories.NewMemoryRepository(db).GenerateWeeklyMemories(userId, repositories.WeekStart(time.Now()))
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%d memories generated\n", len(memories))
+	return nil
+}
+
+func commandNames() string {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
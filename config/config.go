@@ -3,6 +3,8 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
 )
 
 type Config struct {
@@ -12,6 +14,42 @@ type Config struct {
 	DBName     string
 	DBPort     string
 	AppPort    string
+
+	// Store of the asset library, "local" or "s3"
+	BlobStore string
+	// Root of the local asset library, with a directory for every user
+	AssetsRoot string
+	// Bucket of the asset library on an S3 compatible service
+	S3Endpoint  string
+	S3Region    string
+	S3Bucket    string
+	S3AccessKey string
+	S3SecretKey string
+	S3PathStyle bool // bucket in the path, S3_PATH_STYLE=false puts it in the host name
+	// Folders from before the asset library whose files are still served by name, by the user
+	// they belong to. Any name could be asked for, so a folder is only served to its user and
+	// LEGACY_ROOTS pairs every folder with a user ID: "1=/var/cloud/1/,2=/var/cloud/family/".
+	LegacyRoots map[int][]string
+
+	// Key of the access tokens, a random key is used when empty
+	AuthSecret string
+	// Development senders of the one-time codes: a file the codes are appended to, or the log.
+	// Without either codes cannot be sent.
+	SMSFile   string
+	SMSDevLog bool
+
+	// Eviction policy of the thumbnail and icon caches, "lru" or "arc"
+	CachePolicy string
+	// Memory budgets of the thumbnail and icon caches in bytes
+	ThumbCacheBytes int64
+	IconCacheBytes  int64
+	// Directory of the thumbnails rendered on demand
+	ThumbnailCacheDir string
+
+	// Command line of an external face detector, the reference detector is used when empty
+	FaceDetector string
+	// Largest embedding distance between two faces of the same person
+	FaceMatchDistance float64
 }
 
 func LoadConfig() *Config {
@@ -23,6 +61,37 @@ func LoadConfig() *Config {
 		DBName:     getEnv("DB_NAME", "PhotoKit"),
 		DBPort:     getEnv("DB_PORT", "5432"),
 		AppPort:    getEnv("PORT", "8080"),
+
+		BlobStore:   getEnv("BLOB_STORE", "local"),
+		AssetsRoot:  getEnv("ASSETS_ROOT", "/var/cloud/applications/PhotoKit/Assets/"),
+		S3Endpoint:  os.Getenv("S3_ENDPOINT"),
+		S3Region:    os.Getenv("S3_REGION"),
+		S3Bucket:    os.Getenv("S3_BUCKET"),
+		S3AccessKey: os.Getenv("S3_ACCESS_KEY"),
+		S3SecretKey: os.Getenv("S3_SECRET_KEY"),
+		S3PathStyle: os.Getenv("S3_PATH_STYLE") != "false",
+		LegacyRoots: getEnvUserFolders("LEGACY_ROOTS"),
+
+		AuthSecret: os.Getenv("AUTH_SECRET"),
+		SMSFile:    os.Getenv("SMS_FILE"),
+		SMSDevLog:  os.Getenv("SMS_DEV_LOG") == "true",
+
+		CachePolicy:     getEnv("CACHE_POLICY", "lru"),
+		ThumbCacheBytes: getEnvMB("THUMB_CACHE_MB", 256),
+		IconCacheBytes:  getEnvMB("ICON_CACHE_MB", 32),
+
+		ThumbnailCacheDir: getEnv("THUMBNAIL_CACHE_DIR", "/var/cloud/applications/PhotoKit/cache/thumbnails/"),
+
+		FaceDetector:      os.Getenv("FACE_DETECTOR"),
+		FaceMatchDistance: 0.6,
+	}
+
+	if distance := os.Getenv("FACE_MATCH_DISTANCE"); distance != "" {
+		value, err := strconv.ParseFloat(distance, 64)
+		if err != nil {
+			log.Fatalf("Invalid FACE_MATCH_DISTANCE: %s", distance)
+		}
+		cfg.FaceMatchDistance = value
 	}
 
 	return cfg
@@ -38,3 +107,44 @@ func getEnv(key, defaultValue string) string {
 	}
 	return value
 }
+
+// getEnvMB reads a size in megabytes and returns it in bytes
+func getEnvMB(key string, defaultValue int64) int64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue << 20
+	}
+
+	mb, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || mb <= 0 {
+		log.Fatalf("Invalid %s: %s", key, value)
+	}
+	return mb << 20
+}
+
+// getEnvList reads a comma separated list, empty when the variable is not set
+func getEnvList(key string) []string {
+	var list []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			list = append(list, value)
+		}
+	}
+	return list
+}
+
+// getEnvUserFolders reads a comma separated list of "<userId>=<folder>" pairs, the folders of
+// every user in their order
+func getEnvUserFolders(key string) map[int][]string {
+	folders := make(map[int][]string)
+	for _, pair := range getEnvList(key) {
+		user, folder, ok := strings.Cut(pair, "=")
+		userId, err := strconv.Atoi(strings.TrimSpace(user))
+		folder = strings.TrimSpace(folder)
+		if !ok || err != nil || userId <= 0 || folder == "" {
+			log.Fatalf("Invalid %s entry, want <userId>=<folder>: %s", key, pair)
+		}
+		folders[userId] = append(folders[userId], folder)
+	}
+	return folders
+}
@@ -0,0 +1,315 @@
+package controllers
+
+import (
+	"github.com/mahdi-cpp/PhotoKit/models"
+	"github.com/mahdi-cpp/PhotoKit/repositories"
+	"github.com/mahdi-cpp/PhotoKit/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AlbumController struct {
+	albumRepo *repositories.AlbumRepository
+}
+
+func NewAlbumController(albumRepo *repositories.AlbumRepository) *AlbumController {
+	return &AlbumController{albumRepo: albumRepo}
+}
+
+// ListAlbums godoc
+// @Summary List albums
+// @Description Get the albums of a user with asset counts and key photos
+// @Tags albums
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Success 200 {array} repositories.AlbumSummary
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /albums [get]
+func (ac *AlbumController) ListAlbums(c *gin.Context) {
+	userId, err := utils.GetUserID(c)
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	albums, err := ac.albumRepo.ListAlbums(userId)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "Failed to fetch albums")
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, albums)
+}
+
+// CreateAlbum godoc
+// @Summary Create an album
+// @Description Create a new album for a user
+// @Tags albums
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param album body models.CreateAlbumRequest true "Album data"
+// @Success 201 {object} models.Album
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /albums [post]
+func (ac *AlbumController) CreateAlbum(c *gin.Context) {
+	userId, err := utils.GetUserID(c)
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	var req models.CreateAlbumRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	album := models.Album{
+		UserId: userId,
+		Named:  req.Named,
+	}
+
+	if err := ac.albumRepo.CreateAlbum(&album); err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "Failed to create album")
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusCreated, album)
+}
+
+// GetAlbum godoc
+// @Summary Get an album
+// @Description Get an album and a page of its assets
+// @Tags albums
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Album ID"
+// @Security BearerAuth
+// @Param limit query int false "Limit assets"
+// @Param offset query int false "Offset assets"
+// @Success 200 {object} models.Album
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /albums/{id} [get]
+func (ac *AlbumController) GetAlbum(c *gin.Context) {
+	userId, id, ok := albumParams(c)
+	if !ok {
+		return
+	}
+
+	album, err := ac.albumRepo.GetAlbumByID(userId, id)
+	if err != nil {
+		utils.SendError(c, http.StatusNotFound, "Album not found")
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+
+	assets, err := ac.albumRepo.GetAlbumAssets(userId, id, limit, offset)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "Failed to fetch album assets")
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, gin.H{
+		"album":  album,
+		"assets": assets,
+	})
+}
+
+// RenameAlbum godoc
+// @Summary Rename an album
+// @Description Change the name of an album
+// @Tags albums
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Album ID"
+// @Security BearerAuth
+// @Param album body models.UpdateAlbumRequest true "Album update data"
+// @Success 200 {object} models.Album
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /albums/{id} [put]
+func (ac *AlbumController) RenameAlbum(c *gin.Context) {
+	userId, id, ok := albumParams(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateAlbumRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	if err := ac.albumRepo.RenameAlbum(userId, id, req.Named); err != nil {
+		utils.SendError(c, http.StatusNotFound, "Album not found")
+		return
+	}
+
+	album, err := ac.albumRepo.GetAlbumByID(userId, id)
+	if err != nil {
+		utils.SendError(c, http.StatusNotFound, "Album not found")
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, album)
+}
+
+// DeleteAlbum godoc
+// @Summary Delete an album
+// @Description Delete an album, its assets are kept in the library
+// @Tags albums
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Album ID"
+// @Security BearerAuth
+// @Success 204
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /albums/{id} [delete]
+func (ac *AlbumController) DeleteAlbum(c *gin.Context) {
+	userId, id, ok := albumParams(c)
+	if !ok {
+		return
+	}
+
+	if err := ac.albumRepo.DeleteAlbum(userId, id); err != nil {
+		utils.SendError(c, http.StatusNotFound, "Album not found")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// AddAssets godoc
+// @Summary Add assets to an album
+// @Description Add one or more assets of the user to an album
+// @Tags albums
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Album ID"
+// @Security BearerAuth
+// @Param assets body models.AlbumAssetsRequest true "Asset IDs"
+// @Success 200
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /albums/{id}/assets [post]
+func (ac *AlbumController) AddAssets(c *gin.Context) {
+	userId, id, ok := albumParams(c)
+	if !ok {
+		return
+	}
+
+	var req models.AlbumAssetsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	added, err := ac.albumRepo.AddAssets(userId, id, req.AssetIds)
+	if err != nil {
+		utils.SendError(c, http.StatusNotFound, "Album not found")
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, gin.H{"added": added})
+}
+
+// RemoveAssets godoc
+// @Summary Remove assets from an album
+// @Description Remove one or more assets from an album
+// @Tags albums
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Album ID"
+// @Security BearerAuth
+// @Param assets body models.AlbumAssetsRequest true "Asset IDs"
+// @Success 200
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /albums/{id}/assets [delete]
+func (ac *AlbumController) RemoveAssets(c *gin.Context) {
+	userId, id, ok := albumParams(c)
+	if !ok {
+		return
+	}
+
+	var req models.AlbumAssetsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	removed, err := ac.albumRepo.RemoveAssets(userId, id, req.AssetIds)
+	if err != nil {
+		utils.SendError(c, http.StatusNotFound, "Album not found")
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, gin.H{"removed": removed})
+}
+
+// SetKeyAsset godoc
+// @Summary Choose the key photo of an album
+// @Description Set the asset used as the album cover
+// @Tags albums
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Album ID"
+// @Security BearerAuth
+// @Param asset body models.AlbumKeyAssetRequest true "Asset ID"
+// @Success 200 {object} models.Album
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /albums/{id}/key [put]
+func (ac *AlbumController) SetKeyAsset(c *gin.Context) {
+	userId, id, ok := albumParams(c)
+	if !ok {
+		return
+	}
+
+	var req models.AlbumKeyAssetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	if err := ac.albumRepo.SetKeyAsset(userId, id, req.AssetId); err != nil {
+		utils.SendError(c, http.StatusNotFound, err.Error())
+		return
+	}
+
+	album, err := ac.albumRepo.GetAlbumByID(userId, id)
+	if err != nil {
+		utils.SendError(c, http.StatusNotFound, "Album not found")
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, album)
+}
+
+// albumParams reads the user and album IDs of a request, sending an error when invalid
+func albumParams(c *gin.Context) (int, int, bool) {
+	userId, err := utils.GetUserID(c)
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid user ID")
+		return 0, 0, false
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid album ID")
+		return 0, 0, false
+	}
+
+	return userId, id, true
+}
@@ -1,9 +1,14 @@
 package controllers
 
 import (
+	"errors"
 	"github.com/mahdi-cpp/PhotoKit/models"
+	"github.com/mahdi-cpp/PhotoKit/repositories"
 	"github.com/mahdi-cpp/PhotoKit/utils"
+	"log"
 	"net/http"
+	"os"
+	"path/filepath"
 	"strconv"
 	"time"
 
@@ -17,6 +22,13 @@ type AssetController struct {
 }
 
 func NewAssetController(db *gorm.DB) *AssetController {
+
+	// Auto migrate the PHAsset models
+	err := db.AutoMigrate(&models.PHAsset{})
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	return &AssetController{db: db}
 }
 
@@ -41,10 +53,216 @@ type UpdateAssetRequest struct {
 	Named      *string `json:"named"`
 	IsFavorite *bool   `json:"isFavorite"`
 	IsHidden   *bool   `json:"isHidden"`
-	Albums     []int32 `json:"albums"`
-	Trips      []int32 `json:"trips"`
-	Persons    []int32 `json:"persons"`
-	Cameras    []int32 `json:"cameras"`
+	// Turning it off also removes the asset from the shared albums it is in
+	CanAddToSharedAlbum *bool   `json:"CanAddToSharedAlbum"`
+	Cameras             []int32 `json:"cameras"`
+}
+
+// UploadResult reports the outcome of ingesting one uploaded file
+type UploadResult struct {
+	Filename string          `json:"filename"`
+	Status   string          `json:"status"` // "created", "duplicate", "unsupported" or "failed"
+	Error    string          `json:"error,omitempty"`
+	Asset    *models.PHAsset `json:"asset,omitempty"`
+}
+
+// UploadAssets godoc
+// @Summary Upload assets
+// @Description Upload one or more files and run the ingest pipeline for each of them
+// @Tags assets
+// @Accept  multipart/form-data
+// @Produce  json
+// @Security BearerAuth
+// @Param files formData file true "Files to upload"
+// @Param lastModified formData []int false "Modification time of each file in milliseconds since the epoch, in the order of the files"
+// @Success 200 {array} UploadResult
+// @Failure 400 {object} utils.ErrorResponse
+// @Router /assets/upload [post]
+func (ac *AssetController) UploadAssets(c *gin.Context) {
+	userId, err := utils.GetUserID(c)
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	form, err := c.MultipartForm()
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid multipart form")
+		return
+	}
+
+	files := append(form.File["files"], form.File["file"]...)
+	if len(files) == 0 {
+		utils.SendError(c, http.StatusBadRequest, "No files uploaded")
+		return
+	}
+
+	lastModified := form.Value["lastModified"]
+
+	results := make([]UploadResult, 0, len(files))
+	for i, file := range files {
+		named := filepath.Base(file.Filename)
+		result := UploadResult{Filename: named}
+
+		tmp, err := os.CreateTemp("", "upload-*"+filepath.Ext(named))
+		if err != nil {
+			log.Printf("Failed to create a temporary file for %s: %v", named, err)
+			result.Status = "failed"
+			result.Error = "Failed to store upload"
+			results = append(results, result)
+			continue
+		}
+		tmpPath := tmp.Name()
+		tmp.Close()
+
+		if err := c.SaveUploadedFile(file, tmpPath); err != nil {
+			result.Status = "failed"
+			result.Error = "Failed to store upload"
+		} else if asset, err := repositories.IngestFile(ac.db, userId, tmpPath, named, uploadMtimePath(tmpPath, lastModified, i)); errors.Is(err, repositories.ErrUnsupportedFormat) {
+			result.Status = "unsupported"
+		} else if errors.Is(err, repositories.ErrDuplicateAsset) {
+			result.Status = "duplicate"
+			result.Asset = asset
+		} else if err != nil {
+			log.Printf("Failed to ingest %s: %v", named, err)
+			result.Status = "failed"
+			result.Error = "Failed to ingest file"
+		} else {
+			result.Status = "created"
+			result.Asset = asset
+		}
+
+		os.Remove(tmpPath)
+		results = append(results, result)
+	}
+
+	utils.SendSuccess(c, http.StatusOK, results)
+}
+
+// uploadMtimePath dates the temporary file of the i-th upload with the modification time sent by
+// the client and returns its path, or an empty path when the client sent none, the temporary
+// file itself is dated now
+func uploadMtimePath(tmpPath string, lastModified []string, i int) string {
+	if i >= len(lastModified) {
+		return ""
+	}
+
+	millis, err := strconv.ParseInt(lastModified[i], 10, 64)
+	if err != nil || millis <= 0 {
+		return ""
+	}
+
+	modTime := time.UnixMilli(millis)
+	if err := os.Chtimes(tmpPath, modTime, modTime); err != nil {
+		return ""
+	}
+	return tmpPath
+}
+
+// ListDuplicates godoc
+// @Summary List duplicate assets
+// @Description Get groups of the user's assets that have exactly the same content
+// @Tags assets
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Success 200 {array} repositories.DuplicateGroup
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /assets/duplicates [get]
+func (ac *AssetController) ListDuplicates(c *gin.Context) {
+	userId, err := utils.GetUserID(c)
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	groups, err := repositories.GetDuplicateGroups(ac.db, userId)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "Failed to fetch duplicates")
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, groups)
+}
+
+// FindSimilar godoc
+// @Summary Find similar assets
+// @Description Get the user's assets that look like the given one, closest first
+// @Tags assets
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Asset ID"
+// @Security BearerAuth
+// @Param distance query int false "Maximum Hamming distance of the perceptual hashes (default: 6)"
+// @Success 200 {array} repositories.SimilarAsset
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /assets/similar/{id} [get]
+func (ac *AssetController) FindSimilar(c *gin.Context) {
+	userId, err := utils.GetUserID(c)
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid asset ID")
+		return
+	}
+
+	similar, err := repositories.FindSimilarAssets(ac.db, userId, id, similarDistance(c))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.SendError(c, http.StatusNotFound, "Asset not found")
+		} else {
+			utils.SendError(c, http.StatusInternalServerError, "Failed to fetch similar assets")
+		}
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, similar)
+}
+
+// ListSimilarGroups godoc
+// @Summary List groups of similar assets
+// @Description Get clusters of near-identical assets such as resized copies and burst sequences
+// @Tags assets
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param distance query int false "Maximum Hamming distance of the perceptual hashes (default: 6)"
+// @Success 200 {array} repositories.SimilarGroup
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /assets/similar [get]
+func (ac *AssetController) ListSimilarGroups(c *gin.Context) {
+	userId, err := utils.GetUserID(c)
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	groups, err := repositories.GetSimilarGroups(ac.db, userId, similarDistance(c))
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "Failed to fetch similar assets")
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, groups)
+}
+
+// similarDistance reads the distance query parameter, bounded to keep groups meaningful
+func similarDistance(c *gin.Context) int {
+	distance, err := strconv.Atoi(c.Query("distance"))
+	if err != nil || distance < 0 {
+		return repositories.DefaultSimilarDistance
+	}
+	if distance > 16 {
+		distance = 16
+	}
+	return distance
 }
 
 // CreateAsset godoc
@@ -57,8 +275,15 @@ type UpdateAssetRequest struct {
 // @Success 201 {object} models.PHAsset
 // @Failure 400 {object} utils.ErrorResponse
 // @Failure 500 {object} utils.ErrorResponse
+// @Security BearerAuth
 // @Router /assets [post]
 func (ac *AssetController) CreateAsset(c *gin.Context) {
+	userId, err := utils.GetUserID(c)
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
 	var req CreateAssetRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.SendError(c, http.StatusBadRequest, "Invalid request payload")
@@ -66,6 +291,7 @@ func (ac *AssetController) CreateAsset(c *gin.Context) {
 	}
 
 	asset := models.PHAsset{
+		UserId:       userId,
 		URL:          req.URL,
 		MediaType:    req.MediaType,
 		Format:       req.Format,
@@ -98,8 +324,15 @@ func (ac *AssetController) CreateAsset(c *gin.Context) {
 // @Success 200 {object} models.PHAsset
 // @Failure 404 {object} utils.ErrorResponse
 // @Failure 500 {object} utils.ErrorResponse
+// @Security BearerAuth
 // @Router /assets/{id} [get]
 func (ac *AssetController) GetAsset(c *gin.Context) {
+	userId, err := utils.GetUserID(c)
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		utils.SendError(c, http.StatusBadRequest, "Invalid asset ID")
@@ -107,7 +340,7 @@ func (ac *AssetController) GetAsset(c *gin.Context) {
 	}
 
 	var asset models.PHAsset
-	result := ac.db.First(&asset, id)
+	result := ac.db.Where("user_id = ?", userId).First(&asset, id)
 	if result.Error != nil {
 		if result.Error == gorm.ErrRecordNotFound {
 			utils.SendError(c, http.StatusNotFound, "Asset not found")
@@ -120,6 +353,112 @@ func (ac *AssetController) GetAsset(c *gin.Context) {
 	utils.SendSuccess(c, http.StatusOK, asset)
 }
 
+// GetMetadata godoc
+// @Summary Get the metadata of an asset
+// @Description Get the exposure information and every EXIF tag of an asset grouped by IFD
+// @Tags assets
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Asset ID"
+// @Security BearerAuth
+// @Success 200 {object} repositories.AssetMetadata
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /assets/{id}/metadata [get]
+func (ac *AssetController) GetMetadata(c *gin.Context) {
+	userId, err := utils.GetUserID(c)
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid asset ID")
+		return
+	}
+
+	metadata, err := repositories.GetAssetMetadata(ac.db, userId, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.SendError(c, http.StatusNotFound, "Asset not found")
+		} else {
+			utils.SendError(c, http.StatusInternalServerError, "Failed to fetch metadata")
+		}
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, metadata)
+}
+
+// GetThumbnail godoc
+// @Summary Get a thumbnail of an asset
+// @Description Render a thumbnail from the original, upright, and cache it on disk. Sizes are rounded up to 70, 135, 270, 540, 1080 or 2048, the widths made at ingest are served as they are.
+// @Tags assets
+// @Produce  image/jpeg,image/png
+// @Param id path int true "Asset ID"
+// @Param w query int false "Width, 0 follows the aspect ratio"
+// @Param h query int false "Height, 0 follows the aspect ratio"
+// @Param fit query string false "contain (default) or cover"
+// @Param format query string false "jpeg (default) or png"
+// @Success 200 {file} binary
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Security BearerAuth
+// @Router /assets/{id}/thumb [get]
+func (ac *AssetController) GetThumbnail(c *gin.Context) {
+	userId, err := utils.GetUserID(c)
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid asset ID")
+		return
+	}
+
+	spec := repositories.ThumbnailSpec{Fit: c.Query("fit"), Format: c.Query("format")}
+	if spec.Width, err = strconv.Atoi(c.DefaultQuery("w", "0")); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid width")
+		return
+	}
+	if spec.Height, err = strconv.Atoi(c.DefaultQuery("h", "0")); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid height")
+		return
+	}
+	if err := spec.Validate(); err != nil {
+		utils.SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Members of a shared album see the thumbnails of its assets
+	var asset models.PHAsset
+	result := ac.db.Scopes(repositories.AccessibleAssets(userId)).First(&asset, id)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			utils.SendError(c, http.StatusNotFound, "Asset not found")
+		} else {
+			utils.SendError(c, http.StatusInternalServerError, "Failed to fetch asset")
+		}
+		return
+	}
+
+	store, key, err := repositories.RenderThumbnail(asset, spec)
+	if err != nil {
+		log.Printf("Failed to render thumbnail of asset %d: %v", asset.ID, err)
+		utils.SendError(c, http.StatusInternalServerError, "Failed to render thumbnail")
+		return
+	}
+
+	// A variant never changes under its URL, a changed original gets new variants
+	c.Header("Content-Type", "image/"+spec.Format)
+	utils.ServeBlob(c, store, key, "", utils.CacheImmutable)
+}
+
 // UpdateAsset godoc
 // @Summary Update an asset
 // @Description Update an existing asset
@@ -132,8 +471,15 @@ func (ac *AssetController) GetAsset(c *gin.Context) {
 // @Failure 400 {object} utils.ErrorResponse
 // @Failure 404 {object} utils.ErrorResponse
 // @Failure 500 {object} utils.ErrorResponse
+// @Security BearerAuth
 // @Router /assets/{id} [put]
 func (ac *AssetController) UpdateAsset(c *gin.Context) {
+	userId, err := utils.GetUserID(c)
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		utils.SendError(c, http.StatusBadRequest, "Invalid asset ID")
@@ -147,7 +493,7 @@ func (ac *AssetController) UpdateAsset(c *gin.Context) {
 	}
 
 	var asset models.PHAsset
-	result := ac.db.First(&asset, id)
+	result := ac.db.Where("user_id = ?", userId).First(&asset, id)
 	if result.Error != nil {
 		if result.Error == gorm.ErrRecordNotFound {
 			utils.SendError(c, http.StatusNotFound, "Asset not found")
@@ -167,14 +513,8 @@ func (ac *AssetController) UpdateAsset(c *gin.Context) {
 	if req.IsHidden != nil {
 		asset.IsHidden = *req.IsHidden
 	}
-	if req.Albums != nil {
-		asset.Albums = pq.Int32Array(req.Albums)
-	}
-	if req.Trips != nil {
-		asset.Trips = pq.Int32Array(req.Trips)
-	}
-	if req.Persons != nil {
-		asset.Persons = pq.Int32Array(req.Persons)
+	if req.CanAddToSharedAlbum != nil {
+		asset.CanAddToSharedAlbum = *req.CanAddToSharedAlbum
 	}
 
 	asset.ModificationDate = time.Now()
@@ -185,6 +525,13 @@ func (ac *AssetController) UpdateAsset(c *gin.Context) {
 		return
 	}
 
+	if !asset.CanAddToSharedAlbum {
+		if err := repositories.UnshareAssets(ac.db, []int{asset.ID}); err != nil {
+			utils.SendError(c, http.StatusInternalServerError, "Failed to remove asset from shared albums")
+			return
+		}
+	}
+
 	utils.SendSuccess(c, http.StatusOK, asset)
 }
 
@@ -199,15 +546,22 @@ func (ac *AssetController) UpdateAsset(c *gin.Context) {
 // @Failure 400 {object} utils.ErrorResponse
 // @Failure 404 {object} utils.ErrorResponse
 // @Failure 500 {object} utils.ErrorResponse
+// @Security BearerAuth
 // @Router /assets/{id} [delete]
 func (ac *AssetController) DeleteAsset(c *gin.Context) {
+	userId, err := utils.GetUserID(c)
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		utils.SendError(c, http.StatusBadRequest, "Invalid asset ID")
 		return
 	}
 
-	result := ac.db.Delete(&models.PHAsset{}, id)
+	result := ac.db.Where("user_id = ?", userId).Delete(&models.PHAsset{}, id)
 	if result.Error != nil {
 		utils.SendError(c, http.StatusInternalServerError, "Failed to delete asset")
 		return
@@ -217,6 +571,11 @@ func (ac *AssetController) DeleteAsset(c *gin.Context) {
 		return
 	}
 
+	if err := repositories.UnshareAssets(ac.db, []int{id}); err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "Failed to remove asset from shared albums")
+		return
+	}
+
 	c.Status(http.StatusNoContent)
 }
 
@@ -230,13 +589,32 @@ func (ac *AssetController) DeleteAsset(c *gin.Context) {
 // @Param favorite query bool false "Filter by favorite status"
 // @Param recentDays query int false "Filter by recent days"
 // @Param album query int false "Filter by album ID"
+// @Param bbox query string false "Filter by bounding box: minLng,minLat,maxLng,maxLat"
+// @Param near query string false "Filter by distance from a coordinate: lat,lng"
+// @Param radius query number false "Radius around near in kilometers (default: 1)"
+// @Param lens query string false "Filter by lens model (substring)"
+// @Param minIso query int false "Filter by minimum ISO"
+// @Param maxIso query int false "Filter by maximum ISO"
+// @Param minFNumber query number false "Filter by minimum f-number"
+// @Param maxFNumber query number false "Filter by maximum f-number"
+// @Param minFocalLength query number false "Filter by minimum focal length in millimeters"
+// @Param maxFocalLength query number false "Filter by maximum focal length in millimeters"
+// @Param flash query bool false "Filter by whether the flash fired"
+// @Param whiteBalance query string false "Filter by white balance: auto or manual"
 // @Param limit query int false "Limit results"
 // @Param offset query int false "Offset results"
 // @Success 200 {array} models.PHAsset
 // @Failure 500 {object} utils.ErrorResponse
+// @Security BearerAuth
 // @Router /assets [get]
 func (ac *AssetController) ListAssets(c *gin.Context) {
-	query := ac.db.Model(&models.PHAsset{})
+	userId, err := utils.GetUserID(c)
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	query := ac.db.Model(&models.PHAsset{}).Where("user_id = ?", userId)
 
 	// Apply filters
 	if mediaType := c.Query("mediaType"); mediaType != "" {
@@ -256,6 +634,70 @@ func (ac *AssetController) ListAssets(c *gin.Context) {
 		query = query.Where("albums @> ?", pq.Int32Array{int32(albumIDInt)})
 	}
 
+	if bbox := c.Query("bbox"); bbox != "" {
+		box, err := utils.ParseBoundingBox(bbox)
+		if err != nil {
+			utils.SendError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		query = query.Scopes(repositories.WithinBoundingBox(box))
+	}
+	if near := c.Query("near"); near != "" {
+		latitude, longitude, err := utils.ParseCoordinate(near)
+		if err != nil {
+			utils.SendError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		radius, err := strconv.ParseFloat(c.DefaultQuery("radius", "1"), 64)
+		if err != nil || radius <= 0 {
+			utils.SendError(c, http.StatusBadRequest, "Invalid radius")
+			return
+		}
+		query = query.Scopes(repositories.WithinRadius(latitude, longitude, radius))
+	}
+
+	if lens := c.Query("lens"); lens != "" {
+		query = query.Where("lens_model ILIKE ?", "%"+lens+"%")
+	}
+	ranges := []struct{ min, max, column string }{
+		{"minIso", "maxIso", "iso"},
+		{"minFNumber", "maxFNumber", "f_number"},
+		{"minFocalLength", "maxFocalLength", "focal_length"},
+	}
+	for _, r := range ranges {
+		if min := c.Query(r.min); min != "" {
+			value, err := strconv.ParseFloat(min, 64)
+			if err != nil {
+				utils.SendError(c, http.StatusBadRequest, "Invalid "+r.min)
+				return
+			}
+			query = query.Where(r.column+" >= ?", value)
+		}
+		if max := c.Query(r.max); max != "" {
+			value, err := strconv.ParseFloat(max, 64)
+			if err != nil {
+				utils.SendError(c, http.StatusBadRequest, "Invalid "+r.max)
+				return
+			}
+			query = query.Where(r.column+" <= ?", value)
+		}
+	}
+	if flash := c.Query("flash"); flash != "" {
+		fired, _ := strconv.ParseBool(flash)
+		query = query.Where("flash IS NOT NULL AND (flash & 1 = 1) = ?", fired)
+	}
+	if whiteBalance := c.Query("whiteBalance"); whiteBalance != "" {
+		switch whiteBalance {
+		case "auto":
+			query = query.Where("white_balance = ?", 0)
+		case "manual":
+			query = query.Where("white_balance = ?", 1)
+		default:
+			utils.SendError(c, http.StatusBadRequest, "Invalid whiteBalance")
+			return
+		}
+	}
+
 	if cameraId := c.Query("cameras"); cameraId != "" {
 		cameraIdInt, _ := strconv.Atoi(cameraId)
 		query = query.Where("cameras @> ?", pq.Int32Array{int32(cameraIdInt)})
@@ -287,8 +729,15 @@ func (ac *AssetController) ListAssets(c *gin.Context) {
 // @Failure 400 {object} utils.ErrorResponse
 // @Failure 404 {object} utils.ErrorResponse
 // @Failure 500 {object} utils.ErrorResponse
+// @Security BearerAuth
 // @Router /assets/{id}/favorite [patch]
 func (ac *AssetController) ToggleFavorite(c *gin.Context) {
+	userId, err := utils.GetUserID(c)
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		utils.SendError(c, http.StatusBadRequest, "Invalid asset ID")
@@ -296,7 +745,7 @@ func (ac *AssetController) ToggleFavorite(c *gin.Context) {
 	}
 
 	var asset models.PHAsset
-	result := ac.db.First(&asset, id)
+	result := ac.db.Where("user_id = ?", userId).First(&asset, id)
 	if result.Error != nil {
 		if result.Error == gorm.ErrRecordNotFound {
 			utils.SendError(c, http.StatusNotFound, "Asset not found")
@@ -340,12 +789,18 @@ type CameraResponse struct {
 // @Param offset query int false "Offset results (default: 0)"
 // @Success 200 {array} CameraResponse
 // @Failure 500 {object} utils.ErrorResponse
+// @Security BearerAuth
 // @Router /cameras [get]
 func (ac *AssetController) ListCameras(c *gin.Context) {
+	userId, err := utils.GetUserID(c)
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
 
 	query := ac.db.Model(&models.PHAsset{}).
 		Select("camera_make as make, camera_model as model, COUNT(*) as asset_count").
-		Where("camera_make IS NOT NULL AND camera_model IS NOT NULL").
+		Where("user_id = ? AND camera_make IS NOT NULL AND camera_model IS NOT NULL", userId).
 		Group("camera_make, camera_model")
 
 	// Apply filters
@@ -393,8 +848,15 @@ type CameraResponse2 struct {
 // @Produce json
 // @Param make query string false "Filter by manufacturer"
 // @Success 200 {array} CameraResponse
+// @Security BearerAuth
 // @Router /cameras [get]
 func (ac *AssetController) ListCamerasWithImages(c *gin.Context) {
+	userId, err := utils.GetUserID(c)
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
 	// First, get all camera models
 	var cameras []struct {
 		Make  string `json:"make"`
@@ -403,7 +865,7 @@ func (ac *AssetController) ListCamerasWithImages(c *gin.Context) {
 
 	ac.db.Model(&models.PHAsset{}).
 		Select("DISTINCT camera_make as make, camera_model as model").
-		Where("camera_make IS NOT NULL AND camera_model IS NOT NULL").
+		Where("user_id = ? AND camera_make IS NOT NULL AND camera_model IS NOT NULL", userId).
 		Find(&cameras)
 
 	// For each camera, get 3 sample assets
@@ -411,7 +873,7 @@ func (ac *AssetController) ListCamerasWithImages(c *gin.Context) {
 	for _, cam := range cameras {
 		var sampleAssets []models.PHAsset
 		ac.db.Model(&models.PHAsset{}).
-			Where("camera_make = ? AND camera_model = ?", cam.Make, cam.Model).
+			Where("user_id = ? AND camera_make = ? AND camera_model = ?", userId, cam.Make, cam.Model).
 			Limit(3).
 			Find(&sampleAssets)
 
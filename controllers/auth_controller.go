@@ -0,0 +1,220 @@
+package controllers
+
+import (
+	"errors"
+	"github.com/mahdi-cpp/PhotoKit/models"
+	"github.com/mahdi-cpp/PhotoKit/repositories"
+	"github.com/mahdi-cpp/PhotoKit/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type AuthController struct {
+	authRepo *repositories.AuthRepository
+}
+
+func NewAuthController(authRepo *repositories.AuthRepository) *AuthController {
+	return &AuthController{authRepo: authRepo}
+}
+
+// Register godoc
+// @Summary Register a user
+// @Description Create a user with a password and sign them in
+// @Tags auth
+// @Accept  json
+// @Produce  json
+// @Param user body models.RegisterRequest true "Register user"
+// @Success 201 {object} repositories.TokenPair
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 409 {object} utils.ErrorResponse
+// @Router /auth/register [post]
+func (ac *AuthController) Register(c *gin.Context) {
+	var req models.RegisterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	tokens, err := ac.authRepo.Register(req)
+	if err != nil {
+		switch {
+		case errors.Is(err, repositories.ErrUserExists):
+			utils.SendError(c, http.StatusConflict, err.Error())
+		case errors.Is(err, repositories.ErrPhoneNumber):
+			utils.SendError(c, http.StatusBadRequest, err.Error())
+		case errors.Is(err, bcrypt.ErrPasswordTooLong):
+			// max=72 counts characters, bcrypt counts bytes
+			utils.SendError(c, http.StatusBadRequest, "Password is longer than 72 bytes")
+		default:
+			utils.SendError(c, http.StatusInternalServerError, "Failed to register user")
+		}
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusCreated, tokens)
+}
+
+// Login godoc
+// @Summary Sign in
+// @Description Sign in with a username or phone number and a password
+// @Tags auth
+// @Accept  json
+// @Produce  json
+// @Param credentials body models.LoginRequest true "Credentials"
+// @Success 200 {object} repositories.TokenPair
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Router /auth/login [post]
+func (ac *AuthController) Login(c *gin.Context) {
+	var req models.LoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	tokens, err := ac.authRepo.Login(req)
+	if err != nil {
+		if errors.Is(err, repositories.ErrInvalidCredentials) {
+			utils.SendError(c, http.StatusUnauthorized, err.Error())
+		} else {
+			utils.SendError(c, http.StatusInternalServerError, "Failed to sign in")
+		}
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, tokens)
+}
+
+// Refresh godoc
+// @Summary Refresh tokens
+// @Description Trade a refresh token for a new access token and refresh token
+// @Tags auth
+// @Accept  json
+// @Produce  json
+// @Param token body models.RefreshRequest true "Refresh token"
+// @Success 200 {object} repositories.TokenPair
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Router /auth/refresh [post]
+func (ac *AuthController) Refresh(c *gin.Context) {
+	var req models.RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	tokens, err := ac.authRepo.Refresh(req.RefreshToken)
+	if err != nil {
+		if errors.Is(err, repositories.ErrInvalidRefresh) {
+			utils.SendError(c, http.StatusUnauthorized, err.Error())
+		} else {
+			utils.SendError(c, http.StatusInternalServerError, "Failed to refresh tokens")
+		}
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, tokens)
+}
+
+// Logout godoc
+// @Summary Sign out
+// @Description Revoke a refresh token
+// @Tags auth
+// @Accept  json
+// @Produce  json
+// @Param token body models.RefreshRequest true "Refresh token"
+// @Success 204
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /auth/logout [post]
+func (ac *AuthController) Logout(c *gin.Context) {
+	var req models.RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	if err := ac.authRepo.Logout(req.RefreshToken); err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "Failed to sign out")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// RequestOtp godoc
+// @Summary Request a one-time code
+// @Description Send a one-time code to a phone number to sign in with
+// @Tags auth
+// @Accept  json
+// @Produce  json
+// @Param phone body models.OtpRequest true "Phone number"
+// @Success 202 {object} map[string]int
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 429 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Failure 503 {object} utils.ErrorResponse
+// @Router /auth/otp/request [post]
+func (ac *AuthController) RequestOtp(c *gin.Context) {
+	var req models.OtpRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	ttl, err := ac.authRepo.RequestOtp(req.PhoneNumber, c.ClientIP())
+	if err != nil {
+		switch {
+		case errors.Is(err, repositories.ErrPhoneNumber):
+			utils.SendError(c, http.StatusBadRequest, err.Error())
+		case errors.Is(err, repositories.ErrOtpRateLimited):
+			c.Header("Retry-After", strconv.Itoa(int(repositories.OtpResendInterval.Seconds())))
+			utils.SendError(c, http.StatusTooManyRequests, err.Error())
+		case errors.Is(err, repositories.ErrOtpUnavailable):
+			utils.SendError(c, http.StatusServiceUnavailable, err.Error())
+		default:
+			utils.SendError(c, http.StatusInternalServerError, "Failed to send code")
+		}
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusAccepted, gin.H{"expiresIn": int(ttl.Seconds())})
+}
+
+// VerifyOtp godoc
+// @Summary Sign in with a one-time code
+// @Description Check a one-time code and sign in, a user is created for a new phone number
+// @Tags auth
+// @Accept  json
+// @Produce  json
+// @Param code body models.OtpVerifyRequest true "Phone number and code"
+// @Success 200 {object} repositories.TokenPair
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 401 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /auth/otp/verify [post]
+func (ac *AuthController) VerifyOtp(c *gin.Context) {
+	var req models.OtpVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	tokens, err := ac.authRepo.VerifyOtp(req.PhoneNumber, req.Code)
+	if err != nil {
+		switch {
+		case errors.Is(err, repositories.ErrPhoneNumber):
+			utils.SendError(c, http.StatusBadRequest, err.Error())
+		case errors.Is(err, repositories.ErrOtpInvalid):
+			utils.SendError(c, http.StatusUnauthorized, err.Error())
+		default:
+			utils.SendError(c, http.StatusInternalServerError, "Failed to sign in")
+		}
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, tokens)
+}
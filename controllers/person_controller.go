@@ -0,0 +1,453 @@
+package controllers
+
+import (
+	"errors"
+	"github.com/mahdi-cpp/PhotoKit/models"
+	"github.com/mahdi-cpp/PhotoKit/repositories"
+	"github.com/mahdi-cpp/PhotoKit/storage"
+	"github.com/mahdi-cpp/PhotoKit/utils"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PersonController struct {
+	personRepo *repositories.PersonRepository
+}
+
+func NewPersonController(personRepo *repositories.PersonRepository) *PersonController {
+	return &PersonController{personRepo: personRepo}
+}
+
+// ListPersons godoc
+// @Summary List people
+// @Description Get the people of a user, the ones in the most assets first
+// @Tags persons
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param hidden query bool false "Include hidden people"
+// @Success 200 {array} repositories.PersonSummary
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /persons [get]
+func (pc *PersonController) ListPersons(c *gin.Context) {
+	userId, err := utils.GetUserID(c)
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	includeHidden, _ := strconv.ParseBool(c.Query("hidden"))
+
+	persons, err := pc.personRepo.ListPersons(userId, includeHidden)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "Failed to fetch people")
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, persons)
+}
+
+// CreatePerson godoc
+// @Summary Create a person
+// @Description Create a new person for a user
+// @Tags persons
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param person body models.CreatePersonRequest true "Person data"
+// @Success 201 {object} models.Persons
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /persons [post]
+func (pc *PersonController) CreatePerson(c *gin.Context) {
+	userId, err := utils.GetUserID(c)
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	var req models.CreatePersonRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	person := models.Persons{
+		UserId: userId,
+		Named:  req.Named,
+	}
+
+	if err := pc.personRepo.CreatePerson(&person); err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "Failed to create person")
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusCreated, person)
+}
+
+// GetPerson godoc
+// @Summary Get a person
+// @Description Get a person, their tagged regions and a page of their assets
+// @Tags persons
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Person ID"
+// @Security BearerAuth
+// @Param limit query int false "Limit assets"
+// @Param offset query int false "Offset assets"
+// @Success 200 {object} models.Persons
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /persons/{id} [get]
+func (pc *PersonController) GetPerson(c *gin.Context) {
+	userId, id, ok := personParams(c)
+	if !ok {
+		return
+	}
+
+	person, err := pc.personRepo.GetPersonByID(userId, id)
+	if err != nil {
+		utils.SendError(c, http.StatusNotFound, "Person not found")
+		return
+	}
+
+	regions, err := pc.personRepo.GetPersonRegions(userId, id)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "Failed to fetch person regions")
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+
+	assets, err := pc.personRepo.GetPersonAssets(userId, id, limit, offset)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "Failed to fetch person assets")
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, gin.H{
+		"person":  person,
+		"regions": regions,
+		"assets":  assets,
+	})
+}
+
+// UpdatePerson godoc
+// @Summary Rename or hide a person
+// @Description Change the name of a person or hide them from the people list
+// @Tags persons
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Person ID"
+// @Security BearerAuth
+// @Param person body models.UpdatePersonRequest true "Person update data"
+// @Success 200 {object} models.Persons
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /persons/{id} [put]
+func (pc *PersonController) UpdatePerson(c *gin.Context) {
+	userId, id, ok := personParams(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdatePersonRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	if req.Named != nil && *req.Named == "" {
+		utils.SendError(c, http.StatusBadRequest, "Name cannot be empty")
+		return
+	}
+
+	if err := pc.personRepo.UpdatePerson(userId, id, req.Named, req.IsHidden); err != nil {
+		utils.SendError(c, http.StatusNotFound, "Person not found")
+		return
+	}
+
+	person, err := pc.personRepo.GetPersonByID(userId, id)
+	if err != nil {
+		utils.SendError(c, http.StatusNotFound, "Person not found")
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, person)
+}
+
+// MergePersons godoc
+// @Summary Merge people
+// @Description Merge people into the first one of the list, moving their regions and assets
+// @Tags persons
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param persons body models.MergePersonsRequest true "Person IDs"
+// @Success 200 {object} models.Persons
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /persons/merge [post]
+func (pc *PersonController) MergePersons(c *gin.Context) {
+	userId, err := utils.GetUserID(c)
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	var req models.MergePersonsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	person, err := pc.personRepo.MergePersons(userId, req.PersonIds)
+	if err != nil {
+		utils.SendError(c, http.StatusNotFound, err.Error())
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, person)
+}
+
+// TagRegion godoc
+// @Summary Tag a region of an asset
+// @Description Mark a normalized bounding box of an asset as showing the person
+// @Tags persons
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Person ID"
+// @Security BearerAuth
+// @Param region body models.TagRegionRequest true "Region"
+// @Success 201 {object} models.FaceRegion
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /persons/{id}/regions [post]
+func (pc *PersonController) TagRegion(c *gin.Context) {
+	userId, id, ok := personParams(c)
+	if !ok {
+		return
+	}
+
+	var req models.TagRegionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	if req.X+req.Width > 1 || req.Y+req.Height > 1 {
+		utils.SendError(c, http.StatusBadRequest, "Region must be inside the image")
+		return
+	}
+
+	region := models.FaceRegion{
+		AssetId: req.AssetId,
+		X:       req.X,
+		Y:       req.Y,
+		Width:   req.Width,
+		Height:  req.Height,
+	}
+
+	if err := pc.personRepo.TagRegion(userId, id, &region); err != nil {
+		utils.SendError(c, http.StatusNotFound, err.Error())
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusCreated, region)
+}
+
+// UntagRegion godoc
+// @Summary Remove a region tag
+// @Description Delete a region of the person, removing them from the asset when no other region shows them
+// @Tags persons
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Person ID"
+// @Param regionId path int true "Region ID"
+// @Security BearerAuth
+// @Success 204
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /persons/{id}/regions/{regionId} [delete]
+func (pc *PersonController) UntagRegion(c *gin.Context) {
+	userId, id, ok := personParams(c)
+	if !ok {
+		return
+	}
+
+	regionId, err := strconv.Atoi(c.Param("regionId"))
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid region ID")
+		return
+	}
+
+	if err := pc.personRepo.UntagRegion(userId, id, regionId); err != nil {
+		utils.SendError(c, http.StatusNotFound, err.Error())
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// SetKeyFace godoc
+// @Summary Choose the key face of a person
+// @Description Crop one of the person's regions into the face shown for them
+// @Tags persons
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Person ID"
+// @Security BearerAuth
+// @Param region body models.PersonKeyFaceRequest true "Region ID"
+// @Success 200 {object} models.Persons
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /persons/{id}/key [put]
+func (pc *PersonController) SetKeyFace(c *gin.Context) {
+	userId, id, ok := personParams(c)
+	if !ok {
+		return
+	}
+
+	var req models.PersonKeyFaceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	if err := pc.personRepo.SetKeyFace(userId, id, req.RegionId); err != nil {
+		switch {
+		case errors.Is(err, repositories.ErrEmptyRegion):
+			utils.SendError(c, http.StatusBadRequest, err.Error())
+		case strings.HasSuffix(err.Error(), "not found"):
+			utils.SendError(c, http.StatusNotFound, err.Error())
+		default:
+			log.Printf("Failed to crop key face of person %d: %v", id, err)
+			utils.SendError(c, http.StatusInternalServerError, "Failed to create key face")
+		}
+		return
+	}
+
+	person, err := pc.personRepo.GetPersonByID(userId, id)
+	if err != nil {
+		utils.SendError(c, http.StatusNotFound, "Person not found")
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, person)
+}
+
+// GetKeyFace godoc
+// @Summary Get the key face of a person
+// @Description Download the key face crop of a person as a JPEG
+// @Tags persons
+// @Produce  jpeg
+// @Param id path int true "Person ID"
+// @Security BearerAuth
+// @Success 200
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /persons/{id}/face [get]
+func (pc *PersonController) GetKeyFace(c *gin.Context) {
+	userId, id, ok := personParams(c)
+	if !ok {
+		return
+	}
+
+	person, err := pc.personRepo.GetPersonByID(userId, id)
+	if err != nil || person.KeyFaceId == 0 {
+		utils.SendError(c, http.StatusNotFound, "Key face not found")
+		return
+	}
+
+	c.Header("Content-Type", "image/jpeg")
+	utils.ServeBlob(c, storage.Blobs, repositories.FaceKey(userId, person.KeyFaceId), "", utils.CacheRevalidate)
+}
+
+// GetSuggestions godoc
+// @Summary Suggest people for detected faces
+// @Description Group the untagged detected faces by resemblance, with the person each group looks like
+// @Tags persons
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Success 200 {array} repositories.FaceSuggestion
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /persons/suggestions [get]
+func (pc *PersonController) GetSuggestions(c *gin.Context) {
+	userId, err := utils.GetUserID(c)
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	suggestions, err := pc.personRepo.GetSuggestions(userId)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "Failed to fetch suggestions")
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, suggestions)
+}
+
+// AssignRegions godoc
+// @Summary Tag detected faces
+// @Description Tag detected faces, usually a suggestion, with the person
+// @Tags persons
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Person ID"
+// @Security BearerAuth
+// @Param regions body models.AssignRegionsRequest true "Region IDs"
+// @Success 200 {array} models.FaceRegion
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /persons/{id}/faces [post]
+func (pc *PersonController) AssignRegions(c *gin.Context) {
+	userId, id, ok := personParams(c)
+	if !ok {
+		return
+	}
+
+	var req models.AssignRegionsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	if err := pc.personRepo.AssignRegions(userId, id, req.RegionIds); err != nil {
+		utils.SendError(c, http.StatusNotFound, err.Error())
+		return
+	}
+
+	regions, err := pc.personRepo.GetPersonRegions(userId, id)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "Failed to fetch person regions")
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, regions)
+}
+
+// personParams reads the user and person IDs of a request, sending an error when invalid
+func personParams(c *gin.Context) (int, int, bool) {
+	userId, err := utils.GetUserID(c)
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid user ID")
+		return 0, 0, false
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid person ID")
+		return 0, 0, false
+	}
+
+	return userId, id, true
+}
@@ -0,0 +1,112 @@
+package controllers
+
+import (
+	"github.com/mahdi-cpp/PhotoKit/models"
+	"github.com/mahdi-cpp/PhotoKit/repositories"
+	"github.com/mahdi-cpp/PhotoKit/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ShareLinkController struct {
+	shareLinkRepo *repositories.ShareLinkRepository
+}
+
+func NewShareLinkController(shareLinkRepo *repositories.ShareLinkRepository) *ShareLinkController {
+	return &ShareLinkController{shareLinkRepo: shareLinkRepo}
+}
+
+// ListShareLinks godoc
+// @Summary List share links
+// @Description Get the public share links of a user with their view counts
+// @Tags share-links
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Success 200 {array} repositories.ShareLinkSummary
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /share-links [get]
+func (sc *ShareLinkController) ListShareLinks(c *gin.Context) {
+	userId, err := utils.GetUserID(c)
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	links, err := sc.shareLinkRepo.ListShareLinks(userId)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "Failed to fetch share links")
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, links)
+}
+
+// CreateShareLink godoc
+// @Summary Create a share link
+// @Description Create a public link to an album or a selection of assets, served under /download/shared/{token}
+// @Tags share-links
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param link body models.CreateShareLinkRequest true "Share link data"
+// @Success 201 {object} models.ShareLink
+// @Failure 400 {object} utils.ErrorResponse
+// @Router /share-links [post]
+func (sc *ShareLinkController) CreateShareLink(c *gin.Context) {
+	userId, err := utils.GetUserID(c)
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	var req models.CreateShareLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	link, err := sc.shareLinkRepo.CreateShareLink(userId, req)
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusCreated, link)
+}
+
+// RevokeShareLink godoc
+// @Summary Revoke a share link
+// @Description Stop a share link from working
+// @Tags share-links
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Share link ID"
+// @Security BearerAuth
+// @Success 204
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /share-links/{id} [delete]
+func (sc *ShareLinkController) RevokeShareLink(c *gin.Context) {
+	userId, err := utils.GetUserID(c)
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid share link ID")
+		return
+	}
+
+	if err := sc.shareLinkRepo.RevokeShareLink(userId, id); err != nil {
+		utils.SendError(c, http.StatusNotFound, err.Error())
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,650 @@
+package controllers
+
+import (
+	"errors"
+	"github.com/mahdi-cpp/PhotoKit/models"
+	"github.com/mahdi-cpp/PhotoKit/repositories"
+	"github.com/mahdi-cpp/PhotoKit/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SharedAlbumController struct {
+	sharedAlbumRepo *repositories.SharedAlbumRepository
+}
+
+func NewSharedAlbumController(sharedAlbumRepo *repositories.SharedAlbumRepository) *SharedAlbumController {
+	return &SharedAlbumController{sharedAlbumRepo: sharedAlbumRepo}
+}
+
+// ListSharedAlbums godoc
+// @Summary List shared albums
+// @Description Get the shared albums the user owns or joined, with asset counts and key photos
+// @Tags shared-albums
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Success 200 {array} repositories.SharedAlbumSummary
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /shared-albums [get]
+func (sc *SharedAlbumController) ListSharedAlbums(c *gin.Context) {
+	userId, err := utils.GetUserID(c)
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	albums, err := sc.sharedAlbumRepo.ListSharedAlbums(userId)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "Failed to fetch shared albums")
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, albums)
+}
+
+// ListInvitations godoc
+// @Summary List shared album invitations
+// @Description Get the shared albums the user is invited to and did not accept yet
+// @Tags shared-albums
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Success 200 {array} repositories.SharedAlbumSummary
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /shared-albums/invitations [get]
+func (sc *SharedAlbumController) ListInvitations(c *gin.Context) {
+	userId, err := utils.GetUserID(c)
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	albums, err := sc.sharedAlbumRepo.ListInvitations(userId)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "Failed to fetch invitations")
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, albums)
+}
+
+// CreateSharedAlbum godoc
+// @Summary Create a shared album
+// @Description Create a new shared album owned by the user
+// @Tags shared-albums
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param album body models.CreateSharedAlbumRequest true "Shared album data"
+// @Success 201 {object} models.SharedAlbum
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /shared-albums [post]
+func (sc *SharedAlbumController) CreateSharedAlbum(c *gin.Context) {
+	userId, err := utils.GetUserID(c)
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	var req models.CreateSharedAlbumRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	album := models.SharedAlbum{
+		UserId: userId,
+		Named:  req.Named,
+	}
+
+	if err := sc.sharedAlbumRepo.CreateSharedAlbum(&album); err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "Failed to create shared album")
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusCreated, album)
+}
+
+// GetSharedAlbum godoc
+// @Summary Get a shared album
+// @Description Get a shared album, its members and a page of its assets with their reactions
+// @Tags shared-albums
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Shared album ID"
+// @Security BearerAuth
+// @Param limit query int false "Limit assets"
+// @Param offset query int false "Offset assets"
+// @Success 200 {object} repositories.SharedAlbumSummary
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /shared-albums/{id} [get]
+func (sc *SharedAlbumController) GetSharedAlbum(c *gin.Context) {
+	userId, id, ok := sharedAlbumParams(c)
+	if !ok {
+		return
+	}
+
+	album, members, err := sc.sharedAlbumRepo.GetSharedAlbum(userId, id)
+	if err != nil {
+		utils.SendError(c, http.StatusNotFound, "Shared album not found")
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+
+	assets, err := sc.sharedAlbumRepo.GetSharedAlbumAssets(userId, id, limit, offset)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "Failed to fetch shared album assets")
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, gin.H{
+		"album":   album,
+		"members": members,
+		"assets":  assets,
+	})
+}
+
+// RenameSharedAlbum godoc
+// @Summary Rename a shared album
+// @Description Change the name of a shared album, only its owner can
+// @Tags shared-albums
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Shared album ID"
+// @Security BearerAuth
+// @Param album body models.UpdateAlbumRequest true "Shared album update data"
+// @Success 200
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /shared-albums/{id} [put]
+func (sc *SharedAlbumController) RenameSharedAlbum(c *gin.Context) {
+	userId, id, ok := sharedAlbumParams(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateAlbumRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	if err := sc.sharedAlbumRepo.RenameSharedAlbum(userId, id, req.Named); err != nil {
+		sendSharedAlbumError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, gin.H{"named": req.Named})
+}
+
+// DeleteSharedAlbum godoc
+// @Summary Delete a shared album
+// @Description Delete a shared album, the assets stay in the libraries of their contributors
+// @Tags shared-albums
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Shared album ID"
+// @Security BearerAuth
+// @Success 204
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /shared-albums/{id} [delete]
+func (sc *SharedAlbumController) DeleteSharedAlbum(c *gin.Context) {
+	userId, id, ok := sharedAlbumParams(c)
+	if !ok {
+		return
+	}
+
+	if err := sc.sharedAlbumRepo.DeleteSharedAlbum(userId, id); err != nil {
+		sendSharedAlbumError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// InviteMember godoc
+// @Summary Invite a user
+// @Description Invite a user to a shared album as a viewer or a contributor, only its owner can
+// @Tags shared-albums
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Shared album ID"
+// @Security BearerAuth
+// @Param member body models.InviteMemberRequest true "Invited user and role"
+// @Success 201 {object} models.SharedAlbumMember
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /shared-albums/{id}/members [post]
+func (sc *SharedAlbumController) InviteMember(c *gin.Context) {
+	userId, id, ok := sharedAlbumParams(c)
+	if !ok {
+		return
+	}
+
+	var req models.InviteMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	member, err := sc.sharedAlbumRepo.InviteMember(userId, id, req.UserId, req.Role)
+	if err != nil {
+		sendSharedAlbumError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusCreated, member)
+}
+
+// UpdateMember godoc
+// @Summary Change the role of a member
+// @Description Make a member a viewer or a contributor, only the owner can
+// @Tags shared-albums
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Shared album ID"
+// @Param memberId path int true "Member user ID"
+// @Security BearerAuth
+// @Param member body models.UpdateMemberRequest true "Role"
+// @Success 200
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /shared-albums/{id}/members/{memberId} [put]
+func (sc *SharedAlbumController) UpdateMember(c *gin.Context) {
+	userId, id, ok := sharedAlbumParams(c)
+	if !ok {
+		return
+	}
+
+	memberId, err := strconv.Atoi(c.Param("memberId"))
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid member ID")
+		return
+	}
+
+	var req models.UpdateMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	if err := sc.sharedAlbumRepo.UpdateMemberRole(userId, id, memberId, req.Role); err != nil {
+		sendSharedAlbumError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, gin.H{"role": req.Role})
+}
+
+// RemoveMember godoc
+// @Summary Remove a member
+// @Description Remove a member with the assets they added, the owner can remove anybody and members can leave or decline
+// @Tags shared-albums
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Shared album ID"
+// @Param memberId path int true "Member user ID"
+// @Security BearerAuth
+// @Success 204
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /shared-albums/{id}/members/{memberId} [delete]
+func (sc *SharedAlbumController) RemoveMember(c *gin.Context) {
+	userId, id, ok := sharedAlbumParams(c)
+	if !ok {
+		return
+	}
+
+	memberId, err := strconv.Atoi(c.Param("memberId"))
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid member ID")
+		return
+	}
+
+	if err := sc.sharedAlbumRepo.RemoveMember(userId, id, memberId); err != nil {
+		sendSharedAlbumError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// AcceptInvitation godoc
+// @Summary Accept an invitation
+// @Description Join a shared album the user is invited to
+// @Tags shared-albums
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Shared album ID"
+// @Security BearerAuth
+// @Success 200
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /shared-albums/{id}/accept [post]
+func (sc *SharedAlbumController) AcceptInvitation(c *gin.Context) {
+	userId, id, ok := sharedAlbumParams(c)
+	if !ok {
+		return
+	}
+
+	if err := sc.sharedAlbumRepo.AcceptInvitation(userId, id); err != nil {
+		sendSharedAlbumError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, gin.H{"accepted": true})
+}
+
+// AddAssets godoc
+// @Summary Add assets to a shared album
+// @Description Add assets of the user to a shared album, viewers cannot and assets must allow sharing
+// @Tags shared-albums
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Shared album ID"
+// @Security BearerAuth
+// @Param assets body models.AlbumAssetsRequest true "Asset IDs"
+// @Success 200
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /shared-albums/{id}/assets [post]
+func (sc *SharedAlbumController) AddAssets(c *gin.Context) {
+	userId, id, ok := sharedAlbumParams(c)
+	if !ok {
+		return
+	}
+
+	var req models.AlbumAssetsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	added, err := sc.sharedAlbumRepo.AddAssets(userId, id, req.AssetIds)
+	if err != nil {
+		sendSharedAlbumError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, gin.H{"added": added})
+}
+
+// RemoveAssets godoc
+// @Summary Remove assets from a shared album
+// @Description Remove assets from a shared album, the owner can remove any and contributors the ones they added
+// @Tags shared-albums
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Shared album ID"
+// @Security BearerAuth
+// @Param assets body models.AlbumAssetsRequest true "Asset IDs"
+// @Success 200
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /shared-albums/{id}/assets [delete]
+func (sc *SharedAlbumController) RemoveAssets(c *gin.Context) {
+	userId, id, ok := sharedAlbumParams(c)
+	if !ok {
+		return
+	}
+
+	var req models.AlbumAssetsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	removed, err := sc.sharedAlbumRepo.RemoveAssets(userId, id, req.AssetIds)
+	if err != nil {
+		sendSharedAlbumError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, gin.H{"removed": removed})
+}
+
+// SetKeyAsset godoc
+// @Summary Set the shared album cover
+// @Description Choose the asset shown as the cover of a shared album, only its owner can
+// @Tags shared-albums
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Shared album ID"
+// @Security BearerAuth
+// @Param asset body models.AlbumKeyAssetRequest true "Key asset ID"
+// @Success 200
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /shared-albums/{id}/key [put]
+func (sc *SharedAlbumController) SetKeyAsset(c *gin.Context) {
+	userId, id, ok := sharedAlbumParams(c)
+	if !ok {
+		return
+	}
+
+	var req models.AlbumKeyAssetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	if err := sc.sharedAlbumRepo.SetKeyAsset(userId, id, req.AssetId); err != nil {
+		sendSharedAlbumError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, gin.H{"keyAssetId": req.AssetId})
+}
+
+// GetComments godoc
+// @Summary List the comments of an asset
+// @Description Get the comments of an asset of a shared album, oldest first
+// @Tags shared-albums
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Shared album ID"
+// @Param assetId path int true "Asset ID"
+// @Security BearerAuth
+// @Success 200 {array} models.SharedAlbumComment
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /shared-albums/{id}/assets/{assetId}/comments [get]
+func (sc *SharedAlbumController) GetComments(c *gin.Context) {
+	userId, id, assetId, ok := sharedAssetParams(c)
+	if !ok {
+		return
+	}
+
+	comments, err := sc.sharedAlbumRepo.GetComments(userId, id, assetId)
+	if err != nil {
+		sendSharedAlbumError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, comments)
+}
+
+// AddComment godoc
+// @Summary Comment an asset
+// @Description Comment an asset of a shared album, every member can
+// @Tags shared-albums
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Shared album ID"
+// @Param assetId path int true "Asset ID"
+// @Security BearerAuth
+// @Param comment body models.CommentRequest true "Comment"
+// @Success 201 {object} models.SharedAlbumComment
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /shared-albums/{id}/assets/{assetId}/comments [post]
+func (sc *SharedAlbumController) AddComment(c *gin.Context) {
+	userId, id, assetId, ok := sharedAssetParams(c)
+	if !ok {
+		return
+	}
+
+	var req models.CommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	comment, err := sc.sharedAlbumRepo.AddComment(userId, id, assetId, req.Text)
+	if err != nil {
+		sendSharedAlbumError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusCreated, comment)
+}
+
+// DeleteComment godoc
+// @Summary Delete a comment
+// @Description Delete a comment, its author and the owner of the album can
+// @Tags shared-albums
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Shared album ID"
+// @Param commentId path int true "Comment ID"
+// @Security BearerAuth
+// @Success 204
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /shared-albums/{id}/comments/{commentId} [delete]
+func (sc *SharedAlbumController) DeleteComment(c *gin.Context) {
+	userId, id, ok := sharedAlbumParams(c)
+	if !ok {
+		return
+	}
+
+	commentId, err := strconv.Atoi(c.Param("commentId"))
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid comment ID")
+		return
+	}
+
+	if err := sc.sharedAlbumRepo.DeleteComment(userId, id, commentId); err != nil {
+		sendSharedAlbumError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// LikeAsset godoc
+// @Summary Like an asset
+// @Description Like an asset of a shared album
+// @Tags shared-albums
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Shared album ID"
+// @Param assetId path int true "Asset ID"
+// @Security BearerAuth
+// @Success 204
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /shared-albums/{id}/assets/{assetId}/like [post]
+func (sc *SharedAlbumController) LikeAsset(c *gin.Context) {
+	userId, id, assetId, ok := sharedAssetParams(c)
+	if !ok {
+		return
+	}
+
+	if err := sc.sharedAlbumRepo.LikeAsset(userId, id, assetId); err != nil {
+		sendSharedAlbumError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// UnlikeAsset godoc
+// @Summary Unlike an asset
+// @Description Take back the like of the user on an asset of a shared album
+// @Tags shared-albums
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Shared album ID"
+// @Param assetId path int true "Asset ID"
+// @Security BearerAuth
+// @Success 204
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /shared-albums/{id}/assets/{assetId}/like [delete]
+func (sc *SharedAlbumController) UnlikeAsset(c *gin.Context) {
+	userId, id, assetId, ok := sharedAssetParams(c)
+	if !ok {
+		return
+	}
+
+	if err := sc.sharedAlbumRepo.UnlikeAsset(userId, id, assetId); err != nil {
+		sendSharedAlbumError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// sendSharedAlbumError answers 403 for what the role of the user does not allow and 404 otherwise
+func sendSharedAlbumError(c *gin.Context, err error) {
+	if errors.Is(err, repositories.ErrSharedAlbumForbidden) || errors.Is(err, repositories.ErrAssetNotSharable) {
+		utils.SendError(c, http.StatusForbidden, err.Error())
+		return
+	}
+
+	utils.SendError(c, http.StatusNotFound, err.Error())
+}
+
+// sharedAlbumParams reads the user and shared album IDs of a request, sending an error when invalid
+func sharedAlbumParams(c *gin.Context) (int, int, bool) {
+	userId, err := utils.GetUserID(c)
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid user ID")
+		return 0, 0, false
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid shared album ID")
+		return 0, 0, false
+	}
+
+	return userId, id, true
+}
+
+// sharedAssetParams also reads the asset ID of requests about one asset of a shared album
+func sharedAssetParams(c *gin.Context) (int, int, int, bool) {
+	userId, id, ok := sharedAlbumParams(c)
+	if !ok {
+		return 0, 0, 0, false
+	}
+
+	assetId, err := strconv.Atoi(c.Param("assetId"))
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid asset ID")
+		return 0, 0, 0, false
+	}
+
+	return userId, id, assetId, true
+}
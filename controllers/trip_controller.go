@@ -0,0 +1,237 @@
+package controllers
+
+import (
+	"github.com/mahdi-cpp/PhotoKit/models"
+	"github.com/mahdi-cpp/PhotoKit/repositories"
+	"github.com/mahdi-cpp/PhotoKit/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TripController struct {
+	tripRepo *repositories.TripRepository
+}
+
+func NewTripController(tripRepo *repositories.TripRepository) *TripController {
+	return &TripController{tripRepo: tripRepo}
+}
+
+// ListTrips godoc
+// @Summary List trips
+// @Description Get the confirmed and suggested trips of a user with asset counts and key photos
+// @Tags trips
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Success 200 {array} repositories.TripSummary
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /trips [get]
+func (tc *TripController) ListTrips(c *gin.Context) {
+	userId, err := utils.GetUserID(c)
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	trips, err := tc.tripRepo.ListTrips(userId)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "Failed to fetch trips")
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, trips)
+}
+
+// DetectTrips godoc
+// @Summary Detect trips
+// @Description Group the user's photos taken away from home into trips, replacing the unconfirmed suggestions
+// @Tags trips
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Success 200 {array} models.Trip
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /trips/detect [post]
+func (tc *TripController) DetectTrips(c *gin.Context) {
+	userId, err := utils.GetUserID(c)
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	trips, err := tc.tripRepo.DetectTrips(userId)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "Failed to detect trips")
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, trips)
+}
+
+// GetTrip godoc
+// @Summary Get a trip
+// @Description Get a trip and a page of its assets
+// @Tags trips
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Trip ID"
+// @Security BearerAuth
+// @Param limit query int false "Limit assets"
+// @Param offset query int false "Offset assets"
+// @Success 200 {object} models.Trip
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Failure 500 {object} utils.ErrorResponse
+// @Router /trips/{id} [get]
+func (tc *TripController) GetTrip(c *gin.Context) {
+	userId, id, ok := tripParams(c)
+	if !ok {
+		return
+	}
+
+	trip, err := tc.tripRepo.GetTripByID(userId, id)
+	if err != nil {
+		utils.SendError(c, http.StatusNotFound, "Trip not found")
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+
+	assets, err := tc.tripRepo.GetTripAssets(userId, id, limit, offset)
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, "Failed to fetch trip assets")
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, gin.H{
+		"trip":   trip,
+		"assets": assets,
+	})
+}
+
+// RenameTrip godoc
+// @Summary Rename a trip
+// @Description Change the name of a trip, a renamed suggestion is confirmed
+// @Tags trips
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Trip ID"
+// @Security BearerAuth
+// @Param trip body models.UpdateTripRequest true "Trip update data"
+// @Success 200 {object} models.Trip
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /trips/{id} [put]
+func (tc *TripController) RenameTrip(c *gin.Context) {
+	userId, id, ok := tripParams(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateTripRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	if err := tc.tripRepo.RenameTrip(userId, id, req.Named); err != nil {
+		utils.SendError(c, http.StatusNotFound, "Trip not found")
+		return
+	}
+
+	trip, err := tc.tripRepo.GetTripByID(userId, id)
+	if err != nil {
+		utils.SendError(c, http.StatusNotFound, "Trip not found")
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, trip)
+}
+
+// ConfirmTrip godoc
+// @Summary Confirm a trip
+// @Description Keep a suggested trip, later detections no longer replace it
+// @Tags trips
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Trip ID"
+// @Security BearerAuth
+// @Success 200 {object} models.Trip
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /trips/{id}/confirm [post]
+func (tc *TripController) ConfirmTrip(c *gin.Context) {
+	userId, id, ok := tripParams(c)
+	if !ok {
+		return
+	}
+
+	if err := tc.tripRepo.ConfirmTrip(userId, id); err != nil {
+		utils.SendError(c, http.StatusNotFound, "Trip not found")
+		return
+	}
+
+	trip, err := tc.tripRepo.GetTripByID(userId, id)
+	if err != nil {
+		utils.SendError(c, http.StatusNotFound, "Trip not found")
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, trip)
+}
+
+// MergeTrips godoc
+// @Summary Merge trips
+// @Description Merge trips into the first one of the list, the merged trip is confirmed
+// @Tags trips
+// @Accept  json
+// @Produce  json
+// @Security BearerAuth
+// @Param trips body models.MergeTripsRequest true "Trip IDs"
+// @Success 200 {object} models.Trip
+// @Failure 400 {object} utils.ErrorResponse
+// @Failure 404 {object} utils.ErrorResponse
+// @Router /trips/merge [post]
+func (tc *TripController) MergeTrips(c *gin.Context) {
+	userId, err := utils.GetUserID(c)
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	var req models.MergeTripsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	trip, err := tc.tripRepo.MergeTrips(userId, req.TripIds)
+	if err != nil {
+		utils.SendError(c, http.StatusNotFound, err.Error())
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, trip)
+}
+
+// tripParams reads the user and trip IDs of a request, sending an error when invalid
+func tripParams(c *gin.Context) (int, int, bool) {
+	userId, err := utils.GetUserID(c)
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid user ID")
+		return 0, 0, false
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid trip ID")
+		return 0, 0, false
+	}
+
+	return userId, id, true
+}
@@ -19,55 +19,26 @@ func NewUserController(userRepo *repositories.UserRepository) *UserController {
 	return &UserController{userRepo: userRepo}
 }
 
-// CreateUser godoc
-// @Summary Create a new user
-// @Description Create a new user with the input payload
-// @Tags users
-// @Accept  json
-// @Produce  json
-// @Param user body models.CreateUserRequest true "Create user"
-// @Success 201 {object} models.User
-// @Failure 400 {object} utils.ErrorResponse
-// @Failure 500 {object} utils.ErrorResponse
-// @Router /users [post]
-func (uc *UserController) CreateUser(c *gin.Context) {
-	var req models.CreateUserRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.SendError(c, http.StatusBadRequest, "Invalid request payload")
-		return
-	}
-
-	user := models.User{
-		Username:    req.Username,
-		PhoneNumber: req.PhoneNumber,
-		Email:       req.Email,
-		FirstName:   req.FirstName,
-		LastName:    req.LastName,
-		Bio:         req.Bio,
-		IsOnline:    true,
-		LastSeen:    time.Now(),
-	}
-
-	if err := uc.userRepo.CreateUser(&user); err != nil {
-		utils.SendError(c, http.StatusInternalServerError, "Failed to create user")
-		return
-	}
-
-	utils.SendSuccess(c, http.StatusCreated, user)
-}
-
 // GetUser godoc
 // @Summary Get a user by ID
-// @Description Get a user by ID
+// @Description Get a user by ID, the full record for the signed-in user and the public profile for others
 // @Tags users
 // @Accept  json
 // @Produce  json
 // @Param id path int true "User ID"
-// @Success 200 {object} models.User
+// @Success 200 {object} models.PublicUser
+// @Failure 400 {object} utils.ErrorResponse
 // @Failure 404 {object} utils.ErrorResponse
 // @Failure 500 {object} utils.ErrorResponse
+// @Security BearerAuth
 // @Router /users/{id} [get]
 func (uc *UserController) GetUser(c *gin.Context) {
+	userId, err := utils.GetUserID(c)
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		utils.SendError(c, http.StatusBadRequest, "Invalid user ID")
@@ -80,7 +51,7 @@ func (uc *UserController) GetUser(c *gin.Context) {
 		return
 	}
 
-	utils.SendSuccess(c, http.StatusOK, user)
+	utils.SendSuccess(c, http.StatusOK, visibleUser(userId, *user))
 }
 
 // UpdateUser godoc
@@ -93,13 +64,14 @@ func (uc *UserController) GetUser(c *gin.Context) {
 // @Param user body models.UpdateUserRequest true "Update user"
 // @Success 200 {object} models.User
 // @Failure 400 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
 // @Failure 404 {object} utils.ErrorResponse
 // @Failure 500 {object} utils.ErrorResponse
+// @Security BearerAuth
 // @Router /users/{id} [put]
 func (uc *UserController) UpdateUser(c *gin.Context) {
-	id, err := strconv.Atoi(c.Param("id"))
-	if err != nil {
-		utils.SendError(c, http.StatusBadRequest, "Invalid user ID")
+	id, ok := selfParams(c)
+	if !ok {
 		return
 	}
 
@@ -143,13 +115,14 @@ func (uc *UserController) UpdateUser(c *gin.Context) {
 // @Param id path int true "User ID"
 // @Success 204
 // @Failure 400 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
 // @Failure 404 {object} utils.ErrorResponse
 // @Failure 500 {object} utils.ErrorResponse
+// @Security BearerAuth
 // @Router /users/{id} [delete]
 func (uc *UserController) DeleteUser(c *gin.Context) {
-	id, err := strconv.Atoi(c.Param("id"))
-	if err != nil {
-		utils.SendError(c, http.StatusBadRequest, "Invalid user ID")
+	id, ok := selfParams(c)
+	if !ok {
 		return
 	}
 
@@ -163,16 +136,24 @@ func (uc *UserController) DeleteUser(c *gin.Context) {
 
 // ListUsers godoc
 // @Summary List all users
-// @Description Get a list of all users
+// @Description Get a list of all users, the full record for the signed-in user and the public profile for others
 // @Tags users
 // @Accept  json
 // @Produce  json
 // @Param limit query int false "Limit"
 // @Param offset query int false "Offset"
-// @Success 200 {array} models.User
+// @Success 200 {array} models.PublicUser
+// @Failure 400 {object} utils.ErrorResponse
 // @Failure 500 {object} utils.ErrorResponse
+// @Security BearerAuth
 // @Router /users [get]
 func (uc *UserController) ListUsers(c *gin.Context) {
+	userId, err := utils.GetUserID(c)
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
 	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
 
@@ -182,7 +163,12 @@ func (uc *UserController) ListUsers(c *gin.Context) {
 		return
 	}
 
-	utils.SendSuccess(c, http.StatusOK, users)
+	visible := make([]interface{}, 0, len(users))
+	for _, user := range users {
+		visible = append(visible, visibleUser(userId, user))
+	}
+
+	utils.SendSuccess(c, http.StatusOK, visible)
 }
 
 // UpdateOnlineStatus godoc
@@ -195,13 +181,14 @@ func (uc *UserController) ListUsers(c *gin.Context) {
 // @Param isOnline query bool true "Online status"
 // @Success 200 {object} models.User
 // @Failure 400 {object} utils.ErrorResponse
+// @Failure 403 {object} utils.ErrorResponse
 // @Failure 404 {object} utils.ErrorResponse
 // @Failure 500 {object} utils.ErrorResponse
+// @Security BearerAuth
 // @Router /users/{id}/online [put]
 func (uc *UserController) UpdateOnlineStatus(c *gin.Context) {
-	id, err := strconv.Atoi(c.Param("id"))
-	if err != nil {
-		utils.SendError(c, http.StatusBadRequest, "Invalid user ID")
+	id, ok := selfParams(c)
+	if !ok {
 		return
 	}
 
@@ -224,3 +211,33 @@ func (uc *UserController) UpdateOnlineStatus(c *gin.Context) {
 
 	utils.SendSuccess(c, http.StatusOK, user)
 }
+
+// visibleUser returns what the signed-in user may see of a user: their own full record, or the
+// public profile of someone else
+func visibleUser(userId int, user models.User) interface{} {
+	if user.ID == userId {
+		return user
+	}
+	return user.Public()
+}
+
+// selfParams reads the user ID of the path, users can only change their own account
+func selfParams(c *gin.Context) (int, bool) {
+	userId, err := utils.GetUserID(c)
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid user ID")
+		return 0, false
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid user ID")
+		return 0, false
+	}
+	if id != userId {
+		utils.SendError(c, http.StatusForbidden, "Users can only change their own account")
+		return 0, false
+	}
+
+	return id, true
+}
@@ -3,18 +3,24 @@ package main
 import (
 	"fmt"
 	"github.com/gin-gonic/gin"
+	"github.com/mahdi-cpp/PhotoKit/repositories"
 	"github.com/mahdi-cpp/PhotoKit/routes"
+	"github.com/mahdi-cpp/PhotoKit/utils"
+	"gorm.io/gorm"
 )
 
 var (
 	router = gin.Default()
 )
 
-func Run() {
+func Run(db *gorm.DB) {
 
 	router.Use(CORSMiddleware())
 
-	getRoutes()
+	getRoutes(db)
+
+	// Weekly memories for the pinned collections
+	go repositories.NewMemoryRepository(db).RunScheduler()
 
 	err := router.Run(":8095")
 	if err != nil {
@@ -23,13 +29,29 @@ func Run() {
 	}
 }
 
-func getRoutes() {
+func getRoutes(db *gorm.DB) {
 
 	v1 := router.Group("/v1")
 
-	routes.AddPhotosRoutes(v1)
-	routes.AddPhotosHomeRoutes(v1)
-	routes.AddDownloadRoutes(v1)
+	// Signing in and public share links work without an account
+	routes.SetupAuthRoutes(v1, db)
+	routes.AddSharedDownloadRoutes(v1, db)
+
+	// Everything else is scoped to the signed-in user
+	authorized := v1.Group("", utils.RequireAuth())
+
+	routes.AddPhotosRoutes(authorized)
+	routes.AddPhotosHomeRoutes(authorized, db)
+	routes.AddDownloadRoutes(authorized, db)
+	routes.AddCacheRoutes(authorized)
+
+	routes.SetupUserRoutes(authorized, db)
+	routes.SetupAssetRoutes(authorized, db)
+	routes.SetupAlbumRoutes(authorized, db)
+	routes.SetupTripRoutes(authorized, db)
+	routes.SetupPersonRoutes(authorized, db)
+	routes.SetupSharedAlbumRoutes(authorized, db)
+	routes.SetupShareLinkRoutes(authorized, db)
 }
 
 func CORSMiddleware() gin.HandlerFunc {
@@ -2,13 +2,17 @@ package main
 
 import (
 	"fmt"
+	"github.com/mahdi-cpp/PhotoKit/cache"
 	"github.com/mahdi-cpp/PhotoKit/config"
 	"github.com/mahdi-cpp/PhotoKit/repositories"
+	"github.com/mahdi-cpp/PhotoKit/storage"
+	"github.com/mahdi-cpp/PhotoKit/utils"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/schema"
 	"log"
 	"os"
+	"strings"
 )
 
 var db *gorm.DB
@@ -69,10 +73,66 @@ func main() {
 	//}
 	//fmt.Printf("Created user with ID: %d\n", newUser.ID)
 
-	repositories.CreateAssetOfUploadDirectory(db, 1)
+	//repositories.CreateAssetOfUploadDirectory(db, 1)
 	//repositories.CreateOnlyDatabase(db, 1)
 
 	//repositories.InitPhotos()
 	//cache.ReadIcons()
-	//Run()
+
+	storage.Blobs, err = openBlobStore(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(cfg.LegacyRoots) > 0 {
+		log.Printf("Indexed %d legacy files", storage.Keys.IndexLegacy(cfg.LegacyRoots))
+	}
+
+	utils.SetAuthSecret(cfg.AuthSecret)
+	repositories.ThumbnailCache = storage.NewLocalStore(cfg.ThumbnailCacheDir)
+	if err := cache.Configure(cfg.CachePolicy, cfg.ThumbCacheBytes, cfg.IconCacheBytes); err != nil {
+		log.Fatal(err)
+	}
+	switch {
+	case cfg.SMSFile != "":
+		log.Printf("Warning: one-time codes are written to %s instead of being sent, for development only", cfg.SMSFile)
+		repositories.SMSSender = utils.NewFileSender(cfg.SMSFile)
+	case cfg.SMSDevLog:
+		log.Println("Warning: SMS_DEV_LOG is set, one-time codes are logged instead of being sent, for development only")
+		repositories.SMSSender = utils.LogSender{}
+	default:
+		log.Println("Warning: no SMS sender is configured, signing in by one-time code is disabled")
+	}
+
+	// Face detection of ingested images
+	if command := strings.Fields(cfg.FaceDetector); len(command) > 0 {
+		repositories.FaceDetector = utils.NewProcessDetector(command[0], command[1:]...)
+	} else if cfg.FaceDetector != "" {
+		log.Fatal("FACE_DETECTOR must name a command")
+	}
+	repositories.FaceMatchDistance = cfg.FaceMatchDistance
+
+	if runCommand(db, os.Args[1:]) {
+		return
+	}
+
+	Run(db)
+}
+
+// openBlobStore returns the store of the asset library chosen by the configuration
+func openBlobStore(cfg *config.Config) (storage.BlobStore, error) {
+	switch cfg.BlobStore {
+	case "local":
+		return storage.NewLocalStore(cfg.AssetsRoot), nil
+	case "s3":
+		return storage.NewS3Store(storage.S3Config{
+			Endpoint:  cfg.S3Endpoint,
+			Region:    cfg.S3Region,
+			Bucket:    cfg.S3Bucket,
+			AccessKey: cfg.S3AccessKey,
+			SecretKey: cfg.S3SecretKey,
+			PathStyle: cfg.S3PathStyle,
+		})
+	default:
+		return nil, fmt.Errorf("unknown blob store: %s", cfg.BlobStore)
+	}
 }
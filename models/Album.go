@@ -3,8 +3,26 @@ package models
 import "time"
 
 type Album struct {
-	ID        int       `gorm:"primaryKey;autoIncrement" json:"id"`
-	UserId    int       `gorm:"references:users(id);onDelete:SET NULL" json:"userId"`
-	Named     string    `json:"named"`
-	CreatedAt time.Time `gorm:"default:now()" json:"createdAt"`
+	ID         int       `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserId     int       `gorm:"references:users(id);onDelete:SET NULL" json:"userId"`
+	Named      string    `json:"named"`
+	KeyAssetId int       `gorm:"default:0" json:"keyAssetId"`
+	CreatedAt  time.Time `gorm:"default:now()" json:"createdAt"`
+	UpdatedAt  time.Time `gorm:"autoUpdateTime" json:"updatedAt"`
+}
+
+type CreateAlbumRequest struct {
+	Named string `json:"named" binding:"required"`
+}
+
+type UpdateAlbumRequest struct {
+	Named string `json:"named" binding:"required"`
+}
+
+type AlbumAssetsRequest struct {
+	AssetIds []int `json:"assetIds" binding:"required"`
+}
+
+type AlbumKeyAssetRequest struct {
+	AssetId int `json:"assetId" binding:"required"`
 }
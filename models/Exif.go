@@ -0,0 +1,12 @@
+package models
+
+// Exif is the exposure information of a photo, NULL when the tag was not in its EXIF
+type Exif struct {
+	ExposureTime *float64 `json:"exposureTime"` // seconds
+	FNumber      *float64 `json:"fNumber"`
+	ISO          *int     `gorm:"column:iso" json:"iso"`
+	FocalLength  *float64 `json:"focalLength"` // millimeters
+	LensModel    string   `gorm:"default:NULL" json:"lensModel"`
+	Flash        *int     `json:"flash"`        // raw Flash tag, bit 0 is set when the flash fired
+	WhiteBalance *int     `json:"whiteBalance"` // 0 auto, 1 manual
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"github.com/lib/pq"
+	"time"
+)
+
+// FaceRegion is the area of an asset showing a person, as a bounding box normalized to the
+// displayed (orientation applied) image: X and Y are the top left corner, all values are 0 to 1.
+// Regions found by the face detector start with no person and are suggested for tagging.
+type FaceRegion struct {
+	ID       int     `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserId   int     `gorm:"references:users(id);onDelete:SET NULL" json:"userId"`
+	AssetId  int     `gorm:"index" json:"assetId"`
+	PersonId int     `gorm:"default:0;index" json:"personId"`
+	X        float64 `json:"x"`
+	Y        float64 `json:"y"`
+	Width    float64 `json:"width"`
+	Height   float64 `json:"height"`
+
+	IsDetected bool            `gorm:"default:false" json:"isDetected"`
+	Confidence float64         `gorm:"default:0" json:"confidence"`
+	Embedding  pq.Float32Array `gorm:"type:real[]" json:"-"`
+
+	CreatedAt time.Time `gorm:"default:now()" json:"createdAt"`
+}
+
+type TagRegionRequest struct {
+	AssetId int     `json:"assetId" binding:"required"`
+	X       float64 `json:"x" binding:"min=0,max=1"`
+	Y       float64 `json:"y" binding:"min=0,max=1"`
+	Width   float64 `json:"width" binding:"required,gt=0,max=1"`
+	Height  float64 `json:"height" binding:"required,gt=0,max=1"`
+}
+
+type AssignRegionsRequest struct {
+	RegionIds []int `json:"regionIds" binding:"required,min=1"`
+}
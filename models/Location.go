@@ -0,0 +1,11 @@
+package models
+
+// Location is where an asset was taken, read from the GPS IFD of its EXIF and
+// reverse geocoded to the nearest city of the offline city list
+type Location struct {
+	Latitude  *float64 `gorm:"index:idx_user_location,priority:2" json:"latitude"`
+	Longitude *float64 `gorm:"index:idx_user_location,priority:3" json:"longitude"`
+	Altitude  *float64 `json:"altitude"`
+	City      string   `gorm:"default:NULL" json:"city"`
+	Country   string   `gorm:"default:NULL" json:"country"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"github.com/lib/pq"
+	"time"
+)
+
+// Memory is a collection of the assets taken during one week of a past year, generated every
+// week for the same week of the previous years
+type Memory struct {
+	ID         int           `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserId     int           `gorm:"references:users(id);onDelete:SET NULL;uniqueIndex:idx_user_week_year" json:"userId"`
+	WeekStart  time.Time     `gorm:"type:timestamp;uniqueIndex:idx_user_week_year" json:"weekStart"` // Monday of the week it was generated for
+	Year       int           `gorm:"uniqueIndex:idx_user_week_year" json:"year"`
+	StartDate  time.Time     `gorm:"type:timestamp" json:"startDate"`
+	EndDate    time.Time     `gorm:"type:timestamp" json:"endDate"`
+	KeyAssetId int           `gorm:"default:0" json:"keyAssetId"`
+	AssetIds   pq.Int32Array `gorm:"type:integer[]" json:"assetIds"`
+	CreatedAt  time.Time     `gorm:"default:now()" json:"createdAt"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// OtpCode is a one-time code sent to a phone number to sign in, only its bcrypt hash is stored.
+// A code is used once, and a newer code replaces the unused ones of the same phone number.
+type OtpCode struct {
+	ID          int        `gorm:"primaryKey;autoIncrement" json:"id"`
+	PhoneNumber string     `gorm:"type:varchar(20);index" json:"phoneNumber"`
+	ClientIp    string     `gorm:"type:varchar(45);index" json:"-"` // address the code was requested from
+	CodeHash    string     `json:"-"`
+	Attempts    int        `gorm:"default:0" json:"attempts"` // codes entered
+	ExpiresAt   time.Time  `gorm:"type:timestamp" json:"expiresAt"`
+	UsedAt      *time.Time `gorm:"type:timestamp" json:"usedAt"`
+	CreatedAt   time.Time  `gorm:"default:now();index" json:"createdAt"`
+}
+
+type OtpRequest struct {
+	PhoneNumber string `json:"phoneNumber" binding:"required,max=20"`
+}
+
+type OtpVerifyRequest struct {
+	PhoneNumber string `json:"phoneNumber" binding:"required,max=20"`
+	Code        string `json:"code" binding:"required"`
+}
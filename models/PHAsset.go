@@ -7,7 +7,7 @@ import (
 
 type PHAsset struct {
 	ID     int `gorm:"primaryKey;autoIncrement" json:"id"`
-	UserId int `gorm:"references:users(id);onDelete:SET NULL" json:"userId"`
+	UserId int `gorm:"references:users(id);onDelete:SET NULL;uniqueIndex:idx_user_content_hash;index:idx_user_location,priority:1" json:"userId"`
 
 	// Media Characteristics
 	URL   string `json:"url"`
@@ -17,12 +17,23 @@ type PHAsset struct {
 	Format      string `json:"format"`
 	Orientation int    `json:"orientation"`
 
+	// SHA-256 of the original file, NULL for rows linked to an original with DuplicateOf
+	ContentHash string `gorm:"type:char(64);default:NULL;uniqueIndex:idx_user_content_hash" json:"contentHash"`
+	DuplicateOf int    `gorm:"default:0;index" json:"duplicateOf"`
+
+	// 64 bit difference hash, close values in Hamming distance mean similar images. Nil until
+	// computed, flat images hash to 0.
+	PerceptualHash *int64 `json:"perceptualHash"`
+
 	PixelWidth  int `json:"pixelWidth"`
 	PixelHeight int `json:"pixelHeight"`
 
 	CameraMake  string `gorm:"default:NULL" json:"CameraMake"`
 	CameraModel string `gorm:"default:NULL" json:"CameraModel"`
 
+	Exif     Exif     `gorm:"embedded" json:"exif"`
+	Location Location `gorm:"embedded" json:"location"`
+
 	IsFavorite bool `gorm:"default:false" json:"isFavorite"`
 	IsHidden   bool `gorm:"default:false" json:"isHidden"`
 
@@ -41,6 +52,9 @@ type PHAsset struct {
 	Trips   pq.Int32Array `gorm:"type:integer[]" json:"trips"`
 	Persons pq.Int32Array `gorm:"type:integer[]" json:"persons"`
 
+	// Set once the face detector ran on the asset
+	FacesDetected bool `gorm:"default:false" json:"facesDetected"`
+
 	ModificationDate time.Time `gorm:"type:timestamp;default:NULL"`
 	CreationDate     time.Time `gorm:"type:timestamp;not null"`
 }
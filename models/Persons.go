@@ -6,5 +6,25 @@ type Persons struct {
 	ID        int       `gorm:"primaryKey;autoIncrement" json:"id"`
 	UserId    int       `gorm:"references:users(id);onDelete:SET NULL" json:"userId"`
 	Named     string    `json:"named"`
+	IsHidden  bool      `gorm:"default:false" json:"isHidden"`
+	KeyFaceId int       `gorm:"default:0" json:"keyFaceId"` // FaceRegion the key face crop was made from
 	CreatedAt time.Time `gorm:"default:now()" json:"createdAt"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updatedAt"`
+}
+
+type CreatePersonRequest struct {
+	Named string `json:"named" binding:"required"`
+}
+
+type UpdatePersonRequest struct {
+	Named    *string `json:"named"`
+	IsHidden *bool   `json:"isHidden"`
+}
+
+type MergePersonsRequest struct {
+	PersonIds []int `json:"personIds" binding:"required,min=2"`
+}
+
+type PersonKeyFaceRequest struct {
+	RegionId int `json:"regionId" binding:"required"`
 }
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// RefreshToken is a long-lived token a client trades for new access tokens. Only the SHA-256 of
+// the token is stored, a token is used once and replaced by the one issued with it.
+type RefreshToken struct {
+	ID        int        `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserId    int        `gorm:"references:users(id);onDelete:CASCADE;index" json:"userId"`
+	TokenHash string     `gorm:"type:varchar(64);uniqueIndex" json:"-"`
+	ExpiresAt time.Time  `gorm:"type:timestamp" json:"expiresAt"`
+	RevokedAt *time.Time `gorm:"type:timestamp" json:"revokedAt"`
+	CreatedAt time.Time  `gorm:"default:now()" json:"createdAt"`
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"github.com/lib/pq"
+	"time"
+)
+
+// ShareLink gives people without an account access to an album or a selection of assets of its
+// user through a random token. Thumbnails can always be seen, originals only when allowed.
+type ShareLink struct {
+	ID             int           `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserId         int           `gorm:"references:users(id);onDelete:SET NULL;index" json:"userId"`
+	Token          string        `gorm:"type:varchar(64);uniqueIndex" json:"token"`
+	Named          string        `json:"named"`
+	AlbumId        int           `gorm:"default:0" json:"albumId"`       // the album, 0 for a selection
+	AssetIds       pq.Int32Array `gorm:"type:integer[]" json:"assetIds"` // the selection
+	PasswordHash   string        `gorm:"default:NULL" json:"-"`          // bcrypt, empty without password
+	AllowOriginals bool          `gorm:"default:false" json:"allowOriginals"`
+	ExpiresAt      *time.Time    `gorm:"type:timestamp" json:"expiresAt"`
+	ViewCount      int           `gorm:"default:0" json:"viewCount"`
+	RevokedAt      *time.Time    `gorm:"type:timestamp" json:"revokedAt"`
+	CreatedAt      time.Time     `gorm:"default:now()" json:"createdAt"`
+}
+
+// ShareUnlockFailure is a wrong password given for a share link, they throttle the guesses
+type ShareUnlockFailure struct {
+	ID        int       `gorm:"primaryKey;autoIncrement" json:"id"`
+	Token     string    `gorm:"type:varchar(64);index" json:"-"`
+	ClientIp  string    `gorm:"type:varchar(45);index" json:"-"`
+	CreatedAt time.Time `gorm:"default:now();index" json:"createdAt"`
+}
+
+type CreateShareLinkRequest struct {
+	Named          string     `json:"named"`
+	AlbumId        int        `json:"albumId"`
+	AssetIds       []int      `json:"assetIds"`
+	Password       string     `json:"password"`
+	AllowOriginals bool       `json:"allowOriginals"`
+	ExpiresAt      *time.Time `json:"expiresAt"`
+}
+
+// UnlockShareLinkRequest carries the password of a protected link when it is not in the
+// X-Share-Password header
+type UnlockShareLinkRequest struct {
+	Password string `json:"password"`
+}
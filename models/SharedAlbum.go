@@ -0,0 +1,73 @@
+package models
+
+import "time"
+
+// Roles of the members of a shared album, the owner can do everything
+const (
+	SharedRoleViewer      = "viewer"      // sees the assets, comments and likes
+	SharedRoleContributor = "contributor" // also adds and removes their own assets
+)
+
+// SharedAlbum is an album of assets from several users, owned by the user who created it
+type SharedAlbum struct {
+	ID         int       `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserId     int       `gorm:"references:users(id);onDelete:SET NULL;index" json:"userId"`
+	Named      string    `json:"named"`
+	KeyAssetId int       `gorm:"default:0" json:"keyAssetId"`
+	CreatedAt  time.Time `gorm:"default:now()" json:"createdAt"`
+	UpdatedAt  time.Time `gorm:"autoUpdateTime" json:"updatedAt"`
+}
+
+// SharedAlbumMember is a user invited to a shared album, the invitation is pending until accepted
+type SharedAlbumMember struct {
+	ID            int       `gorm:"primaryKey;autoIncrement" json:"id"`
+	SharedAlbumId int       `gorm:"uniqueIndex:idx_shared_album_member" json:"sharedAlbumId"`
+	UserId        int       `gorm:"uniqueIndex:idx_shared_album_member;index" json:"userId"`
+	Role          string    `gorm:"type:varchar(20)" json:"role"`
+	IsAccepted    bool      `gorm:"default:false" json:"isAccepted"`
+	InvitedBy     int       `json:"invitedBy"`
+	CreatedAt     time.Time `gorm:"default:now()" json:"createdAt"`
+}
+
+// SharedAlbumAsset is an asset of a shared album, with the member who added it
+type SharedAlbumAsset struct {
+	ID            int       `gorm:"primaryKey;autoIncrement" json:"id"`
+	SharedAlbumId int       `gorm:"uniqueIndex:idx_shared_album_asset" json:"sharedAlbumId"`
+	AssetId       int       `gorm:"uniqueIndex:idx_shared_album_asset;index" json:"assetId"`
+	ContributorId int       `json:"contributorId"`
+	CreatedAt     time.Time `gorm:"default:now()" json:"createdAt"`
+}
+
+type SharedAlbumComment struct {
+	ID            int       `gorm:"primaryKey;autoIncrement" json:"id"`
+	SharedAlbumId int       `gorm:"index:idx_shared_album_comment" json:"sharedAlbumId"`
+	AssetId       int       `gorm:"index:idx_shared_album_comment" json:"assetId"`
+	UserId        int       `json:"userId"`
+	Text          string    `gorm:"type:text" json:"text"`
+	CreatedAt     time.Time `gorm:"default:now()" json:"createdAt"`
+}
+
+type SharedAlbumLike struct {
+	ID            int       `gorm:"primaryKey;autoIncrement" json:"id"`
+	SharedAlbumId int       `gorm:"uniqueIndex:idx_shared_album_like" json:"sharedAlbumId"`
+	AssetId       int       `gorm:"uniqueIndex:idx_shared_album_like" json:"assetId"`
+	UserId        int       `gorm:"uniqueIndex:idx_shared_album_like" json:"userId"`
+	CreatedAt     time.Time `gorm:"default:now()" json:"createdAt"`
+}
+
+type CreateSharedAlbumRequest struct {
+	Named string `json:"named" binding:"required"`
+}
+
+type InviteMemberRequest struct {
+	UserId int    `json:"userId" binding:"required"`
+	Role   string `json:"role" binding:"required,oneof=viewer contributor"`
+}
+
+type UpdateMemberRequest struct {
+	Role string `json:"role" binding:"required,oneof=viewer contributor"`
+}
+
+type CommentRequest struct {
+	Text string `json:"text" binding:"required,max=2000"`
+}
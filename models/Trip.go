@@ -2,9 +2,28 @@ package models
 
 import "time"
 
+// Trip is a run of days spent away from home, suggested by the trip detection
+// and kept across detections once the user confirms, renames or merges it
 type Trip struct {
-	ID        int       `gorm:"primaryKey;autoIncrement" json:"id"`
-	UserId    int       `gorm:"references:users(id);onDelete:SET NULL" json:"userId"`
-	Named     string    `json:"named"`
-	CreatedAt time.Time `gorm:"default:now()" json:"createdAt"`
+	ID          int       `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserId      int       `gorm:"references:users(id);onDelete:SET NULL" json:"userId"`
+	Named       string    `json:"named"`
+	City        string    `gorm:"default:NULL" json:"city"`
+	Country     string    `gorm:"default:NULL" json:"country"`
+	Latitude    float64   `json:"latitude"`
+	Longitude   float64   `json:"longitude"`
+	StartDate   time.Time `gorm:"type:timestamp" json:"startDate"`
+	EndDate     time.Time `gorm:"type:timestamp" json:"endDate"`
+	KeyAssetId  int       `gorm:"default:0" json:"keyAssetId"`
+	IsConfirmed bool      `gorm:"default:false" json:"isConfirmed"`
+	CreatedAt   time.Time `gorm:"default:now()" json:"createdAt"`
+	UpdatedAt   time.Time `gorm:"autoUpdateTime" json:"updatedAt"`
+}
+
+type UpdateTripRequest struct {
+	Named string `json:"named" binding:"required"`
+}
+
+type MergeTripsRequest struct {
+	TripIds []int `json:"tripIds" binding:"required,min=2"`
 }
@@ -3,18 +3,39 @@ package models
 import "time"
 
 type User struct {
-	ID          int       `gorm:"primaryKey;autoIncrement" json:"id"`
-	Username    string    `gorm:"type:varchar(50);unique" json:"username"`
-	PhoneNumber string    `gorm:"type:varchar(20);unique;not null" json:"phoneNumber"`
-	Email       string    `gorm:"type:varchar(100)" json:"email"`
-	FirstName   string    `gorm:"type:varchar(50)" json:"firstName"`
-	LastName    string    `gorm:"type:varchar(50)" json:"lastName"`
-	Bio         string    `gorm:"type:text" json:"bio"`
-	AvatarURL   string    `gorm:"type:varchar(255)" json:"avatarUrl"`
-	IsOnline    bool      `gorm:"default:false" json:"isOnline"`
-	LastSeen    time.Time `json:"lastSeen"`
-	CreatedAt   time.Time `gorm:"autoCreateTime" json:"createdAt"`
-	UpdatedAt   time.Time `gorm:"autoUpdateTime" json:"updatedAt"`
+	ID           int       `gorm:"primaryKey;autoIncrement" json:"id"`
+	Username     string    `gorm:"type:varchar(50);unique" json:"username"`
+	PhoneNumber  string    `gorm:"type:varchar(20);unique;not null" json:"phoneNumber"`
+	Email        string    `gorm:"type:varchar(100)" json:"email"`
+	FirstName    string    `gorm:"type:varchar(50)" json:"firstName"`
+	LastName     string    `gorm:"type:varchar(50)" json:"lastName"`
+	Bio          string    `gorm:"type:text" json:"bio"`
+	AvatarURL    string    `gorm:"type:varchar(255)" json:"avatarUrl"`
+	PasswordHash string    `gorm:"default:NULL" json:"-"` // bcrypt, empty for users without a password
+	IsOnline     bool      `gorm:"default:false" json:"isOnline"`
+	LastSeen     time.Time `json:"lastSeen"`
+	CreatedAt    time.Time `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt    time.Time `gorm:"autoUpdateTime" json:"updatedAt"`
+}
+
+// PublicUser is what other users see of a user, without their phone number, email or activity
+type PublicUser struct {
+	ID        int    `json:"id"`
+	Username  string `json:"username"`
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+	AvatarURL string `json:"avatarUrl"`
+}
+
+// Public returns the public profile of the user
+func (u User) Public() PublicUser {
+	return PublicUser{
+		ID:        u.ID,
+		Username:  u.Username,
+		FirstName: u.FirstName,
+		LastName:  u.LastName,
+		AvatarURL: u.AvatarURL,
+	}
 }
 
 type CreateUserRequest struct {
@@ -35,3 +56,23 @@ type UpdateUserRequest struct {
 	AvatarURL string `json:"avatarUrl"`
 	IsOnline  bool   `json:"isOnline"`
 }
+
+type RegisterRequest struct {
+	Username    string `json:"username" binding:"required"`
+	PhoneNumber string `json:"phoneNumber" binding:"required"`
+	Password    string `json:"password" binding:"required,min=8,max=72"` // bcrypt reads 72 bytes at most
+	Email       string `json:"email"`
+	FirstName   string `json:"firstName"`
+	LastName    string `json:"lastName"`
+	Bio         string `json:"bio"`
+}
+
+// LoginRequest signs a user in by username or phone number
+type LoginRequest struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refreshToken" binding:"required"`
+}
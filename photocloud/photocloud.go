@@ -4,10 +4,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/gorilla/mux"
+	"github.com/mahdi-cpp/PhotoKit/storage"
 	"io"
 	"log"
 	"net/http"
-	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
@@ -15,11 +15,12 @@ import (
 	"time"
 )
 
+// Keys of the blobs of the storage system
 const (
-	AssetsDir     = "/var/cloud/applications/PhotoKit/photocloud/assets/" // Use external storage
-	MetadataDir   = "/var/cloud/applications/PhotoKit/photocloud/metadata/"
-	ThumbnailsDir = "/var/cloud/applications/PhotoKit/photocloud/thumbnails/"
-	IndexFile     = "/var/cloud/applications/PhotoKit/photocloud/index.dat"
+	AssetsPrefix     = "photocloud/assets/"
+	MetadataPrefix   = "photocloud/metadata/"
+	ThumbnailsPrefix = "photocloud/thumbnails/"
+	IndexKey         = "photocloud/index.dat"
 )
 
 // PHAsset represents a photo/video asset in the system
@@ -52,7 +53,8 @@ type AssetUpdate struct {
 }
 
 type StorageSystem struct {
-	mu sync.RWMutex
+	mu    sync.RWMutex
+	blobs storage.BlobStore
 
 	// Indexes
 	assetIndex    map[int]string   // ID -> filename
@@ -72,8 +74,10 @@ type StorageSystem struct {
 	indexDirty bool
 }
 
-func NewStorageSystem() (*StorageSystem, error) {
+// NewStorageSystem returns a storage system keeping its assets, metadata and index in blobs
+func NewStorageSystem(blobs storage.BlobStore) (*StorageSystem, error) {
 	s := &StorageSystem{
+		blobs:         blobs,
 		assetIndex:    make(map[int]string),
 		userIndex:     make(map[int][]int),
 		dateIndex:     make(map[string][]int),
@@ -83,14 +87,6 @@ func NewStorageSystem() (*StorageSystem, error) {
 		maxCacheSize:  1000,
 	}
 
-	// Ensure directories exist
-	dirs := []string{AssetsDir, MetadataDir, ThumbnailsDir}
-	for _, dir := range dirs {
-		if err := os.MkdirAll(dir, 0755); err != nil {
-			return nil, fmt.Errorf("failed to create directory %s: %v", dir, err)
-		}
-	}
-
 	// Load index
 	if err := s.loadIndex(); err != nil {
 		log.Printf("Could not load index: %v. Rebuilding...", err)
@@ -112,20 +108,12 @@ func (s *StorageSystem) UploadAsset(userID int, file io.Reader, filename string,
 	// Generate ID based on timestamp to avoid collisions
 	id := int(time.Now().UnixNano())
 
-	// Create file paths
+	// Create file keys
 	ext := filepath.Ext(filename)
 	assetFilename := fmt.Sprintf("%d%s", id, ext)
-	assetPath := filepath.Join(AssetsDir, assetFilename)
-	metaPath := filepath.Join(MetadataDir, fmt.Sprintf("%d.json", id))
 
 	// Save asset file
-	assetFile, err := os.Create(assetPath)
-	if err != nil {
-		return nil, err
-	}
-	defer assetFile.Close()
-
-	if _, err := io.Copy(assetFile, file); err != nil {
+	if err := s.blobs.Put(AssetsPrefix+assetFilename, file, size, ""); err != nil {
 		return nil, err
 	}
 
@@ -146,7 +134,7 @@ func (s *StorageSystem) UploadAsset(userID int, file io.Reader, filename string,
 		return nil, err
 	}
 
-	if err := os.WriteFile(metaPath, metaData, 0644); err != nil {
+	if err := storage.PutBytes(s.blobs, metadataKey(id), metaData, "application/json"); err != nil {
 		return nil, err
 	}
 
@@ -347,13 +335,15 @@ func (s *StorageSystem) removeFromTextIndex(id int, word string) {
 	}
 }
 
-// getAssetFromDisk loads asset directly from disk (bypasses cache)
-func (s *StorageSystem) getAssetFromDisk(id int) (*PHAsset, error) {
-	// Get metadata file path
-	metaPath := filepath.Join(MetadataDir, fmt.Sprintf("%d.json", id))
+// metadataKey returns the key of the metadata of an asset
+func metadataKey(id int) string {
+	return MetadataPrefix + fmt.Sprintf("%d.json", id)
+}
 
+// getAssetFromDisk loads asset directly from the store (bypasses cache)
+func (s *StorageSystem) getAssetFromDisk(id int) (*PHAsset, error) {
 	// Read file
-	data, err := os.ReadFile(metaPath)
+	data, err := storage.ReadBlob(s.blobs, metadataKey(id))
 	if err != nil {
 		return nil, err
 	}
@@ -367,11 +357,8 @@ func (s *StorageSystem) getAssetFromDisk(id int) (*PHAsset, error) {
 	return &asset, nil
 }
 
-// saveAssetToDisk saves asset to disk
+// saveAssetToDisk saves asset to the store
 func (s *StorageSystem) saveAssetToDisk(asset *PHAsset) error {
-	// Get metadata file path
-	metaPath := filepath.Join(MetadataDir, fmt.Sprintf("%d.json", asset.ID))
-
 	// Marshal to JSON
 	data, err := json.MarshalIndent(asset, "", "  ")
 	if err != nil {
@@ -379,7 +366,7 @@ func (s *StorageSystem) saveAssetToDisk(asset *PHAsset) error {
 	}
 
 	// Write to file
-	return os.WriteFile(metaPath, data, 0644)
+	return storage.PutBytes(s.blobs, metadataKey(asset.ID), data, "application/json")
 }
 
 // getAsset retrieves asset (uses cache when possible)
@@ -462,7 +449,7 @@ func (s *StorageSystem) rebuildIndex() error {
 	s.favoriteIndex = make(map[int]bool)
 
 	// Get list of metadata files
-	files, err := os.ReadDir(MetadataDir)
+	files, err := s.blobs.List(MetadataPrefix)
 	if err != nil {
 		return err
 	}
@@ -477,18 +464,16 @@ func (s *StorageSystem) rebuildIndex() error {
 
 		batch := files[i:end]
 		for _, file := range batch {
-			if file.IsDir() {
-				continue
-			}
+			name := strings.TrimPrefix(file.Key, MetadataPrefix)
 
 			// Only process JSON files
-			if filepath.Ext(file.Name()) != ".json" {
+			if filepath.Ext(name) != ".json" {
 				continue
 			}
 
 			// Parse asset ID from filename
 			var id int
-			_, err := fmt.Sscanf(file.Name(), "%d.json", &id)
+			_, err := fmt.Sscanf(name, "%d.json", &id)
 			if err != nil {
 				continue
 			}
@@ -526,7 +511,7 @@ func (s *StorageSystem) rebuildIndex() error {
 	return nil
 }
 
-// saveIndex persists indexes to disk
+// saveIndex persists indexes to the store
 func (s *StorageSystem) saveIndex() error {
 	// Create index data structure
 	indexData := struct {
@@ -550,12 +535,12 @@ func (s *StorageSystem) saveIndex() error {
 	}
 
 	// Write to file
-	return os.WriteFile(IndexFile, data, 0644)
+	return storage.PutBytes(s.blobs, IndexKey, data, "application/json")
 }
 
-// loadIndex loads indexes from disk
+// loadIndex loads indexes from the store
 func (s *StorageSystem) loadIndex() error {
-	data, err := os.ReadFile(IndexFile)
+	data, err := storage.ReadBlob(s.blobs, IndexKey)
 	if err != nil {
 		return err
 	}
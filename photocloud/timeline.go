@@ -0,0 +1,199 @@
+package photocloud
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/mahdi-cpp/PhotoKit/utils"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TimelinePage is a page of the assets of one bucket, newest first
+type TimelinePage struct {
+	Bucket     string     `json:"bucket"`
+	Assets     []*PHAsset `json:"assets"`
+	NextCursor string     `json:"nextCursor"` // empty on the last page
+}
+
+// Timeline counts the user's visible assets per year, month or day from the date index, newest first
+func (s *StorageSystem) Timeline(userID int, granularity string) []utils.TimelineBucket {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	owned := s.visibleUserAssets(userID)
+
+	counts := make(map[string]int)
+	for dateKey, ids := range s.dateIndex {
+		bucket := utils.BucketOfDay(dateKey, granularity)
+		for _, id := range ids {
+			if owned[id] {
+				counts[bucket]++
+			}
+		}
+	}
+
+	buckets := make([]utils.TimelineBucket, 0, len(counts))
+	for bucket, count := range counts {
+		buckets = append(buckets, utils.TimelineBucket{Bucket: bucket, Count: count})
+	}
+	sort.Slice(buckets, func(i, j int) bool {
+		return buckets[i].Bucket > buckets[j].Bucket
+	})
+
+	return buckets
+}
+
+// BucketAssets retrieves a page of the user's visible assets in one bucket, the cursor is the
+// NextCursor of the previous page. Assets are ordered by day of the date index, then by creation
+// date and ID, so that the days before the cursor are skipped and only the metadata of the days
+// of the page is loaded.
+func (s *StorageSystem) BucketAssets(userID int, bucket string, cursor string, limit int) (*TimelinePage, error) {
+	if _, _, _, err := utils.ParseBucket(bucket); err != nil {
+		return nil, err
+	}
+
+	// The cursor names the day of the last asset of the previous page, then its date and ID
+	cursorDay, cursorDate, cursorID := "", time.Time{}, 0
+	if cursor != "" {
+		day, position, ok := strings.Cut(cursor, ".")
+		if !ok || !strings.HasPrefix(day, bucket) {
+			return nil, errors.New("invalid cursor")
+		}
+		date, id, err := utils.DecodeCursor(position)
+		if err != nil {
+			return nil, err
+		}
+		cursorDay, cursorDate, cursorID = day, date, id
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	owned := s.visibleUserAssets(userID)
+
+	var days []string
+	for dateKey := range s.dateIndex {
+		if strings.HasPrefix(dateKey, bucket) && (cursorDay == "" || dateKey <= cursorDay) {
+			days = append(days, dateKey)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(days)))
+
+	// One asset past the page tells whether there is a next one
+	var assets []*PHAsset
+	var assetDays []string
+	for _, day := range days {
+		if len(assets) > limit {
+			break
+		}
+
+		var dayAssets []*PHAsset
+		for _, id := range s.dateIndex[day] {
+			if !owned[id] {
+				continue
+			}
+			asset, err := s.getAsset(id)
+			if err != nil {
+				log.Printf("Error loading asset %d: %v", id, err)
+				continue
+			}
+			if day == cursorDay && !(asset.CreationDate.Before(cursorDate) || (asset.CreationDate.Equal(cursorDate) && asset.ID < cursorID)) {
+				continue
+			}
+			dayAssets = append(dayAssets, asset)
+		}
+
+		sort.Slice(dayAssets, func(i, j int) bool {
+			if !dayAssets[i].CreationDate.Equal(dayAssets[j].CreationDate) {
+				return dayAssets[i].CreationDate.After(dayAssets[j].CreationDate)
+			}
+			return dayAssets[i].ID > dayAssets[j].ID
+		})
+
+		assets = append(assets, dayAssets...)
+		for range dayAssets {
+			assetDays = append(assetDays, day)
+		}
+	}
+
+	page := &TimelinePage{Bucket: bucket, Assets: assets}
+	if len(assets) > limit {
+		page.Assets = assets[:limit]
+		last := page.Assets[limit-1]
+		page.NextCursor = assetDays[limit-1] + "." + utils.EncodeCursor(last.CreationDate, last.ID)
+	}
+	if page.Assets == nil {
+		page.Assets = []*PHAsset{}
+	}
+
+	return page, nil
+}
+
+// visibleUserAssets returns the IDs of the user's assets that are not hidden
+func (s *StorageSystem) visibleUserAssets(userID int) map[int]bool {
+	owned := make(map[int]bool, len(s.userIndex[userID]))
+	for _, id := range s.userIndex[userID] {
+		if !s.hiddenIndex[id] {
+			owned[id] = true
+		}
+	}
+	return owned
+}
+
+// TimelineHandler API Handler for the timeline buckets
+func TimelineHandler(s *StorageSystem) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := strconv.Atoi(r.URL.Query().Get("userId"))
+		if err != nil {
+			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		granularity := r.URL.Query().Get("granularity")
+		if granularity == "" {
+			granularity = utils.GranularityMonth
+		}
+		if !utils.ValidGranularity(granularity) {
+			http.Error(w, "Invalid granularity", http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"granularity": granularity,
+			"buckets":     s.Timeline(userID, granularity),
+		})
+	}
+}
+
+// BucketAssetsHandler API Handler for the assets of a timeline bucket
+func BucketAssetsHandler(s *StorageSystem) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := strconv.Atoi(r.URL.Query().Get("userId"))
+		if err != nil {
+			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		// Apply default limit
+		limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+		if err != nil || limit <= 0 || limit > 500 {
+			limit = 100
+		}
+
+		page, err := s.BucketAssets(userID, r.URL.Query().Get("bucket"), r.URL.Query().Get("cursor"), limit)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(page)
+	}
+}
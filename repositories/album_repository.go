@@ -0,0 +1,205 @@
+package repositories
+
+import (
+	"errors"
+	"github.com/lib/pq"
+	"github.com/mahdi-cpp/PhotoKit/models"
+	"log"
+
+	"gorm.io/gorm"
+)
+
+type AlbumRepository struct {
+	db *gorm.DB
+}
+
+func NewAlbumRepository(db *gorm.DB) *AlbumRepository {
+
+	// Auto migrate the Album models
+	err := db.AutoMigrate(&models.Album{})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return &AlbumRepository{db: db}
+}
+
+// CreateAlbum creates a new album for a user
+func (r *AlbumRepository) CreateAlbum(album *models.Album) error {
+	if album == nil {
+		return errors.New("album cannot be nil")
+	}
+
+	result := r.db.Create(album)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+// GetAlbumByID retrieves an album owned by the user
+func (r *AlbumRepository) GetAlbumByID(userId, id int) (*models.Album, error) {
+	var album models.Album
+	result := r.db.Where("user_id = ?", userId).First(&album, id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, errors.New("album not found")
+		}
+		return nil, result.Error
+	}
+
+	return &album, nil
+}
+
+// RenameAlbum changes the name of an album
+func (r *AlbumRepository) RenameAlbum(userId, id int, named string) error {
+	result := r.db.Model(&models.Album{}).
+		Where("id = ? AND user_id = ?", id, userId).
+		Update("named", named)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("album not found")
+	}
+
+	return nil
+}
+
+// DeleteAlbum deletes an album and removes it from the albums array of its assets
+func (r *AlbumRepository) DeleteAlbum(userId, id int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("user_id = ?", userId).Delete(&models.Album{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("album not found")
+		}
+
+		return tx.Model(&models.PHAsset{}).
+			Where("user_id = ? AND albums @> ?", userId, pq.Int32Array{int32(id)}).
+			Update("albums", gorm.Expr("array_remove(albums, ?)", id)).Error
+	})
+}
+
+// AddAssets appends the album to the albums array of the given assets
+func (r *AlbumRepository) AddAssets(userId, id int, assetIds []int) (int64, error) {
+	if _, err := r.GetAlbumByID(userId, id); err != nil {
+		return 0, err
+	}
+
+	result := r.db.Model(&models.PHAsset{}).
+		Where("user_id = ? AND id IN ?", userId, assetIds).
+		Where("NOT (COALESCE(albums, '{}') @> ?)", pq.Int32Array{int32(id)}).
+		Update("albums", gorm.Expr("array_append(COALESCE(albums, '{}'), ?)", id))
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	return result.RowsAffected, nil
+}
+
+// RemoveAssets removes the album from the albums array of the given assets
+func (r *AlbumRepository) RemoveAssets(userId, id int, assetIds []int) (int64, error) {
+	album, err := r.GetAlbumByID(userId, id)
+	if err != nil {
+		return 0, err
+	}
+
+	var removed int64
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.PHAsset{}).
+			Where("user_id = ? AND id IN ? AND albums @> ?", userId, assetIds, pq.Int32Array{int32(id)}).
+			Update("albums", gorm.Expr("array_remove(albums, ?)", id))
+		if result.Error != nil {
+			return result.Error
+		}
+		removed = result.RowsAffected
+
+		// Clear the key photo if it is no longer part of the album
+		for _, assetId := range assetIds {
+			if assetId == album.KeyAssetId {
+				return tx.Model(album).Update("key_asset_id", 0).Error
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return removed, nil
+}
+
+// SetKeyAsset chooses the asset shown as the album cover
+func (r *AlbumRepository) SetKeyAsset(userId, id, assetId int) error {
+	album, err := r.GetAlbumByID(userId, id)
+	if err != nil {
+		return err
+	}
+
+	var count int64
+	result := r.db.Model(&models.PHAsset{}).
+		Where("id = ? AND user_id = ? AND albums @> ?", assetId, userId, pq.Int32Array{int32(id)}).
+		Count(&count)
+	if result.Error != nil {
+		return result.Error
+	}
+	if count == 0 {
+		return errors.New("asset not in album")
+	}
+
+	return r.db.Model(album).Update("key_asset_id", assetId).Error
+}
+
+// GetAlbumAssets retrieves the assets of an album with pagination
+func (r *AlbumRepository) GetAlbumAssets(userId, id, limit, offset int) ([]models.PHAsset, error) {
+	var assets []models.PHAsset
+	result := r.db.Where("user_id = ? AND albums @> ?", userId, pq.Int32Array{int32(id)}).
+		Order("creation_date desc").
+		Limit(limit).Offset(offset).
+		Find(&assets)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return assets, nil
+}
+
+// ListAlbums retrieves the albums of a user with their asset counts and key photos
+func (r *AlbumRepository) ListAlbums(userId int) ([]AlbumSummary, error) {
+	var albums []models.Album
+	result := r.db.Where("user_id = ?", userId).Order("created_at desc").Find(&albums)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	countByAlbum, err := albumGroup.countAssets(r.db, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	chosen := make(map[int]int, len(albums))
+	for _, album := range albums {
+		chosen[album.ID] = album.KeyAssetId
+	}
+	keyByAlbum, err := albumGroup.keyAssets(r.db, userId, "creation_date desc", chosen)
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make([]AlbumSummary, 0, len(albums))
+	for _, album := range albums {
+		summary := AlbumSummary{
+			Album:      album,
+			AssetCount: countByAlbum[album.ID],
+			KeyAsset:   keyByAlbum[album.ID],
+		}
+
+		summaries = append(summaries, summary)
+	}
+
+	return summaries, nil
+}
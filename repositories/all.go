@@ -20,18 +20,6 @@ func InitPhotos() {
 	//FetchLibraries("/var/cloud/00-instagram/razzle/", true)
 	//FetchLibraries("/var/cloud/00-instagram/video/", true)
 
-	var a = "/var/cloud/family/"
-	var m = "/var/cloud/family/"
-
-	GetRecently("/var/cloud/00-instagram/ashtonhall/")
-	GetPeoples("/var/cloud/people/")
-	GetTrips(m)
-	GetPinned("/var/cloud/00-instagram/razzle-photo/")
-	GetPinnedGallery(a)
-
-	albumDTO = GetAlbums(a)
-
-	shareAlbumDTO = GetShareAlbums("/var/cloud/00-instagram/razzle-photo/")
 	cameraDTO = GetCameras("/var/cloud/00-instagram/video/")
 
 	utils.GetCities()
@@ -51,21 +39,21 @@ func ReloadSubtitle() {
 	newSubTitle, _ = GetSubtitle()
 }
 
-func RestCollections() map[string]any {
+func RestCollections(recentDays RecentDaysDTO, people []PersonSummary, pinned PinnedCollectionDTO, albums []AlbumSummary, sharedAlbums []SharedAlbumSummary, trips []TripSummary) map[string]any {
 	return gin.H{
-		"recentDaysDTO":       recentDaysDTO,
-		"peopleDTO":           peopleDTO,
-		"tripDTO":             tripDTO,
-		"pinnedCollectionDTO": pinnedCollectionDTO,
-		"albumDTO":            albumDTO,
-		"shareAlbumDTO":       shareAlbumDTO,
+		"recentDaysDTO":       recentDays,
+		"peopleDTO":           PeopleDTO{PeopleGroup: people},
+		"tripDTO":             TripDTO{Trips: trips},
+		"pinnedCollectionDTO": pinned,
+		"albumDTO":            AlbumDTO{Albums: albums},
+		"shareAlbumDTO":       ShareAlbumDTO{Albums: sharedAlbums},
 		"cameraDTO":           cameraDTO,
 	}
 }
 
-func RestRecentDays() map[string]any {
+func RestRecentDays(recentDays RecentDaysDTO) map[string]any {
 	return gin.H{
-		"recentDaysDTO": recentDaysDTO,
+		"recentDaysDTO": recentDays,
 	}
 }
 
@@ -75,20 +63,50 @@ func RestCamera() map[string]any {
 	}
 }
 
-func RestAlbums() map[string]any {
+func RestAlbums(albums []AlbumSummary) map[string]any {
+	return gin.H{
+		"albumDTO": AlbumDTO{Albums: albums},
+	}
+}
+
+func RestMap(zoom int, clusters []MapCluster) map[string]any {
+	return gin.H{
+		"mapDTO": MapDTO{Zoom: zoom, Clusters: clusters},
+	}
+}
+
+func RestTimeline(granularity string, buckets []utils.TimelineBucket) map[string]any {
 	return gin.H{
-		"albumDTO": albumDTO,
+		"timelineDTO": TimelineDTO{Granularity: granularity, Buckets: buckets},
 	}
 }
 
-func RestTrips() map[string]any {
+func RestTimelineBucket(page TimelinePage) map[string]any {
 	return gin.H{
-		"tripDTO": tripDTO,
+		"timelineBucketDTO": page,
 	}
 }
-func RestPinnedCollections() map[string]any {
+
+func RestSharedAlbums(sharedAlbums []SharedAlbumSummary) map[string]any {
+	return gin.H{
+		"shareAlbumDTO": ShareAlbumDTO{Albums: sharedAlbums},
+	}
+}
+
+func RestTrips(trips []TripSummary) map[string]any {
+	return gin.H{
+		"tripDTO": TripDTO{Trips: trips},
+	}
+}
+func RestPinnedCollections(pinned PinnedCollectionDTO) map[string]any {
+	return gin.H{
+		"pinnedCollectionDTO": pinned,
+	}
+}
+
+func RestMemories(date string, memories []MemoryYear) map[string]any {
 	return gin.H{
-		"pinnedCollectionDTO": pinnedCollectionDTO2,
+		"memoriesDTO": MemoriesDTO{Date: date, Memories: memories},
 	}
 }
 
@@ -1,6 +1,8 @@
 package repositories
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"github.com/disintegration/imaging"
 	"github.com/google/uuid"
@@ -11,40 +13,39 @@ import (
 	"image"
 	"log"
 	"os"
+	"path"
 	"strconv"
 	"strings"
 	"time"
 )
 
-var PHAssetsPath = "/var/cloud/applications/PhotoKit/Assets/"
 var uploadPath = "/var/cloud/applications/PhotoKit/upload/"
 
-func checkUrlExists(named string) (bool, error) {
+func checkUrlExists(userId int, named string) (bool, error) {
 	var exists bool
 	err := db.Model(&models.PHAsset{}).
 		Select("count(*) > 0").
-		Where("url = ?", named).
+		Where("user_id = ? AND url = ?", userId, named).
 		Find(&exists).
 		Error
 	return exists, err
 }
 
-func checkAssetExists(named string) (bool, error) {
+func checkAssetExists(userId int, named string) (bool, error) {
 	var exists bool
 	err := db.Model(&models.PHAsset{}).
 		Select("count(*) > 0").
-		Where("named = ?", named).
+		Where("user_id = ? AND named = ?", userId, named).
 		Find(&exists).
 		Error
 	return exists, err
 }
 
-func CreateAssetOfUploadDirectory(db1 *gorm.DB, id int) {
+// CreateAssetOfUploadDirectory ingests every file of the upload directory into the library of the user
+func CreateAssetOfUploadDirectory(db1 *gorm.DB, userId int) {
 
 	db = db1
 
-	var userIdPath = strconv.FormatInt(int64(id), 10) + "/"
-
 	files, err := os.ReadDir(uploadPath)
 	if err != nil {
 		fmt.Println(err)
@@ -54,148 +55,267 @@ func CreateAssetOfUploadDirectory(db1 *gorm.DB, id int) {
 
 		var named = file.Name()
 
-		found, textFile, line, err := storage.SearchTextInFiles(PHAssetsPath+userIdPath, ".txt", named, true)
-		if err != nil {
-			fmt.Printf("Error: %v\n", err)
-			//continue
+		asset, err := IngestFile(db, userId, uploadPath+named, named, uploadPath+named)
+		if errors.Is(err, ErrUnsupportedFormat) {
+			continue
 		}
-		if found {
-			fmt.Printf("Found text in %s (line %d)\n", textFile, line)
+		if errors.Is(err, ErrDuplicateAsset) {
+			fmt.Printf("%s is a duplicate of asset %d\n", named, asset.ID)
 			continue
 		}
+		if err != nil {
+			log.Printf("Failed to ingest %s: %v", named, err)
+			continue
+		}
+		fmt.Printf("Created PHAsset: %+v\n", *asset)
+	}
+}
 
-		if strings.HasSuffix(file.Name(), ".jpg") || strings.HasSuffix(file.Name(), ".JPG") || strings.HasSuffix(file.Name(), ".jpeg") || strings.HasSuffix(file.Name(), ".JPEG") {
+// ErrUnsupportedFormat is returned by IngestFile for files of an unknown format
+var ErrUnsupportedFormat = errors.New("unsupported file format")
 
-			var assetUrl = uuid.New().String()
-			var assetFormat = ".jpg"
+// IngestFile stores a file in the user's library under a UUID name, reads its
+// metadata from the source file, writes the JSON sidecar, inserts the database
+// row, creates the thumbnails and detects the faces of images. When the library
+// already holds the same content the existing asset is returned with
+// ErrDuplicateAsset. The modification time of mtimePath dates the asset when
+// neither EXIF nor the name do, an empty mtimePath dates it now.
+func IngestFile(db *gorm.DB, userId int, sourcePath string, named string, mtimePath string) (*models.PHAsset, error) {
 
-			err = storage.CopyFile(uploadPath+file.Name(), PHAssetsPath+userIdPath+assetUrl+assetFormat)
-			if err != nil {
-				panic(err)
-			}
+	assetFormat, mediaType, err := utils.DetectFormat(sourcePath)
+	if errors.Is(err, utils.ErrUnknownFormat) {
+		return nil, ErrUnsupportedFormat
+	}
+	if err != nil {
+		return nil, fmt.Errorf("detect format: %w", err)
+	}
 
-			//textFile, err := os.OpenFile(PHAssetsPath+userIdPath+assetUrl+".txt", os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0644)
-			//if err != nil {
-			//	panic(err)
-			//}
-			//defer textFile.Close()
-			//
-			//if _, err := textFile.WriteString(file.Name() + "\n"); err != nil {
-			//	panic(err)
-			//}
-
-			var portrait = false
-			var Orientation = 0
+	contentHash, err := storage.FileSHA256(sourcePath)
+	if err != nil {
+		return nil, fmt.Errorf("hash file: %w", err)
+	}
 
-			var a = PHAssetsPath + userIdPath + assetUrl + ".jpg"
+	existing, err := findAssetByHash(db, userId, contentHash)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return existing, ErrDuplicateAsset
+	}
 
-			var cameraMake = ""
-			var cameraModel = ""
+	var assetUrl = uuid.New().String()
 
-			if utils.PhotoHasExifData(a) {
-				has, orientation := utils.ReadExifData(a)
+	asset := models.PHAsset{
+		UserId:      userId,
+		URL:         assetUrl,
+		Named:       named,
+		MediaType:   mediaType,
+		Format:      assetFormat,
+		ContentHash: contentHash,
+	}
 
-				if has {
-					fmt.Println("Orientation: ", orientation)
-					if strings.Compare(orientation, "6") == 0 {
-						portrait = true
-					}
+	var thumbnailSource image.Image
+	var portrait = false
 
-					i, err := strconv.Atoi(orientation)
-					if err != nil {
-						fmt.Println("Orientation: ", err)
-					} else {
-						Orientation = i
-					}
-				}
+	if mediaType == "video" {
+		thumbnailSource, err = readVideoAsset(&asset, sourcePath)
+	} else {
+		thumbnailSource, portrait, err = readImageAsset(&asset, sourcePath, assetFormat)
+	}
+	if err != nil {
+		return nil, err
+	}
 
-				cMake, cModel, err := utils.GetCameraModel(a)
-				if err != nil {
-					log.Printf("Warning: error getting camera info: %v", err)
-					cameraMake = ""
-					cameraModel = ""
-				} else {
-					cameraMake = cMake
-					cameraModel = cModel
+	// Videos already have the creation time of their container
+	if asset.CreationDate.IsZero() {
+		asset.CreationDate = creationDate(sourcePath, mediaType, named, mtimePath)
+	}
 
-					// Convert to NULL if empty after sanitization
-					if cameraMake == "" {
-						cameraMake = "NULL" // For raw SQL, or use sql.NullString
-					}
-					if cameraModel == "" {
-						cameraModel = "NULL"
-					}
-				}
+	var assetKey = storage.Keys.Original(asset)
+	err = storage.PutFile(storage.Blobs, assetKey, sourcePath, utils.ContentTypeOf(assetFormat))
+	if err != nil {
+		return nil, fmt.Errorf("store file: %w", err)
+	}
 
-			} else {
-				fmt.Println("not exif data")
-			}
+	// Save the asset
+	err = storage.SaveAsset(&asset)
+	if err != nil {
+		storage.Blobs.Delete(assetKey)
+		return nil, fmt.Errorf("save asset: %w", err)
+	}
 
-			w, h := getImageDimension(PHAssetsPath + userIdPath + assetUrl + ".jpg")
-			var width = 0
-			var height = 0
-			if Orientation == 6 {
-				width = h
-				height = w
-			} else {
-				width = w
-				height = h
-			}
+	// Save the asset to the database
+	if err := db.Create(&asset).Error; err != nil {
+		storage.Blobs.Delete(assetKey)
+		storage.Blobs.Delete(storage.Keys.Sidecar(asset))
 
-			asset := models.PHAsset{
-				URL:         assetUrl,
-				Named:       named,
-				MediaType:   "image",
-				Format:      "jpg",
-				Orientation: Orientation,
+		// A concurrent ingest of the same content wins the unique index
+		if existing, findErr := findAssetByHash(db, userId, contentHash); findErr == nil && existing != nil {
+			return existing, ErrDuplicateAsset
+		}
+		return nil, fmt.Errorf("create PHAsset: %w", err)
+	}
 
-				CameraMake:  cameraMake,
-				CameraModel: cameraModel,
+	for _, size := range ThumbnailSizes {
+		if err := createTinyAssetFromImage(thumbnailSource, asset, size, portrait); err != nil {
+			log.Printf("Failed to create %d thumbnail of %s: %v", size, assetUrl, err)
+		}
+	}
 
-				PixelWidth:  width,
-				PixelHeight: height,
+	// Face detection reads the thumbnail of formats that cannot be decoded, it runs after the
+	// upload returns and BackfillFaces picks up what it misses
+	if mediaType == "image" {
+		queueFaceDetection(db, asset)
+	}
+
+	return &asset, nil
+}
+
+// creationDate resolves when an asset was taken, falling back to now when nothing is known
+func creationDate(assetPath string, mediaType string, named string, mtimePath string) time.Time {
+	var exifPath = assetPath
+	if mediaType == "video" {
+		exifPath = ""
+	}
 
-				CreationDate: time.Now(),
+	date, source := utils.ResolveCreationDate(exifPath, named, mtimePath)
+	if source == "" {
+		return time.Now()
+	}
+	return date
+}
+
+// readImageAsset fills in the EXIF orientation, camera, dimensions and perceptual
+// hash of a photo and returns the decoded image for the thumbnails. GIFs use their
+// first frame, HEIC images only get the dimensions of their container.
+func readImageAsset(asset *models.PHAsset, assetPath string, assetFormat string) (image.Image, bool, error) {
+
+	var portrait = false
+	var Orientation = 0
+
+	var cameraMake = ""
+	var cameraModel = ""
+
+	if utils.PhotoHasExifData(assetPath) {
+		has, orientation := utils.ReadExifData(assetPath)
+
+		if has {
+			fmt.Println("Orientation: ", orientation)
+			if strings.Compare(orientation, "6") == 0 {
+				portrait = true
 			}
 
-			// Save the asset
-			err = storage.SaveAsset(&asset, userIdPath)
+			i, err := strconv.Atoi(orientation)
 			if err != nil {
-				fmt.Println("Error saving asset:", err)
-				return
+				fmt.Println("Orientation: ", err)
+			} else {
+				Orientation = i
 			}
+		}
 
-			// Save the chat to the database
-			if err := db.Debug().Create(&asset).Error; err != nil {
-				log.Printf("Failed to create PHAsset: %v", err)
-			} else {
-				fmt.Printf("Created PHAsset: %+v\n", asset)
-				CreateTinyAsset(file.Name(), assetUrl, userIdPath, 540, portrait)
-				CreateTinyAsset(file.Name(), assetUrl, userIdPath, 270, portrait)
-				CreateTinyAsset(file.Name(), assetUrl, userIdPath, 135, portrait)
-				CreateTinyAsset(file.Name(), assetUrl, userIdPath, 70, portrait)
+		cMake, cModel, err := utils.GetCameraModel(assetPath)
+		if err != nil {
+			log.Printf("Warning: error getting camera info: %v", err)
+			cameraMake = ""
+			cameraModel = ""
+		} else {
+			cameraMake = cMake
+			cameraModel = cModel
+
+			// Convert to NULL if empty after sanitization
+			if cameraMake == "" {
+				cameraMake = "NULL" // For raw SQL, or use sql.NullString
 			}
+			if cameraModel == "" {
+				cameraModel = "NULL"
+			}
+		}
+
+	} else {
+		fmt.Println("not exif data")
+	}
+
+	asset.Orientation = Orientation
+	asset.CameraMake = cameraMake
+	asset.CameraModel = cameraModel
+
+	readAssetExif(asset, assetPath)
+	readAssetLocation(asset, assetPath)
+
+	if assetFormat == "heic" {
+		meta, err := utils.ReadHeifMetadata(assetPath)
+		if err != nil {
+			return nil, false, fmt.Errorf("read heif metadata: %w", err)
 		}
+		asset.PixelWidth = meta.PixelWidth
+		asset.PixelHeight = meta.PixelHeight
+		return utils.PlaceholderImage(meta.PixelWidth, meta.PixelHeight), false, nil
+	}
+
+	img, err := imaging.Open(assetPath)
+	if err != nil {
+		return nil, false, fmt.Errorf("decode image: %w", err)
+	}
+
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	var width = 0
+	var height = 0
+	if Orientation == 6 {
+		width = h
+		height = w
+	} else {
+		width = w
+		height = h
 	}
+
+	asset.PixelWidth = width
+	asset.PixelHeight = height
+	hash := int64(utils.DHash(img))
+	asset.PerceptualHash = &hash
+
+	return img, portrait, nil
+}
+
+// readVideoAsset fills in the duration, dimensions and creation time of a video
+// from its container and returns the poster frame for the thumbnails
+func readVideoAsset(asset *models.PHAsset, assetPath string) (image.Image, error) {
+
+	meta, err := utils.ReadVideoMetadata(assetPath)
+	if err != nil {
+		return nil, fmt.Errorf("read video metadata: %w", err)
+	}
+
+	asset.Duration = meta.Duration
+	asset.PixelWidth = meta.PixelWidth
+	asset.PixelHeight = meta.PixelHeight
+	if !meta.CreationTime.IsZero() {
+		asset.CreationDate = meta.CreationTime
+	}
+
+	return utils.VideoPoster(assetPath, meta), nil
 }
 
 func CreateOnlyDatabase(db1 *gorm.DB, userId int) {
 
 	db = db1
 
-	var userIdPath = strconv.FormatInt(int64(userId), 10) + "/"
-
-	files, err := os.ReadDir(PHAssetsPath + userIdPath)
+	blobs, err := storage.Blobs.List(storage.Keys.UserDir(userId))
 	if err != nil {
 		fmt.Println(err)
 	}
 
-	for _, file := range files {
+	for _, blob := range blobs {
 
-		var named = strings.Replace(file.Name(), ".jpg", "", 1)
+		// Only the originals, not the thumbnails and faces
+		var filename = path.Base(blob.Key)
+		if blob.Key != storage.Keys.UserDir(userId)+filename {
+			continue
+		}
+
+		var named = strings.Replace(filename, ".jpg", "", 1)
 
 		// Check if asset exists by name
-		exists, err := checkUrlExists(named)
+		exists, err := checkUrlExists(userId, named)
 		if err != nil {
 			fmt.Printf("Error checking product: %v\n", err)
 			continue
@@ -206,10 +326,14 @@ func CreateOnlyDatabase(db1 *gorm.DB, userId int) {
 			continue
 		}
 
-		if strings.HasSuffix(file.Name(), ".jpg") || strings.HasSuffix(file.Name(), ".JPG") || strings.HasSuffix(file.Name(), ".jpeg") || strings.HasSuffix(file.Name(), ".JPEG") {
+		if strings.HasSuffix(filename, ".jpg") || strings.HasSuffix(filename, ".JPG") || strings.HasSuffix(filename, ".jpeg") || strings.HasSuffix(filename, ".JPEG") {
 
 			var Orientation = 0
-			var a = PHAssetsPath + userIdPath + file.Name()
+			a, release, err := storage.Fetch(storage.Blobs, blob.Key)
+			if err != nil {
+				log.Printf("Failed to fetch %s: %v", blob.Key, err)
+				continue
+			}
 
 			var cameraMake = ""
 			var cameraModel = ""
@@ -253,7 +377,7 @@ func CreateOnlyDatabase(db1 *gorm.DB, userId int) {
 				fmt.Println("not exif data")
 			}
 
-			w, h := getImageDimension(PHAssetsPath + userIdPath + file.Name())
+			w, h := getImageDimension(a)
 			var width = 0
 			var height = 0
 			if Orientation == 6 {
@@ -277,8 +401,9 @@ func CreateOnlyDatabase(db1 *gorm.DB, userId int) {
 				PixelWidth:  width,
 				PixelHeight: height,
 
-				CreationDate: time.Now(),
+				CreationDate: creationDate(a, "image", filename, a),
 			}
+			release()
 
 			// Save the chat to the database
 			if err := db.Debug().Create(&newPHAsset).Error; err != nil {
@@ -288,16 +413,23 @@ func CreateOnlyDatabase(db1 *gorm.DB, userId int) {
 	}
 }
 
-func CreateTinyAsset(sourceName string, assetNewName string, userIdPath string, createSize int, portrait bool) {
+// ThumbnailSizes are the widths of the thumbnails created for every asset
+var ThumbnailSizes = []int{540, 270, 135, 70}
+
+func CreateTinyAsset(sourcePath string, asset models.PHAsset, createSize int, portrait bool) error {
 
-	file := uploadPath + sourceName
-	fmt.Println("CreateTinyAsset: ", sourceName, createSize)
+	fmt.Println("CreateTinyAsset: ", sourcePath, createSize)
 
-	srcImage, err := imaging.Open(file)
+	srcImage, err := imaging.Open(sourcePath)
 	if err != nil {
-		panic(err)
+		return err
 	}
 
+	return createTinyAssetFromImage(srcImage, asset, createSize, portrait)
+}
+
+func createTinyAssetFromImage(srcImage image.Image, asset models.PHAsset, createSize int, portrait bool) error {
+
 	var dstImage *image.NRGBA
 
 	if portrait {
@@ -310,12 +442,12 @@ func CreateTinyAsset(sourceName string, assetNewName string, userIdPath string,
 		dstImage = imaging.Resize(srcImage, createSize, 0, imaging.Lanczos)
 	}
 
-	var name2 = PHAssetsPath + userIdPath + "thumbnail/" + assetNewName + "_" + strconv.Itoa(createSize) + ".jpg"
-
-	err = imaging.Save(dstImage, name2)
-	if err != nil {
-		panic(err)
+	var buf bytes.Buffer
+	if err := imaging.Encode(&buf, dstImage, imaging.JPEG); err != nil {
+		return err
 	}
+
+	return storage.PutBytes(storage.Blobs, storage.Keys.Thumbnail(asset, createSize), buf.Bytes(), "image/jpeg")
 }
 
 func getImageDimension(imagePath string) (int, int) {
@@ -0,0 +1,66 @@
+package repositories
+
+import (
+	"fmt"
+	"github.com/mahdi-cpp/PhotoKit/models"
+	"github.com/mahdi-cpp/PhotoKit/storage"
+	"github.com/mahdi-cpp/PhotoKit/utils"
+	"gorm.io/gorm"
+	"log"
+	"time"
+)
+
+// BackfillCreationDates sets the creation date of the user's assets from their EXIF,
+// video container or file name. Assets for which none is found keep their date, as
+// the modification time of the stored copy is only the import time.
+func BackfillCreationDates(db *gorm.DB, userId int) error {
+
+	var updated = 0
+
+	var assets []models.PHAsset
+	result := db.Where("user_id = ?", userId).FindInBatches(&assets, 500, func(tx *gorm.DB, batch int) error {
+		for i := range assets {
+			asset := &assets[i]
+			assetPath, release, err := fetchOriginal(*asset)
+			if err != nil {
+				log.Printf("Failed to fetch asset %d: %v", asset.ID, err)
+				continue
+			}
+
+			var date time.Time
+			if asset.MediaType == "video" {
+				if meta, err := utils.ReadVideoMetadata(assetPath); err == nil {
+					date = meta.CreationTime
+				}
+			}
+			if date.IsZero() {
+				exifPath := assetPath
+				if asset.MediaType == "video" {
+					exifPath = ""
+				}
+				date, _ = utils.ResolveCreationDate(exifPath, asset.Named, "")
+			}
+			release()
+
+			if date.IsZero() || date.Equal(asset.CreationDate) {
+				continue
+			}
+
+			asset.CreationDate = date
+			if err := db.Model(asset).Update("creation_date", date).Error; err != nil {
+				return err
+			}
+			if err := storage.SaveAsset(asset); err != nil {
+				log.Printf("Failed to update sidecar of asset %d: %v", asset.ID, err)
+			}
+			updated++
+		}
+		return nil
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	fmt.Printf("Updated the creation date of %d assets\n", updated)
+	return nil
+}
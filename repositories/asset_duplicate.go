@@ -0,0 +1,111 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"github.com/mahdi-cpp/PhotoKit/models"
+	"github.com/mahdi-cpp/PhotoKit/storage"
+	"gorm.io/gorm"
+	"log"
+)
+
+// ErrDuplicateAsset is returned by IngestFile together with the existing asset
+// when the user's library already holds a file with the same content
+var ErrDuplicateAsset = errors.New("asset already exists")
+
+// DuplicateGroup is an original asset with the assets that have the same content
+type DuplicateGroup struct {
+	Original   models.PHAsset   `json:"original"`
+	Duplicates []models.PHAsset `json:"duplicates"`
+}
+
+// findAssetByHash returns the user's asset with the content hash, nil when there is none
+func findAssetByHash(db *gorm.DB, userId int, contentHash string) (*models.PHAsset, error) {
+	var asset models.PHAsset
+	result := db.Where("user_id = ? AND content_hash = ?", userId, contentHash).Limit(1).Find(&asset)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+	return &asset, nil
+}
+
+// GetDuplicateGroups lists the user's assets that were linked to an original with the same content
+func GetDuplicateGroups(db *gorm.DB, userId int) ([]DuplicateGroup, error) {
+	var duplicates []models.PHAsset
+	result := db.Where("user_id = ? AND duplicate_of <> 0", userId).
+		Order("duplicate_of, creation_date").
+		Find(&duplicates)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	var originalIds []int
+	byOriginal := make(map[int][]models.PHAsset)
+	for _, asset := range duplicates {
+		if _, exists := byOriginal[asset.DuplicateOf]; !exists {
+			originalIds = append(originalIds, asset.DuplicateOf)
+		}
+		byOriginal[asset.DuplicateOf] = append(byOriginal[asset.DuplicateOf], asset)
+	}
+
+	groups := make([]DuplicateGroup, 0, len(originalIds))
+	if len(originalIds) == 0 {
+		return groups, nil
+	}
+
+	var originals []models.PHAsset
+	result = db.Where("user_id = ? AND id IN ?", userId, originalIds).Find(&originals)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	for _, original := range originals {
+		groups = append(groups, DuplicateGroup{
+			Original:   original,
+			Duplicates: byOriginal[original.ID],
+		})
+	}
+
+	return groups, nil
+}
+
+// BackfillContentHashes computes the content hash of the user's assets that have none.
+// Assets whose content is already in the library are linked to it with DuplicateOf.
+func BackfillContentHashes(db *gorm.DB, userId int) error {
+
+	var assets []models.PHAsset
+	result := db.Where("user_id = ? AND content_hash IS NULL AND duplicate_of = 0", userId).
+		Order("id").
+		Find(&assets)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	for _, asset := range assets {
+		contentHash, err := storage.BlobSHA256(storage.Blobs, storage.Keys.Original(asset))
+		if err != nil {
+			log.Printf("Failed to hash asset %d: %v", asset.ID, err)
+			continue
+		}
+
+		original, err := findAssetByHash(db, userId, contentHash)
+		if err != nil {
+			return err
+		}
+
+		if original != nil {
+			err = db.Model(&asset).Update("duplicate_of", original.ID).Error
+			fmt.Printf("Asset %d is a duplicate of %d\n", asset.ID, original.ID)
+		} else {
+			err = db.Model(&asset).Update("content_hash", contentHash).Error
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,106 @@
+package repositories
+
+import (
+	"github.com/mahdi-cpp/PhotoKit/models"
+	"github.com/mahdi-cpp/PhotoKit/utils"
+	"gorm.io/gorm"
+	"log"
+)
+
+// AssetMetadata is the full EXIF tag dump of an asset grouped by IFD path
+type AssetMetadata struct {
+	AssetId int                         `json:"assetId"`
+	Exif    models.Exif                 `json:"exif"`
+	Ifds    map[string][]utils.IfdEntry `json:"ifds"`
+}
+
+func readAssetExif(asset *models.PHAsset, assetPath string) bool {
+	technical, err := utils.GetExifTechnical(assetPath)
+	if err != nil {
+		return false
+	}
+
+	asset.Exif = models.Exif{
+		ExposureTime: technical.ExposureTime,
+		FNumber:      technical.FNumber,
+		ISO:          technical.ISO,
+		FocalLength:  technical.FocalLength,
+		LensModel:    technical.LensModel,
+		Flash:        technical.Flash,
+		WhiteBalance: technical.WhiteBalance,
+	}
+
+	return true
+}
+
+// GetAssetMetadata reads every EXIF tag of one of the user's assets from its original file
+func GetAssetMetadata(db *gorm.DB, userId int, assetId int) (*AssetMetadata, error) {
+
+	var asset models.PHAsset
+	err := db.Where("id = ? AND user_id = ?", assetId, userId).First(&asset).Error
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := &AssetMetadata{
+		AssetId: asset.ID,
+		Exif:    asset.Exif,
+		Ifds:    make(map[string][]utils.IfdEntry),
+	}
+
+	// Videos and images without EXIF have an empty dump
+	if asset.MediaType != "image" {
+		return metadata, nil
+	}
+
+	assetPath, release, err := fetchOriginal(asset)
+	if err != nil {
+		return metadata, nil
+	}
+	defer release()
+
+	entries, _ := utils.ReadExifEntries(assetPath)
+	for _, entry := range entries {
+		metadata.Ifds[entry.IfdPath] = append(metadata.Ifds[entry.IfdPath], entry)
+	}
+
+	return metadata, nil
+}
+
+// BackfillExif reads the exposure information of the user's photos that were ingested before it was stored
+func BackfillExif(db *gorm.DB, userId int) error {
+
+	var assets []models.PHAsset
+	result := db.Where("user_id = ? AND media_type = ? AND exposure_time IS NULL AND f_number IS NULL AND iso IS NULL", userId, "image").
+		FindInBatches(&assets, 500, func(tx *gorm.DB, batch int) error {
+			for i := range assets {
+				asset := &assets[i]
+				assetPath, release, err := fetchOriginal(*asset)
+				if err != nil {
+					log.Printf("Failed to fetch asset %d: %v", asset.ID, err)
+					continue
+				}
+				found := readAssetExif(asset, assetPath)
+				release()
+				if !found {
+					continue
+				}
+
+				err = db.Model(asset).Updates(map[string]interface{}{
+					"exposure_time": asset.Exif.ExposureTime,
+					"f_number":      asset.Exif.FNumber,
+					"iso":           asset.Exif.ISO,
+					"focal_length":  asset.Exif.FocalLength,
+					"lens_model":    asset.Exif.LensModel,
+					"flash":         asset.Exif.Flash,
+					"white_balance": asset.Exif.WhiteBalance,
+				}).Error
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+
+	return result.Error
+}
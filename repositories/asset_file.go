@@ -0,0 +1,90 @@
+package repositories
+
+import (
+	"errors"
+	"github.com/mahdi-cpp/PhotoKit/models"
+	"github.com/mahdi-cpp/PhotoKit/storage"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+var ErrFileNotFound = errors.New("file not found")
+
+// AccessibleAssets scopes a query of assets to the ones the user can see: their own and the assets
+// of the shared albums they own or accepted to join
+func AccessibleAssets(userId int) func(tx *gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		db := tx.Session(&gorm.Session{NewDB: true})
+		members := db.Model(&models.SharedAlbumMember{}).Select("shared_album_id").
+			Where("user_id = ? AND is_accepted = ?", userId, true)
+		albums := db.Model(&models.SharedAlbum{}).Select("id").
+			Where("user_id = ? OR id IN (?)", userId, members)
+		shared := db.Model(&models.SharedAlbumAsset{}).Select("asset_id").
+			Where("shared_album_id IN (?)", albums)
+
+		return tx.Where("user_id = ? OR id IN (?)", userId, shared)
+	}
+}
+
+// UserAssetFile resolves a download file name, an original "<url>.<format>" or a thumbnail
+// "<url>_<size>.jpg", to its blob when the user can see the asset
+func UserAssetFile(db *gorm.DB, userId int, filename string) (string, error) {
+	filename = path.Base(filename)
+	named := strings.TrimSuffix(filename, path.Ext(filename))
+
+	var asset models.PHAsset
+	result := db.Select("id, user_id, url, format").Scopes(AccessibleAssets(userId)).Where("url = ?", named).First(&asset)
+	if result.Error == nil {
+		if filename != asset.URL+"."+asset.Format {
+			return "", ErrFileNotFound
+		}
+		return storage.Keys.Original(asset), nil
+	}
+	if !errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return "", result.Error
+	}
+
+	// Thumbnails carry their width after the URL of their asset
+	cut := strings.LastIndex(named, "_")
+	if cut < 0 || path.Ext(filename) != ".jpg" {
+		return "", ErrFileNotFound
+	}
+	size, err := strconv.Atoi(named[cut+1:])
+	if err != nil || !slices.Contains(ThumbnailSizes, size) {
+		return "", ErrFileNotFound
+	}
+
+	result = db.Select("id, user_id").Scopes(AccessibleAssets(userId)).Where("url = ?", named[:cut]).First(&asset)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return "", ErrFileNotFound
+		}
+		return "", result.Error
+	}
+
+	// Thumbnails live with the owner of the asset
+	return storage.Keys.ThumbnailDir(asset.UserId) + filename, nil
+}
+
+// fetchOriginal returns a local file with the original of an asset for the readers of files, release
+// removes it when it had to be downloaded
+func fetchOriginal(asset models.PHAsset) (string, func(), error) {
+	return storage.Fetch(storage.Blobs, storage.Keys.Original(asset))
+}
+
+// UserAsset returns an asset the user can see with the fields its files and their ETags come from
+func UserAsset(db *gorm.DB, userId int, assetId int) (models.PHAsset, error) {
+	var asset models.PHAsset
+	result := db.Select("id, user_id, url, format, content_hash").Scopes(AccessibleAssets(userId)).First(&asset, assetId)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return asset, errors.New("asset not found")
+		}
+		return asset, result.Error
+	}
+	return asset, nil
+}
@@ -0,0 +1,96 @@
+package repositories
+
+import (
+	"github.com/lib/pq"
+	"github.com/mahdi-cpp/PhotoKit/models"
+	"slices"
+
+	"gorm.io/gorm"
+)
+
+// assetGroup is an array column of PHAsset that puts assets in albums, trips or persons
+type assetGroup struct {
+	column string
+	of     func(asset models.PHAsset) pq.Int32Array
+}
+
+var (
+	albumGroup  = assetGroup{column: "albums", of: func(asset models.PHAsset) pq.Int32Array { return asset.Albums }}
+	tripGroup   = assetGroup{column: "trips", of: func(asset models.PHAsset) pq.Int32Array { return asset.Trips }}
+	personGroup = assetGroup{column: "persons", of: func(asset models.PHAsset) pq.Int32Array { return asset.Persons }}
+)
+
+// countAssets counts the assets of a user in each group in one pass over the arrays
+func (g assetGroup) countAssets(db *gorm.DB, userId int) (map[int]int, error) {
+	var counts []struct {
+		GroupId    int
+		AssetCount int
+	}
+	result := db.Model(&models.PHAsset{}).
+		Select("unnest("+g.column+") as group_id, COUNT(*) as asset_count").
+		Where("user_id = ?", userId).
+		Group("group_id").
+		Scan(&counts)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	countByGroup := make(map[int]int, len(counts))
+	for _, c := range counts {
+		countByGroup[c.GroupId] = c.AssetCount
+	}
+	return countByGroup, nil
+}
+
+// keyAssets loads the key photos of the groups of a user, by group. A group gets the asset chosen
+// for it while that asset is still in the group, else its first asset in order.
+func (g assetGroup) keyAssets(db *gorm.DB, userId int, order string, chosen map[int]int) (map[int]*models.PHAsset, error) {
+	var firsts []struct {
+		GroupId int
+		ID      int
+	}
+	inGroups := db.Model(&models.PHAsset{}).
+		Select("unnest("+g.column+") as group_id, id, creation_date").
+		Where("user_id = ?", userId)
+	result := db.Table("(?) as grouped", inGroups).
+		Select("DISTINCT ON (group_id) group_id, id").
+		Order("group_id, " + order).
+		Scan(&firsts)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	ids := make([]int, 0, len(firsts)+len(chosen))
+	for _, first := range firsts {
+		ids = append(ids, first.ID)
+	}
+	for _, id := range chosen {
+		if id != 0 {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return map[int]*models.PHAsset{}, nil
+	}
+
+	var assets []models.PHAsset
+	result = db.Where("user_id = ? AND id IN ?", userId, ids).Find(&assets)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	byId := make(map[int]*models.PHAsset, len(assets))
+	for i := range assets {
+		byId[assets[i].ID] = &assets[i]
+	}
+
+	keyByGroup := make(map[int]*models.PHAsset, len(firsts))
+	for _, first := range firsts {
+		if asset, ok := byId[chosen[first.GroupId]]; ok && slices.Contains(g.of(*asset), int32(first.GroupId)) {
+			keyByGroup[first.GroupId] = asset
+		} else if asset, ok := byId[first.ID]; ok {
+			keyByGroup[first.GroupId] = asset
+		}
+	}
+	return keyByGroup, nil
+}
@@ -0,0 +1,113 @@
+package repositories
+
+import (
+	"github.com/mahdi-cpp/PhotoKit/models"
+	"github.com/mahdi-cpp/PhotoKit/utils"
+	"gorm.io/gorm"
+	"log"
+	"math"
+)
+
+// WithinBoundingBox is a scope keeping the assets located inside a bounding box
+func WithinBoundingBox(box utils.BoundingBox) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		db = db.Where("latitude BETWEEN ? AND ?", box.MinLatitude, box.MaxLatitude)
+		if box.MinLongitude > box.MaxLongitude {
+			return db.Where("(longitude >= ? OR longitude <= ?)", box.MinLongitude, box.MaxLongitude)
+		}
+		return db.Where("longitude BETWEEN ? AND ?", box.MinLongitude, box.MaxLongitude)
+	}
+}
+
+// WithinRadius is a scope keeping the assets located less than radiusKm from a coordinate
+func WithinRadius(latitude, longitude, radiusKm float64) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+
+		// Narrow down with the bounding box of the circle so the location index is used
+		dLat := radiusKm / 111.32
+		dLng := 180.0
+		if cos := math.Cos(latitude * math.Pi / 180); cos > 0.01 {
+			dLng = math.Min(radiusKm/(111.32*cos), 180)
+		}
+
+		box := utils.BoundingBox{
+			MinLatitude:  math.Max(latitude-dLat, -90),
+			MaxLatitude:  math.Min(latitude+dLat, 90),
+			MinLongitude: longitude - dLng,
+			MaxLongitude: longitude + dLng,
+		}
+		if box.MinLongitude < -180 {
+			box.MinLongitude += 360
+		}
+		if box.MaxLongitude > 180 {
+			box.MaxLongitude -= 360
+		}
+		if dLng >= 180 {
+			box.MinLongitude, box.MaxLongitude = -180, 180
+		}
+
+		return db.Scopes(WithinBoundingBox(box)).
+			Where(`2 * 6371 * asin(sqrt(power(sin(radians(latitude - ?) / 2), 2) +
+				cos(radians(?)) * cos(radians(latitude)) * power(sin(radians(longitude - ?) / 2), 2))) <= ?`,
+				latitude, latitude, longitude, radiusKm)
+	}
+}
+
+// readAssetLocation fills in the GPS position of an asset and names it after the nearest city
+func readAssetLocation(asset *models.PHAsset, assetPath string) bool {
+	gps, err := utils.GetGpsLocation(assetPath)
+	if err != nil {
+		return false
+	}
+
+	asset.Location = models.Location{
+		Latitude:  &gps.Latitude,
+		Longitude: &gps.Longitude,
+	}
+	if gps.Altitude != 0 {
+		asset.Location.Altitude = &gps.Altitude
+	}
+
+	if city := utils.NearestCity(gps.Latitude, gps.Longitude); city != nil {
+		asset.Location.City = city.Name
+		asset.Location.Country = city.Country
+	}
+
+	return true
+}
+
+// BackfillLocations reads the GPS position of the user's photos that have no location
+func BackfillLocations(db *gorm.DB, userId int) error {
+
+	var assets []models.PHAsset
+	result := db.Where("user_id = ? AND media_type = ? AND latitude IS NULL", userId, "image").
+		FindInBatches(&assets, 500, func(tx *gorm.DB, batch int) error {
+			for i := range assets {
+				asset := &assets[i]
+				assetPath, release, err := fetchOriginal(*asset)
+				if err != nil {
+					log.Printf("Failed to fetch asset %d: %v", asset.ID, err)
+					continue
+				}
+				found := readAssetLocation(asset, assetPath)
+				release()
+				if !found {
+					continue
+				}
+
+				err = db.Model(asset).Updates(map[string]interface{}{
+					"latitude":  asset.Location.Latitude,
+					"longitude": asset.Location.Longitude,
+					"altitude":  asset.Location.Altitude,
+					"city":      asset.Location.City,
+					"country":   asset.Location.Country,
+				}).Error
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+
+	return result.Error
+}
@@ -0,0 +1,308 @@
+package repositories
+
+import (
+	"github.com/mahdi-cpp/PhotoKit/models"
+	"github.com/mahdi-cpp/PhotoKit/utils"
+	"gorm.io/gorm"
+	"log"
+	"sort"
+	"time"
+)
+
+// DefaultSimilarDistance is the Hamming distance under which two dHashes are considered similar
+const DefaultSimilarDistance = 6
+
+// burstInterval is the longest gap between two shots of a burst sequence
+const burstInterval = 2 * time.Second
+
+// SimilarAsset is an asset with its distance to the asset it was compared with
+type SimilarAsset struct {
+	models.PHAsset
+	Distance int `json:"distance"`
+}
+
+// SimilarGroup is a cluster of near-identical assets
+type SimilarGroup struct {
+	Assets  []models.PHAsset `json:"assets"`
+	BestId  int              `json:"bestId"`  // suggested asset to keep
+	IsBurst bool             `json:"isBurst"` // shots taken in a quick sequence
+}
+
+type hashedAsset struct {
+	ID             int
+	PerceptualHash int64
+	CreationDate   time.Time
+}
+
+// bkTree indexes hashes by Hamming distance so that neighbours are found
+// without comparing every pair of assets
+type bkTree struct {
+	root *bkNode
+}
+
+type bkNode struct {
+	index    int
+	hash     uint64
+	children map[int]*bkNode
+}
+
+func (t *bkTree) add(index int, hash uint64) {
+	if t.root == nil {
+		t.root = &bkNode{index: index, hash: hash}
+		return
+	}
+
+	node := t.root
+	for {
+		distance := utils.HammingDistance(node.hash, hash)
+		child, exists := node.children[distance]
+		if !exists {
+			if node.children == nil {
+				node.children = make(map[int]*bkNode)
+			}
+			node.children[distance] = &bkNode{index: index, hash: hash}
+			return
+		}
+		node = child
+	}
+}
+
+// search calls found for every indexed hash within maxDistance of hash
+func (t *bkTree) search(hash uint64, maxDistance int, found func(index, distance int)) {
+	if t.root == nil {
+		return
+	}
+
+	stack := []*bkNode{t.root}
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		distance := utils.HammingDistance(node.hash, hash)
+		if distance <= maxDistance {
+			found(node.index, distance)
+		}
+
+		for d, child := range node.children {
+			if d >= distance-maxDistance && d <= distance+maxDistance {
+				stack = append(stack, child)
+			}
+		}
+	}
+}
+
+func loadHashedAssets(db *gorm.DB, userId int) ([]hashedAsset, error) {
+	var hashed []hashedAsset
+	result := db.Model(&models.PHAsset{}).
+		Select("id, perceptual_hash, creation_date").
+		Where("user_id = ? AND perceptual_hash IS NOT NULL AND is_hidden = ?", userId, false).
+		Order("creation_date").
+		Scan(&hashed)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return hashed, nil
+}
+
+// FindSimilarAssets returns the user's assets within maxDistance of the given asset, closest first
+func FindSimilarAssets(db *gorm.DB, userId, assetId, maxDistance int) ([]SimilarAsset, error) {
+	var asset models.PHAsset
+	result := db.Where("user_id = ?", userId).First(&asset, assetId)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	similar := make([]SimilarAsset, 0)
+	if asset.PerceptualHash == nil {
+		return similar, nil
+	}
+
+	hashed, err := loadHashedAssets(db, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	distances := make(map[int]int)
+	for _, h := range hashed {
+		if h.ID == asset.ID {
+			continue
+		}
+		distance := utils.HammingDistance(uint64(*asset.PerceptualHash), uint64(h.PerceptualHash))
+		if distance <= maxDistance {
+			distances[h.ID] = distance
+		}
+	}
+	if len(distances) == 0 {
+		return similar, nil
+	}
+
+	ids := make([]int, 0, len(distances))
+	for id := range distances {
+		ids = append(ids, id)
+	}
+
+	var assets []models.PHAsset
+	result = db.Where("id IN ?", ids).Find(&assets)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	for _, a := range assets {
+		similar = append(similar, SimilarAsset{PHAsset: a, Distance: distances[a.ID]})
+	}
+	sort.Slice(similar, func(i, j int) bool {
+		return similar[i].Distance < similar[j].Distance
+	})
+
+	return similar, nil
+}
+
+// GetSimilarGroups clusters the user's assets whose hashes are within maxDistance of each other
+func GetSimilarGroups(db *gorm.DB, userId, maxDistance int) ([]SimilarGroup, error) {
+	hashed, err := loadHashedAssets(db, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	tree := bkTree{}
+	for i, h := range hashed {
+		tree.add(i, uint64(h.PerceptualHash))
+	}
+
+	// Union-find over the assets, linking every pair of neighbours
+	parent := make([]int, len(hashed))
+	for i := range parent {
+		parent[i] = i
+	}
+	find := func(i int) int {
+		for parent[i] != i {
+			parent[i] = parent[parent[i]]
+			i = parent[i]
+		}
+		return i
+	}
+
+	for i, h := range hashed {
+		tree.search(uint64(h.PerceptualHash), maxDistance, func(j, _ int) {
+			if ri, rj := find(i), find(j); ri != rj {
+				parent[rj] = ri
+			}
+		})
+	}
+
+	clusters := make(map[int][]int)
+	for i := range hashed {
+		root := find(i)
+		clusters[root] = append(clusters[root], i)
+	}
+
+	var ids []int
+	for _, members := range clusters {
+		if len(members) < 2 {
+			continue
+		}
+		for _, i := range members {
+			ids = append(ids, hashed[i].ID)
+		}
+	}
+
+	groups := make([]SimilarGroup, 0)
+	if len(ids) == 0 {
+		return groups, nil
+	}
+
+	var assets []models.PHAsset
+	result := db.Where("id IN ?", ids).Find(&assets)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	assetById := make(map[int]models.PHAsset, len(assets))
+	for _, a := range assets {
+		assetById[a.ID] = a
+	}
+
+	for _, members := range clusters {
+		if len(members) < 2 {
+			continue
+		}
+
+		// Members are in creation date order since hashed is
+		sort.Ints(members)
+
+		group := SimilarGroup{IsBurst: true}
+		for k, i := range members {
+			asset, exists := assetById[hashed[i].ID]
+			if !exists {
+				continue
+			}
+			group.Assets = append(group.Assets, asset)
+
+			if k > 0 && hashed[i].CreationDate.Sub(hashed[members[k-1]].CreationDate) > burstInterval {
+				group.IsBurst = false
+			}
+		}
+		// Assets deleted since they were hashed may leave nothing to compare
+		if len(group.Assets) < 2 {
+			continue
+		}
+		group.BestId = bestAsset(group.Assets)
+
+		groups = append(groups, group)
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].Assets[0].CreationDate.After(groups[j].Assets[0].CreationDate)
+	})
+
+	return groups, nil
+}
+
+// bestAsset suggests which asset of a group to keep: favorites first, then the largest
+func bestAsset(assets []models.PHAsset) int {
+	best := assets[0]
+	for _, a := range assets[1:] {
+		if a.IsFavorite != best.IsFavorite {
+			if a.IsFavorite {
+				best = a
+			}
+			continue
+		}
+		if a.PixelWidth*a.PixelHeight > best.PixelWidth*best.PixelHeight {
+			best = a
+		}
+	}
+	return best.ID
+}
+
+// BackfillPerceptualHashes computes the dHash of the user's image assets that have none
+func BackfillPerceptualHashes(db *gorm.DB, userId int) error {
+
+	var assets []models.PHAsset
+	result := db.Where("user_id = ? AND media_type = ? AND perceptual_hash IS NULL", userId, "image").
+		Find(&assets)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	for _, asset := range assets {
+		assetPath, release, err := fetchOriginal(asset)
+		if err != nil {
+			log.Printf("Failed to fetch asset %d: %v", asset.ID, err)
+			continue
+		}
+
+		hash, err := utils.FileDHash(assetPath)
+		release()
+		if err != nil {
+			log.Printf("Failed to hash asset %d: %v", asset.ID, err)
+			continue
+		}
+
+		if err := db.Model(&asset).Update("perceptual_hash", int64(hash)).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,228 @@
+package repositories
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"github.com/mahdi-cpp/PhotoKit/models"
+	"github.com/mahdi-cpp/PhotoKit/utils"
+	"golang.org/x/crypto/bcrypt"
+	"math/big"
+	"regexp"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SMSSender delivers the one-time codes, main sets it from the configuration. Without one no
+// codes are sent and signing in by code is unavailable.
+var SMSSender utils.SMSSender
+
+var (
+	OtpTTL            = 5 * time.Minute // how long a code can be entered
+	OtpResendInterval = time.Minute     // wait before another code is sent to the same phone
+	OtpHourlyLimit    = 5               // codes a phone number gets per hour
+	OtpHourlyIpLimit  = 20              // codes one IP address can request per hour, for any phone numbers
+	OtpMaxAttempts    = 5               // codes entered before a code stops working
+)
+
+const otpDigits = 6
+
+var (
+	ErrOtpRateLimited = errors.New("too many codes requested, try again later")
+	ErrOtpInvalid     = errors.New("invalid or expired code")
+	ErrPhoneNumber    = errors.New("invalid phone number")
+	ErrOtpUnavailable = errors.New("signing in by code is not available")
+)
+
+var phoneNumberPattern = regexp.MustCompile(`^\+?[0-9]{6,15}$`)
+
+// RequestOtp sends a new one-time code to a phone number for a client at an IP address, the
+// unused codes sent before stop working. It returns how long the code is valid.
+func (r *AuthRepository) RequestOtp(phoneNumber, clientIp string) (time.Duration, error) {
+	if SMSSender == nil {
+		return 0, ErrOtpUnavailable
+	}
+
+	phoneNumber, err := normalizePhoneNumber(phoneNumber)
+	if err != nil {
+		return 0, err
+	}
+
+	var recent []models.OtpCode
+	result := r.db.Where("phone_number = ? AND created_at > ?", phoneNumber, time.Now().Add(-time.Hour)).
+		Order("created_at desc").
+		Find(&recent)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if len(recent) >= OtpHourlyLimit || (len(recent) > 0 && time.Since(recent[0].CreatedAt) < OtpResendInterval) {
+		return 0, ErrOtpRateLimited
+	}
+
+	// One client cannot spread its guesses over many phone numbers
+	var fromIp int64
+	result = r.db.Model(&models.OtpCode{}).
+		Where("client_ip = ? AND created_at > ?", clientIp, time.Now().Add(-time.Hour)).
+		Count(&fromIp)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if fromIp >= int64(OtpHourlyIpLimit) {
+		return 0, ErrOtpRateLimited
+	}
+
+	code, err := randomOtp()
+	if err != nil {
+		return 0, err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(code), bcrypt.DefaultCost)
+	if err != nil {
+		return 0, err
+	}
+
+	otp := models.OtpCode{
+		PhoneNumber: phoneNumber,
+		ClientIp:    clientIp,
+		CodeHash:    string(hash),
+		ExpiresAt:   time.Now().Add(OtpTTL),
+		CreatedAt:   time.Now(),
+	}
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.OtpCode{}).
+			Where("phone_number = ? AND used_at IS NULL", phoneNumber).
+			Update("used_at", time.Now()).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(&otp).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	message := fmt.Sprintf("Your PhotoKit code is %s, it expires in %d minutes.", code, int(OtpTTL.Minutes()))
+	if err := SMSSender.Send(phoneNumber, message); err != nil {
+		r.db.Delete(&otp)
+		return 0, err
+	}
+
+	return OtpTTL, nil
+}
+
+// VerifyOtp checks the latest code sent to a phone number and signs its user in, a user is
+// created for a phone number without one
+func (r *AuthRepository) VerifyOtp(phoneNumber, code string) (*TokenPair, error) {
+	phoneNumber, err := normalizePhoneNumber(phoneNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	var otp models.OtpCode
+	result := r.db.Where("phone_number = ? AND used_at IS NULL AND expires_at > ? AND attempts < ?", phoneNumber, time.Now(), OtpMaxAttempts).
+		Order("created_at desc").
+		First(&otp)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, ErrOtpInvalid
+		}
+		return nil, result.Error
+	}
+
+	// Reserve the attempt before comparing, so that concurrent guesses cannot all pass the limit
+	result = r.db.Model(&models.OtpCode{}).
+		Where("id = ? AND attempts < ? AND used_at IS NULL", otp.ID, OtpMaxAttempts).
+		Update("attempts", gorm.Expr("attempts + 1"))
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrOtpInvalid
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(otp.CodeHash), []byte(strings.TrimSpace(code))) != nil {
+		return nil, ErrOtpInvalid
+	}
+
+	// Only one of two concurrent verifications of the same code wins
+	result = r.db.Model(&otp).Where("used_at IS NULL").Update("used_at", time.Now())
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrOtpInvalid
+	}
+
+	var user models.User
+	result = r.db.Where("phone_number = ?", phoneNumber).Limit(1).Find(&user)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		username, err := r.availableUsername("user" + strings.TrimPrefix(phoneNumber, "+"))
+		if err != nil {
+			return nil, err
+		}
+		user = models.User{
+			Username:    username,
+			PhoneNumber: phoneNumber,
+			LastSeen:    time.Now(),
+		}
+		if err := r.db.Create(&user).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	return r.IssueTokens(&user)
+}
+
+// availableUsername returns the name when no user has it yet, otherwise the name with a random
+// suffix no user has. Users can rename themselves later.
+func (r *AuthRepository) availableUsername(name string) (string, error) {
+	candidate := name
+	for i := 0; i < 5; i++ {
+		var count int64
+		if err := r.db.Model(&models.User{}).Where("username = ?", candidate).Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return candidate, nil
+		}
+
+		suffix, err := rand.Int(rand.Reader, big.NewInt(1_000_000))
+		if err != nil {
+			return "", err
+		}
+		candidate = fmt.Sprintf("%s_%06d", name, suffix)
+	}
+	return "", errors.New("no free username")
+}
+
+// normalizePhoneNumber drops the spaces, dashes and parentheses people type in phone numbers
+func normalizePhoneNumber(phoneNumber string) (string, error) {
+	phoneNumber = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(" -()", r) {
+			return -1
+		}
+		return r
+	}, phoneNumber)
+
+	if !phoneNumberPattern.MatchString(phoneNumber) {
+		return "", ErrPhoneNumber
+	}
+	return phoneNumber, nil
+}
+
+func randomOtp() (string, error) {
+	max := big.NewInt(1)
+	for i := 0; i < otpDigits; i++ {
+		max.Mul(max, big.NewInt(10))
+	}
+
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", otpDigits, n), nil
+}
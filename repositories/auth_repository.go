@@ -0,0 +1,196 @@
+package repositories
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"github.com/mahdi-cpp/PhotoKit/models"
+	"github.com/mahdi-cpp/PhotoKit/utils"
+	"golang.org/x/crypto/bcrypt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RefreshTokenTTL is how long a refresh token can be traded for new tokens
+var RefreshTokenTTL = 30 * 24 * time.Hour
+
+const refreshTokenBytes = 32
+
+var (
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	ErrInvalidRefresh     = errors.New("invalid or expired refresh token")
+	ErrUserExists         = errors.New("username or phone number already registered")
+)
+
+// TokenPair is what a client gets when it signs in or refreshes
+type TokenPair struct {
+	AccessToken  string       `json:"accessToken"`
+	RefreshToken string       `json:"refreshToken"`
+	TokenType    string       `json:"tokenType"`
+	ExpiresIn    int          `json:"expiresIn"` // seconds until the access token expires
+	User         *models.User `json:"user"`
+}
+
+type AuthRepository struct {
+	db *gorm.DB
+}
+
+func NewAuthRepository(db *gorm.DB) *AuthRepository {
+
+	// Auto migrate the User, RefreshToken and OtpCode models
+	err := db.AutoMigrate(&models.User{}, &models.RefreshToken{}, &models.OtpCode{})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return &AuthRepository{db: db}
+}
+
+// Register creates a user with a password and signs them in. The phone number is stored in the
+// form the one-time codes use, so both ways of signing in find the same user.
+func (r *AuthRepository) Register(req models.RegisterRequest) (*TokenPair, error) {
+	phoneNumber, err := normalizePhoneNumber(req.PhoneNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	user := models.User{
+		Username:     req.Username,
+		PhoneNumber:  phoneNumber,
+		Email:        req.Email,
+		FirstName:    req.FirstName,
+		LastName:     req.LastName,
+		Bio:          req.Bio,
+		PasswordHash: string(hash),
+		LastSeen:     time.Now(),
+	}
+
+	var count int64
+	result := r.db.Model(&models.User{}).
+		Where("username = ? OR phone_number = ?", user.Username, user.PhoneNumber).
+		Count(&count)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if count > 0 {
+		return nil, ErrUserExists
+	}
+
+	if err := r.db.Create(&user).Error; err != nil {
+		return nil, err
+	}
+
+	return r.IssueTokens(&user)
+}
+
+// Login signs a user in with their username or phone number and password
+func (r *AuthRepository) Login(req models.LoginRequest) (*TokenPair, error) {
+	// A phone number may be typed with spaces or dashes
+	phoneNumber, err := normalizePhoneNumber(req.Username)
+	if err != nil {
+		phoneNumber = req.Username
+	}
+
+	var user models.User
+	result := r.db.Where("username = ? OR phone_number = ?", req.Username, phoneNumber).First(&user)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, result.Error
+	}
+
+	if user.PasswordHash == "" || bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)) != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	return r.IssueTokens(&user)
+}
+
+// IssueTokens creates an access token and a refresh token for the user
+func (r *AuthRepository) IssueTokens(user *models.User) (*TokenPair, error) {
+	accessToken, expiresAt, err := utils.IssueAccessToken(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	secret := make([]byte, refreshTokenBytes)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	refreshToken := base64.RawURLEncoding.EncodeToString(secret)
+
+	err = r.db.Create(&models.RefreshToken{
+		UserId:    user.ID,
+		TokenHash: hashRefreshToken(refreshToken),
+		ExpiresAt: time.Now().Add(RefreshTokenTTL),
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(time.Until(expiresAt).Seconds()),
+		User:         user,
+	}, nil
+}
+
+// Refresh trades a refresh token for new tokens, the old refresh token stops working
+func (r *AuthRepository) Refresh(refreshToken string) (*TokenPair, error) {
+	var token models.RefreshToken
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&token).
+			Where("token_hash = ? AND revoked_at IS NULL AND expires_at > ?", hashRefreshToken(refreshToken), time.Now()).
+			First(&token)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		// Only one of two concurrent refreshes with the same token wins
+		result = tx.Model(&token).Where("revoked_at IS NULL").Update("revoked_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidRefresh
+		}
+		return nil, err
+	}
+
+	var user models.User
+	if result := r.db.First(&user, token.UserId); result.Error != nil {
+		return nil, ErrInvalidRefresh
+	}
+
+	return r.IssueTokens(&user)
+}
+
+// Logout revokes a refresh token, access tokens issued with it run out on their own
+func (r *AuthRepository) Logout(refreshToken string) error {
+	return r.db.Model(&models.RefreshToken{}).
+		Where("token_hash = ? AND revoked_at IS NULL", hashRefreshToken(refreshToken)).
+		Update("revoked_at", time.Now()).Error
+}
+
+func hashRefreshToken(refreshToken string) string {
+	sum := sha256.Sum256([]byte(refreshToken))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,331 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"github.com/mahdi-cpp/PhotoKit/models"
+	"github.com/mahdi-cpp/PhotoKit/utils"
+	"gorm.io/gorm"
+	"log"
+	"sort"
+)
+
+// FaceDetector finds the faces of ingested images, the reference detector unless an external one
+// is configured
+var FaceDetector utils.FaceDetector = utils.SkinToneDetector{}
+
+// FaceMatchDistance is the largest embedding distance between two faces of the same person, it
+// depends on the detector
+var FaceMatchDistance = 0.6
+
+// faceClusterMinSize is the number of similar unassigned faces making a new person suggestion
+const faceClusterMinSize = 2
+
+// faceOverlapMatch is the intersection over union above which a tagged region is taken to be
+// the detected face at the same place
+const faceOverlapMatch = 0.5
+
+// faceDetectionSlots bounds the detectors running in the background for new uploads
+var faceDetectionSlots = make(chan struct{}, 2)
+
+// FaceSuggestion is a group of detected faces that look alike and are not tagged yet. PersonId
+// is the existing person they resemble, 0 when they look like somebody new.
+type FaceSuggestion struct {
+	Regions  []models.FaceRegion `json:"regions"`
+	Count    int                 `json:"count"`
+	PersonId int                 `json:"personId"`
+}
+
+// detectAssetFaces runs the face detector on an image asset and replaces its untagged detections
+func detectAssetFaces(db *gorm.DB, asset *models.PHAsset) error {
+	src, err := assetSource(*asset)
+	if err != nil {
+		return err
+	}
+
+	faces, err := FaceDetector.Detect(src)
+	if err != nil {
+		return fmt.Errorf("detect faces: %w", err)
+	}
+
+	regions := make([]models.FaceRegion, 0, len(faces))
+	for _, face := range faces {
+		regions = append(regions, models.FaceRegion{
+			UserId:     asset.UserId,
+			AssetId:    asset.ID,
+			X:          face.X,
+			Y:          face.Y,
+			Width:      face.Width,
+			Height:     face.Height,
+			IsDetected: true,
+			Confidence: face.Confidence,
+			Embedding:  face.Embedding,
+		})
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("asset_id = ? AND is_detected = ? AND person_id = 0", asset.ID, true).
+			Delete(&models.FaceRegion{}).Error
+		if err != nil {
+			return err
+		}
+
+		if len(regions) > 0 {
+			if err := tx.Create(&regions).Error; err != nil {
+				return err
+			}
+		}
+
+		asset.FacesDetected = true
+		return tx.Model(asset).Update("faces_detected", true).Error
+	})
+}
+
+// queueFaceDetection detects the faces of a new asset in the background
+func queueFaceDetection(db *gorm.DB, asset models.PHAsset) {
+	go func() {
+		faceDetectionSlots <- struct{}{}
+		defer func() { <-faceDetectionSlots }()
+
+		if err := detectAssetFaces(db, &asset); err != nil {
+			log.Printf("Failed to detect faces of %s: %v", asset.URL, err)
+		}
+	}()
+}
+
+// BackfillFaces runs the face detector on the user's images it has not seen yet
+func BackfillFaces(db *gorm.DB, userId int) error {
+	var assets []models.PHAsset
+	result := db.Where("user_id = ? AND media_type = ? AND faces_detected = ?", userId, "image", false).Find(&assets)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	for i := range assets {
+		if err := detectAssetFaces(db, &assets[i]); err != nil {
+			log.Printf("Failed to detect faces of asset %d: %v", assets[i].ID, err)
+		}
+	}
+
+	return nil
+}
+
+// GetSuggestions groups the untagged detected faces of the user by embedding distance. Groups are
+// matched to the closest person, those matching nobody are suggested as new people when they
+// have enough faces.
+func (r *PersonRepository) GetSuggestions(userId int) ([]FaceSuggestion, error) {
+	var regions []models.FaceRegion
+	result := r.db.Where("user_id = ? AND is_detected = ? AND person_id = 0 AND cardinality(embedding) > 0", userId, true).
+		Order("id").
+		Find(&regions)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	var tagged []models.FaceRegion
+	result = r.db.Select("person_id", "embedding").
+		Where("user_id = ? AND person_id > 0 AND cardinality(embedding) > 0", userId).
+		Find(&tagged)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	embeddingsByPerson := make(map[int][][]float32)
+	for _, region := range tagged {
+		embeddingsByPerson[region.PersonId] = append(embeddingsByPerson[region.PersonId], region.Embedding)
+	}
+	centroids := make(map[int][]float32, len(embeddingsByPerson))
+	for personId, embeddings := range embeddingsByPerson {
+		centroids[personId] = centroid(embeddings)
+	}
+
+	embeddings := make([][]float32, len(regions))
+	for i := range regions {
+		embeddings[i] = regions[i].Embedding
+	}
+
+	suggestions := []FaceSuggestion{}
+	for _, cluster := range clusterEmbeddings(embeddings, FaceMatchDistance) {
+		suggestion := FaceSuggestion{Count: len(cluster)}
+		members := make([][]float32, 0, len(cluster))
+		for _, i := range cluster {
+			suggestion.Regions = append(suggestion.Regions, regions[i])
+			members = append(members, regions[i].Embedding)
+		}
+
+		center := centroid(members)
+		closest := FaceMatchDistance
+		for personId, personCenter := range centroids {
+			if distance := utils.EmbeddingDistance(center, personCenter); distance <= closest {
+				closest = distance
+				suggestion.PersonId = personId
+			}
+		}
+
+		if suggestion.PersonId == 0 && suggestion.Count < faceClusterMinSize {
+			continue
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		return suggestions[i].Count > suggestions[j].Count
+	})
+
+	return suggestions, nil
+}
+
+// AssignRegions tags detected faces with the person and adds the person to their assets
+func (r *PersonRepository) AssignRegions(userId, personId int, regionIds []int) error {
+	if _, err := r.GetPersonByID(userId, personId); err != nil {
+		return err
+	}
+
+	var regions []models.FaceRegion
+	result := r.db.Where("user_id = ? AND id IN ?", userId, regionIds).Find(&regions)
+	if result.Error != nil {
+		return result.Error
+	}
+	if len(regions) != len(regionIds) {
+		return errors.New("region not found")
+	}
+
+	assetIds := make([]int, 0, len(regions))
+	for _, region := range regions {
+		assetIds = append(assetIds, region.AssetId)
+	}
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.FaceRegion{}).
+			Where("user_id = ? AND id IN ?", userId, regionIds).
+			Update("person_id", personId).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(&models.Persons{}).
+			Where("user_id = ? AND id <> ? AND key_face_id IN ?", userId, personId, regionIds).
+			Update("key_face_id", 0).Error
+		if err != nil {
+			return err
+		}
+
+		// Previous persons of the regions keep the asset only while another region shows them
+		for _, region := range regions {
+			if region.PersonId == 0 || region.PersonId == personId {
+				continue
+			}
+			err = tx.Model(&models.PHAsset{}).
+				Where("id = ? AND user_id = ?", region.AssetId, userId).
+				Where("NOT EXISTS (?)", tx.Model(&models.FaceRegion{}).Select("1").
+					Where("asset_id = ? AND person_id = ?", region.AssetId, region.PersonId)).
+				Update("persons", gorm.Expr("array_remove(persons, ?)", region.PersonId)).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		return tx.Model(&models.PHAsset{}).
+			Where("id IN ? AND user_id = ?", assetIds, userId).
+			Where("NOT (COALESCE(persons, '{}') @> ?)", pq.Int32Array{int32(personId)}).
+			Update("persons", gorm.Expr("array_append(COALESCE(persons, '{}'), ?)", personId)).Error
+	})
+}
+
+// matchDetectedRegion returns the untagged detected face of the asset that a region drawn by the
+// user covers, nil when there is none
+func matchDetectedRegion(db *gorm.DB, userId int, region models.FaceRegion) (*models.FaceRegion, error) {
+	var detected []models.FaceRegion
+	result := db.Where("user_id = ? AND asset_id = ? AND is_detected = ? AND person_id = 0", userId, region.AssetId, true).
+		Find(&detected)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	var best *models.FaceRegion
+	bestOverlap := faceOverlapMatch
+	for i := range detected {
+		if overlap := regionOverlap(region, detected[i]); overlap >= bestOverlap {
+			bestOverlap = overlap
+			best = &detected[i]
+		}
+	}
+
+	return best, nil
+}
+
+// regionOverlap returns the intersection over union of two regions
+func regionOverlap(a, b models.FaceRegion) float64 {
+	width := min(a.X+a.Width, b.X+b.Width) - max(a.X, b.X)
+	height := min(a.Y+a.Height, b.Y+b.Height) - max(a.Y, b.Y)
+	if width <= 0 || height <= 0 {
+		return 0
+	}
+
+	intersection := width * height
+	return intersection / (a.Width*a.Height + b.Width*b.Height - intersection)
+}
+
+// clusterEmbeddings links every two embeddings closer than maxDistance and returns the connected
+// groups as indexes, in the order of their first member. Every pair is compared, which is fine
+// for the untagged faces of one library.
+func clusterEmbeddings(embeddings [][]float32, maxDistance float64) [][]int {
+	parent := make([]int, len(embeddings))
+	for i := range parent {
+		parent[i] = i
+	}
+
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	for i := range embeddings {
+		for j := i + 1; j < len(embeddings); j++ {
+			if utils.EmbeddingDistance(embeddings[i], embeddings[j]) > maxDistance {
+				continue
+			}
+			if ri, rj := find(i), find(j); ri != rj {
+				parent[max(ri, rj)] = min(ri, rj)
+			}
+		}
+	}
+
+	clusters := [][]int{}
+	indexOfRoot := make(map[int]int)
+	for i := range embeddings {
+		root := find(i)
+		index, exists := indexOfRoot[root]
+		if !exists {
+			index = len(clusters)
+			indexOfRoot[root] = index
+			clusters = append(clusters, nil)
+		}
+		clusters[index] = append(clusters[index], i)
+	}
+
+	return clusters
+}
+
+// centroid returns the unit length mean of embeddings of the same size
+func centroid(embeddings [][]float32) []float32 {
+	if len(embeddings) == 0 {
+		return nil
+	}
+
+	sum := make([]float32, len(embeddings[0]))
+	for _, embedding := range embeddings {
+		if len(embedding) != len(sum) {
+			continue
+		}
+		for i, v := range embedding {
+			sum[i] += v
+		}
+	}
+
+	return utils.NormalizeEmbedding(sum)
+}
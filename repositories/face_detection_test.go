@@ -0,0 +1,138 @@
+package repositories
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/mahdi-cpp/PhotoKit/models"
+)
+
+func TestClusterEmbeddings(t *testing.T) {
+	tests := []struct {
+		name        string
+		embeddings  [][]float32
+		maxDistance float64
+		want        [][]int
+	}{
+		{
+			name: "none",
+			want: [][]int{},
+		},
+		{
+			name:        "all apart",
+			embeddings:  [][]float32{{0, 0}, {1, 0}, {0, 1}},
+			maxDistance: 0.5,
+			want:        [][]int{{0}, {1}, {2}},
+		},
+		{
+			name:        "two groups in order of their first member",
+			embeddings:  [][]float32{{0, 0}, {5, 5}, {0.1, 0}, {5, 5.1}, {0, 0.1}},
+			maxDistance: 0.5,
+			want:        [][]int{{0, 2, 4}, {1, 3}},
+		},
+		{
+			name:        "chained through a middle embedding",
+			embeddings:  [][]float32{{0, 0}, {0.8, 0}, {0.4, 0}},
+			maxDistance: 0.5,
+			want:        [][]int{{0, 1, 2}},
+		},
+		{
+			name:        "distance is inclusive",
+			embeddings:  [][]float32{{0, 0}, {0.5, 0}},
+			maxDistance: 0.5,
+			want:        [][]int{{0, 1}},
+		},
+		{
+			name:        "sizes differ",
+			embeddings:  [][]float32{{0, 0}, {0, 0, 0}},
+			maxDistance: 10,
+			want:        [][]int{{0}, {1}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := clusterEmbeddings(tt.embeddings, tt.maxDistance)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("clusterEmbeddings = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCentroid(t *testing.T) {
+	tests := []struct {
+		name       string
+		embeddings [][]float32
+		want       []float32
+	}{
+		{
+			name: "none",
+			want: nil,
+		},
+		{
+			name:       "one",
+			embeddings: [][]float32{{3, 4}},
+			want:       []float32{0.6, 0.8},
+		},
+		{
+			name:       "mean is normalized",
+			embeddings: [][]float32{{1, 0}, {0, 1}},
+			want:       []float32{float32(math.Sqrt2 / 2), float32(math.Sqrt2 / 2)},
+		},
+		{
+			name:       "other sizes are skipped",
+			embeddings: [][]float32{{1, 0}, {0, 1, 0}, {1, 0}},
+			want:       []float32{1, 0},
+		},
+		{
+			name:       "opposites cancel out",
+			embeddings: [][]float32{{1, 0}, {-1, 0}},
+			want:       []float32{0, 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := centroid(tt.embeddings)
+			if len(got) != len(tt.want) {
+				t.Fatalf("centroid = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if math.Abs(float64(got[i]-tt.want[i])) > 1e-6 {
+					t.Fatalf("centroid = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestRegionOverlap(t *testing.T) {
+	region := func(x, y, width, height float64) models.FaceRegion {
+		return models.FaceRegion{X: x, Y: y, Width: width, Height: height}
+	}
+
+	tests := []struct {
+		name string
+		a, b models.FaceRegion
+		want float64
+	}{
+		{"same", region(0.1, 0.1, 0.2, 0.2), region(0.1, 0.1, 0.2, 0.2), 1},
+		{"apart", region(0, 0, 0.1, 0.1), region(0.5, 0.5, 0.1, 0.1), 0},
+		{"touching edges", region(0, 0, 0.5, 0.5), region(0.5, 0, 0.5, 0.5), 0},
+		{"half shifted", region(0, 0, 0.2, 0.2), region(0.1, 0, 0.2, 0.2), 1.0 / 3},
+		{"contained", region(0, 0, 0.4, 0.4), region(0.1, 0.1, 0.2, 0.2), 0.25},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := regionOverlap(tt.a, tt.b); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("regionOverlap = %v, want %v", got, tt.want)
+			}
+			if got := regionOverlap(tt.b, tt.a); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("regionOverlap swapped = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
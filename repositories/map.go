@@ -0,0 +1,95 @@
+package repositories
+
+import (
+	"github.com/mahdi-cpp/PhotoKit/models"
+	"github.com/mahdi-cpp/PhotoKit/utils"
+	"gorm.io/gorm"
+	"math"
+	"strconv"
+)
+
+// clustersPerTile is how many grid cells divide the width of a 256px map tile
+const clustersPerTile = 4
+
+type MapDTO struct {
+	Zoom     int          `json:"zoom"`
+	Clusters []MapCluster `json:"clusters"`
+}
+
+// MapCluster is a pin standing for the assets of one grid cell of the viewport
+type MapCluster struct {
+	Latitude     float64 `json:"latitude"`
+	Longitude    float64 `json:"longitude"`
+	Count        int     `json:"count"`
+	AssetId      int     `json:"assetId"`
+	ThumbnailURL string  `json:"thumbnailUrl"`
+}
+
+// GetMapClusters groups the user's located assets inside the bounding box into a
+// grid whose cells shrink as the zoom grows. The aggregation runs in the database
+// so only one row per cell is read whatever the size of the library.
+func GetMapClusters(db *gorm.DB, userId int, box utils.BoundingBox, zoom int) ([]MapCluster, error) {
+	if zoom < 0 {
+		zoom = 0
+	}
+	if zoom > 22 {
+		zoom = 22
+	}
+	cellSize := strconv.FormatFloat(360/(math.Pow(2, float64(zoom))*clustersPerTile), 'f', -1, 64)
+
+	var cells []struct {
+		Latitude  float64
+		Longitude float64
+		Count     int
+		AssetId   int
+	}
+
+	result := db.Model(&models.PHAsset{}).
+		Select(`AVG(latitude) AS latitude, AVG(longitude) AS longitude, COUNT(*) AS count,
+			(array_agg(id ORDER BY is_favorite DESC, creation_date DESC))[1] AS asset_id`).
+		Where("user_id = ? AND is_hidden = ?", userId, false).
+		Scopes(WithinBoundingBox(box)).
+		Group("FLOOR(latitude / " + cellSize + "), FLOOR(longitude / " + cellSize + ")").
+		Scan(&cells)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	clusters := make([]MapCluster, 0, len(cells))
+	if len(cells) == 0 {
+		return clusters, nil
+	}
+
+	ids := make([]int, len(cells))
+	for i, cell := range cells {
+		ids[i] = cell.AssetId
+	}
+
+	var assets []models.PHAsset
+	result = db.Select("id, url").Where("id IN ?", ids).Find(&assets)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	urls := make(map[int]string, len(assets))
+	for _, asset := range assets {
+		urls[asset.ID] = asset.URL
+	}
+
+	for _, cell := range cells {
+		clusters = append(clusters, MapCluster{
+			Latitude:     cell.Latitude,
+			Longitude:    cell.Longitude,
+			Count:        cell.Count,
+			AssetId:      cell.AssetId,
+			ThumbnailURL: ThumbnailURL(urls[cell.AssetId], 135),
+		})
+	}
+
+	return clusters, nil
+}
+
+// ThumbnailURL returns the download route of one of the thumbnails created at ingest
+func ThumbnailURL(assetUrl string, size int) string {
+	return "/v1/download/thumbnail/" + assetUrl + "_" + strconv.Itoa(size) + ".jpg"
+}
@@ -0,0 +1,255 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"github.com/mahdi-cpp/PhotoKit/models"
+	"github.com/mahdi-cpp/PhotoKit/utils"
+	"gorm.io/gorm"
+	"log"
+	"time"
+)
+
+const memoryMinAssets = 3 // weeks with fewer assets do not make a memory
+
+type MemoriesDTO struct {
+	Date     string       `json:"date"`
+	Memories []MemoryYear `json:"memories"`
+}
+
+// MemoryYear is the assets taken on the same day of one past year
+type MemoryYear struct {
+	Year     int              `json:"year"`
+	Name     string           `json:"name"`
+	Count    int              `json:"count"`
+	KeyAsset *models.PHAsset  `json:"keyAsset"`
+	Assets   []models.PHAsset `json:"assets"`
+}
+
+type MemoryRepository struct {
+	db *gorm.DB
+}
+
+func NewMemoryRepository(db *gorm.DB) *MemoryRepository {
+
+	// Auto migrate the Memory models
+	err := db.AutoMigrate(&models.Memory{})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return &MemoryRepository{db: db}
+}
+
+// GetOnThisDay retrieves the user's assets taken on the month and day of the date in previous years,
+// grouped by year, newest year first, with a favorite as the cover when there is one
+func (r *MemoryRepository) GetOnThisDay(userId int, date time.Time, language string) ([]MemoryYear, error) {
+	query := r.db.Scopes(timelineAssets(userId)).
+		Where("EXTRACT(YEAR FROM creation_date) < ?", date.Year())
+
+	// The 29th of February is remembered on the 28th in other years
+	if date.Month() == time.February && date.Day() == 28 && !isLeapYear(date.Year()) {
+		query = query.Where("EXTRACT(MONTH FROM creation_date) = 2 AND EXTRACT(DAY FROM creation_date) IN (28, 29)")
+	} else {
+		query = query.Where("EXTRACT(MONTH FROM creation_date) = ? AND EXTRACT(DAY FROM creation_date) = ?", int(date.Month()), date.Day())
+	}
+
+	var assets []models.PHAsset
+	result := query.Order("creation_date DESC").Find(&assets)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	memories := []MemoryYear{}
+	for _, asset := range assets {
+		year := asset.CreationDate.Year()
+		if len(memories) == 0 || memories[len(memories)-1].Year != year {
+			memories = append(memories, MemoryYear{
+				Year: year,
+				Name: utils.YearsAgoTitle(date.Year()-year, language),
+			})
+		}
+
+		memory := &memories[len(memories)-1]
+		memory.Assets = append(memory.Assets, asset)
+		memory.Count++
+	}
+
+	for i := range memories {
+		memories[i].KeyAsset = coverAsset(memories[i].Assets)
+	}
+
+	return memories, nil
+}
+
+// GenerateWeeklyMemories replaces the user's memories of the week starting on weekStart with one memory
+// for each previous year that has assets in the same week
+func (r *MemoryRepository) GenerateWeeklyMemories(userId int, weekStart time.Time) ([]models.Memory, error) {
+
+	var first models.PHAsset
+	result := r.db.Scopes(timelineAssets(userId)).Order("creation_date").Limit(1).Find(&first)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	// Without assets there are no past years to look at
+	firstYear := weekStart.Year()
+	if result.RowsAffected > 0 {
+		firstYear = first.CreationDate.Year()
+	}
+
+	memories := []models.Memory{}
+	for year := weekStart.Year() - 1; year >= firstYear; year-- {
+		start := weekStart.AddDate(year-weekStart.Year(), 0, 0)
+		end := start.AddDate(0, 0, 7)
+
+		var assets []models.PHAsset
+		err := r.db.Scopes(timelineAssets(userId)).
+			Where("creation_date >= ? AND creation_date < ?", start, end).
+			Order("creation_date").
+			Find(&assets).Error
+		if err != nil {
+			return nil, err
+		}
+		if len(assets) < memoryMinAssets {
+			continue
+		}
+
+		memory := models.Memory{
+			UserId:     userId,
+			WeekStart:  weekStart,
+			Year:       year,
+			StartDate:  start,
+			EndDate:    end,
+			KeyAssetId: coverAsset(assets).ID,
+		}
+		for _, asset := range assets {
+			memory.AssetIds = append(memory.AssetIds, int32(asset.ID))
+		}
+		memories = append(memories, memory)
+	}
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("user_id = ? AND week_start = ?", userId, weekStart).Delete(&models.Memory{}).Error
+		if err != nil {
+			return err
+		}
+		if len(memories) == 0 {
+			return nil
+		}
+		return tx.Create(&memories).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return memories, nil
+}
+
+// weekMemories retrieves the memories generated for the current week, newest year first
+func weekMemories(db *gorm.DB, userId int) ([]models.Memory, error) {
+	var memories []models.Memory
+	result := db.Where("user_id = ? AND week_start = ?", userId, WeekStart(time.Now())).
+		Order("year DESC").
+		Find(&memories)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return memories, nil
+}
+
+// GetMemory retrieves a memory of the user and its assets
+func (r *MemoryRepository) GetMemory(userId, id int) (*models.Memory, []models.PHAsset, error) {
+	var memory models.Memory
+	result := r.db.Where("user_id = ?", userId).First(&memory, id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil, errors.New("memory not found")
+		}
+		return nil, nil, result.Error
+	}
+
+	assets := []models.PHAsset{}
+	if len(memory.AssetIds) > 0 {
+		result = r.db.Where("user_id = ? AND id = ANY(?)", userId, memory.AssetIds).
+			Order("creation_date").
+			Find(&assets)
+		if result.Error != nil {
+			return nil, nil, result.Error
+		}
+	}
+
+	return &memory, assets, nil
+}
+
+// RunScheduler generates the memories of the current week for every user who has none yet, now
+// and then every Monday at midnight. Failed users are retried hourly, the others are kept.
+func (r *MemoryRepository) RunScheduler() {
+	for {
+		weekStart := WeekStart(time.Now())
+
+		if err := r.generateAllUsers(weekStart); err != nil {
+			log.Printf("Memories generation failed: %v", err)
+			time.Sleep(time.Hour)
+			continue
+		}
+
+		time.Sleep(time.Until(weekStart.AddDate(0, 0, 7)))
+	}
+}
+
+// generateAllUsers generates the memories of the week for the users with assets and no memories
+// of the week yet, a failed user does not stop the others
+func (r *MemoryRepository) generateAllUsers(weekStart time.Time) error {
+	generated := r.db.Model(&models.Memory{}).Select("user_id").Where("week_start = ? AND user_id IS NOT NULL", weekStart)
+
+	var userIds []int
+	err := r.db.Model(&models.PHAsset{}).
+		Where("user_id NOT IN (?)", generated).
+		Distinct("user_id").
+		Pluck("user_id", &userIds).Error
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, userId := range userIds {
+		memories, err := r.GenerateWeeklyMemories(userId, weekStart)
+		if err != nil {
+			log.Printf("Failed to generate memories for user %d: %v", userId, err)
+			failed++
+			continue
+		}
+		log.Printf("Generated %d memories for user %d", len(memories), userId)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d users failed", failed, len(userIds))
+	}
+	return nil
+}
+
+// WeekStart returns the Monday at midnight of the week of a date
+func WeekStart(date time.Time) time.Time {
+	daysSinceMonday := (int(date.Weekday()) + 6) % 7
+	year, month, day := date.AddDate(0, 0, -daysSinceMonday).Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, date.Location())
+}
+
+// coverAsset returns the first favorite of the assets, otherwise the first asset
+func coverAsset(assets []models.PHAsset) *models.PHAsset {
+	if len(assets) == 0 {
+		return nil
+	}
+	for i := range assets {
+		if assets[i].IsFavorite {
+			return &assets[i]
+		}
+	}
+	return &assets[0]
+}
+
+func isLeapYear(year int) bool {
+	return year%4 == 0 && (year%100 != 0 || year%400 == 0)
+}
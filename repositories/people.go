@@ -1,50 +1,28 @@
 package repositories
 
 import (
-	"github.com/mahdi-cpp/PhotoKit/cache"
 	"github.com/mahdi-cpp/PhotoKit/models"
-	"github.com/mahdi-cpp/PhotoKit/utils"
+	"strconv"
 )
 
-var peopleDTO PeopleDTO
-
 type PeopleDTO struct {
-	PeopleGroup []PeopleGroup `json:"peopleGroupArray"`
+	PeopleGroup []PersonSummary `json:"peopleGroupArray"`
 }
 
-type PeopleGroup struct {
-	Name  string         `json:"name"`
-	Photo models.UIImage `json:"photo"`
+// PersonSummary is a person with the data needed to render them in a list
+type PersonSummary struct {
+	models.Persons
+	AssetCount int    `json:"assetCount"`
+	KeyFaceURL string `json:"keyFaceUrl"` // empty until a key face is chosen
 }
 
-func GetPeoples(folder string) {
-
-	var file = "data.txt"
-	var uiImages = cache.ReadOfFile(folder, file)
-
-	var count = len(uiImages) - 1
-
-	if count > 15 {
-		count = 15
+func newPersonSummary(person models.Persons, assetCount int) PersonSummary {
+	summary := PersonSummary{
+		Persons:    person,
+		AssetCount: assetCount,
 	}
-
-	var index = 0
-	var nameIndex = 0
-
-	for i := 0; i < count; i++ {
-		var personGroup = PeopleGroup{}
-
-		if nameIndex+1 >= len(utils.FackNames) {
-			nameIndex = 0
-		}
-
-		personGroup.Name = utils.FackNames[nameIndex]
-		personGroup.Photo = uiImages[index+1]
-		peopleDTO.PeopleGroup = append(peopleDTO.PeopleGroup, personGroup)
-
-		nameIndex++
-		index += 1
+	if person.KeyFaceId != 0 {
+		summary.KeyFaceURL = "/v1/persons/" + strconv.Itoa(person.ID) + "/face"
 	}
-
-	index = 0
+	return summary
 }
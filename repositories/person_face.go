@@ -0,0 +1,78 @@
+package repositories
+
+import (
+	"bytes"
+	"errors"
+	"github.com/disintegration/imaging"
+	"github.com/mahdi-cpp/PhotoKit/models"
+	"github.com/mahdi-cpp/PhotoKit/storage"
+	"github.com/mahdi-cpp/PhotoKit/utils"
+	"image"
+)
+
+const (
+	faceCropSize   = 256
+	faceCropMargin = 0.25 // extra space around the face, relative to the region size
+)
+
+var ErrEmptyRegion = errors.New("region is outside of the image")
+
+// FaceKey returns the blob of the key face crop made from a region
+func FaceKey(userId int, regionId int) string {
+	return storage.Keys.Face(userId, regionId)
+}
+
+// createFaceCrop cuts the region out of its asset with some margin and saves it as a square JPEG
+func createFaceCrop(asset models.PHAsset, region models.FaceRegion) error {
+	src, err := assetSource(asset)
+	if err != nil {
+		return err
+	}
+
+	bounds := src.Bounds()
+	width := float64(bounds.Dx())
+	height := float64(bounds.Dy())
+
+	// Square around the center of the region
+	side := (1 + 2*faceCropMargin) * max(region.Width*width, region.Height*height)
+	centerX := (region.X + region.Width/2) * width
+	centerY := (region.Y + region.Height/2) * height
+
+	rect := image.Rect(
+		int(centerX-side/2), int(centerY-side/2),
+		int(centerX+side/2), int(centerY+side/2),
+	).Add(bounds.Min).Intersect(bounds)
+	if rect.Empty() {
+		return ErrEmptyRegion
+	}
+
+	face := imaging.Fill(utils.CropImage(src, rect), faceCropSize, faceCropSize, imaging.Center, imaging.Lanczos)
+
+	var buf bytes.Buffer
+	if err := imaging.Encode(&buf, face, imaging.JPEG); err != nil {
+		return err
+	}
+
+	return storage.PutBytes(storage.Blobs, FaceKey(asset.UserId, region.ID), buf.Bytes(), "image/jpeg")
+}
+
+// assetSource decodes an asset upright, the image face regions are relative to and thumbnails are
+// rendered from. The thumbnail stands in for videos and formats that cannot be decoded.
+func assetSource(asset models.PHAsset) (image.Image, error) {
+	if asset.MediaType == "image" && asset.Format != "heic" {
+		return decodeBlob(storage.Keys.Original(asset), imaging.AutoOrientation(true))
+	}
+
+	return decodeBlob(storage.Keys.Thumbnail(asset, ThumbnailSizes[0]))
+}
+
+// decodeBlob decodes the image of a blob
+func decodeBlob(key string, opts ...imaging.DecodeOption) (image.Image, error) {
+	body, _, err := storage.Blobs.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	return imaging.Decode(body, opts...)
+}
@@ -0,0 +1,323 @@
+package repositories
+
+import (
+	"errors"
+	"github.com/lib/pq"
+	"github.com/mahdi-cpp/PhotoKit/models"
+	"github.com/mahdi-cpp/PhotoKit/storage"
+	"log"
+	"sort"
+
+	"gorm.io/gorm"
+)
+
+type PersonRepository struct {
+	db *gorm.DB
+}
+
+func NewPersonRepository(db *gorm.DB) *PersonRepository {
+
+	// Auto migrate the Persons and FaceRegion models
+	err := db.AutoMigrate(&models.Persons{}, &models.FaceRegion{})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return &PersonRepository{db: db}
+}
+
+// CreatePerson creates a new person for a user
+func (r *PersonRepository) CreatePerson(person *models.Persons) error {
+	if person == nil {
+		return errors.New("person cannot be nil")
+	}
+
+	return r.db.Create(person).Error
+}
+
+// GetPersonByID retrieves a person of the user
+func (r *PersonRepository) GetPersonByID(userId, id int) (*models.Persons, error) {
+	var person models.Persons
+	result := r.db.Where("user_id = ?", userId).First(&person, id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, errors.New("person not found")
+		}
+		return nil, result.Error
+	}
+
+	return &person, nil
+}
+
+// UpdatePerson renames and hides or shows a person, nil values are left unchanged
+func (r *PersonRepository) UpdatePerson(userId, id int, named *string, isHidden *bool) error {
+	updates := map[string]interface{}{}
+	if named != nil {
+		updates["named"] = *named
+	}
+	if isHidden != nil {
+		updates["is_hidden"] = *isHidden
+	}
+
+	if _, err := r.GetPersonByID(userId, id); err != nil {
+		return err
+	}
+	if len(updates) == 0 {
+		return nil
+	}
+
+	return r.db.Model(&models.Persons{}).
+		Where("id = ? AND user_id = ?", id, userId).
+		Updates(updates).Error
+}
+
+// MergePersons moves the regions and assets of the other persons to the first one and deletes them
+func (r *PersonRepository) MergePersons(userId int, ids []int) (*models.Persons, error) {
+	if len(ids) < 2 {
+		return nil, errors.New("at least two persons are needed")
+	}
+
+	var persons []models.Persons
+	result := r.db.Where("user_id = ? AND id IN ?", userId, ids).Find(&persons)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if len(persons) != len(ids) {
+		return nil, errors.New("person not found")
+	}
+
+	var target *models.Persons
+	for i := range persons {
+		if persons[i].ID == ids[0] {
+			target = &persons[i]
+		}
+	}
+
+	// Key face crops of the merged persons go once the merge is committed, a rollback keeps them
+	var staleFaces []string
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		staleFaces = nil
+		for _, person := range persons {
+			if person.ID == target.ID {
+				continue
+			}
+
+			err := tx.Model(&models.FaceRegion{}).
+				Where("user_id = ? AND person_id = ?", userId, person.ID).
+				Update("person_id", target.ID).Error
+			if err != nil {
+				return err
+			}
+
+			// Move the assets, skipping those that already show the target
+			err = tx.Model(&models.PHAsset{}).
+				Where("user_id = ? AND persons @> ?", userId, pq.Int32Array{int32(person.ID)}).
+				Update("persons", gorm.Expr("CASE WHEN persons @> ? THEN array_remove(persons, ?) ELSE array_replace(persons, ?, ?) END",
+					pq.Int32Array{int32(target.ID)}, person.ID, person.ID, target.ID)).Error
+			if err != nil {
+				return err
+			}
+
+			if target.KeyFaceId == 0 {
+				target.KeyFaceId = person.KeyFaceId
+			} else if person.KeyFaceId != 0 {
+				staleFaces = append(staleFaces, FaceKey(userId, person.KeyFaceId))
+			}
+
+			if err := tx.Delete(&models.Persons{}, person.ID).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Model(target).Update("key_face_id", target.KeyFaceId).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, key := range staleFaces {
+		storage.Blobs.Delete(key)
+	}
+
+	return target, nil
+}
+
+// TagRegion stores a region of an asset showing the person and adds the person to the asset, a
+// detected face at the same place becomes the region
+func (r *PersonRepository) TagRegion(userId, personId int, region *models.FaceRegion) error {
+	if _, err := r.GetPersonByID(userId, personId); err != nil {
+		return err
+	}
+
+	var count int64
+	result := r.db.Model(&models.PHAsset{}).Where("id = ? AND user_id = ?", region.AssetId, userId).Count(&count)
+	if result.Error != nil {
+		return result.Error
+	}
+	if count == 0 {
+		return errors.New("asset not found")
+	}
+
+	// Tagging a detected face keeps its embedding for the suggestions
+	detected, err := matchDetectedRegion(r.db, userId, *region)
+	if err != nil {
+		return err
+	}
+	if detected != nil {
+		*region = *detected
+	}
+
+	region.UserId = userId
+	region.PersonId = personId
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(region).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&models.PHAsset{}).
+			Where("id = ? AND user_id = ?", region.AssetId, userId).
+			Where("NOT (COALESCE(persons, '{}') @> ?)", pq.Int32Array{int32(personId)}).
+			Update("persons", gorm.Expr("array_append(COALESCE(persons, '{}'), ?)", personId)).Error
+	})
+}
+
+// UntagRegion deletes a region of the person, the person is removed from the asset when it was
+// the last region showing them there
+func (r *PersonRepository) UntagRegion(userId, personId, regionId int) error {
+	var region models.FaceRegion
+	result := r.db.Where("user_id = ? AND person_id = ?", userId, personId).First(&region, regionId)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return errors.New("region not found")
+		}
+		return result.Error
+	}
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&region).Error; err != nil {
+			return err
+		}
+
+		var remaining int64
+		err := tx.Model(&models.FaceRegion{}).
+			Where("asset_id = ? AND person_id = ?", region.AssetId, personId).
+			Count(&remaining).Error
+		if err != nil {
+			return err
+		}
+		if remaining == 0 {
+			err = tx.Model(&models.PHAsset{}).
+				Where("id = ? AND user_id = ?", region.AssetId, userId).
+				Update("persons", gorm.Expr("array_remove(persons, ?)", personId)).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		return tx.Model(&models.Persons{}).
+			Where("id = ? AND key_face_id = ?", personId, regionId).
+			Update("key_face_id", 0).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	// The key face crop made from the region, if any
+	storage.Blobs.Delete(FaceKey(userId, regionId))
+	return nil
+}
+
+// GetPersonRegions retrieves the regions tagged with the person
+func (r *PersonRepository) GetPersonRegions(userId, personId int) ([]models.FaceRegion, error) {
+	regions := []models.FaceRegion{}
+	result := r.db.Where("user_id = ? AND person_id = ?", userId, personId).
+		Order("created_at desc").
+		Find(&regions)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return regions, nil
+}
+
+// SetKeyFace makes the key face crop of the person from one of their regions
+func (r *PersonRepository) SetKeyFace(userId, personId, regionId int) error {
+	person, err := r.GetPersonByID(userId, personId)
+	if err != nil {
+		return err
+	}
+
+	var region models.FaceRegion
+	result := r.db.Where("user_id = ? AND person_id = ?", userId, personId).First(&region, regionId)
+	if result.Error != nil {
+		return errors.New("region not found")
+	}
+
+	var asset models.PHAsset
+	result = r.db.Where("user_id = ?", userId).First(&asset, region.AssetId)
+	if result.Error != nil {
+		return errors.New("asset not found")
+	}
+
+	if err := createFaceCrop(asset, region); err != nil {
+		return err
+	}
+
+	previous := person.KeyFaceId
+	if err := r.db.Model(person).Update("key_face_id", regionId).Error; err != nil {
+		return err
+	}
+	if previous != 0 && previous != regionId {
+		storage.Blobs.Delete(FaceKey(userId, previous))
+	}
+
+	return nil
+}
+
+// GetPersonAssets retrieves the assets showing the person with pagination
+func (r *PersonRepository) GetPersonAssets(userId, id, limit, offset int) ([]models.PHAsset, error) {
+	var assets []models.PHAsset
+	result := r.db.Where("user_id = ? AND persons @> ?", userId, pq.Int32Array{int32(id)}).
+		Order("creation_date desc").
+		Limit(limit).Offset(offset).
+		Find(&assets)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return assets, nil
+}
+
+// ListPersons retrieves the persons of a user, the ones in the most assets first
+func (r *PersonRepository) ListPersons(userId int, includeHidden bool) ([]PersonSummary, error) {
+	query := r.db.Where("user_id = ?", userId)
+	if !includeHidden {
+		query = query.Where("is_hidden = ?", false)
+	}
+
+	var persons []models.Persons
+	result := query.Find(&persons)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	countByPerson, err := personGroup.countAssets(r.db, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make([]PersonSummary, 0, len(persons))
+	for _, person := range persons {
+		summaries = append(summaries, newPersonSummary(person, countByPerson[person.ID]))
+	}
+
+	sort.SliceStable(summaries, func(i, j int) bool {
+		if summaries[i].AssetCount != summaries[j].AssetCount {
+			return summaries[i].AssetCount > summaries[j].AssetCount
+		}
+		return summaries[i].Named < summaries[j].Named
+	})
+
+	return summaries, nil
+}
@@ -1,121 +1,91 @@
 package repositories
 
 import (
-	"github.com/mahdi-cpp/PhotoKit/cache"
 	"github.com/mahdi-cpp/PhotoKit/models"
 	"github.com/mahdi-cpp/PhotoKit/utils"
+	"gorm.io/gorm"
+	"time"
 )
 
-var pinnedCollectionDTO PinnedCollectionDTO
-var pinnedCollectionDTO2 PinnedCollectionDTO
-
 type PinnedCollectionDTO struct {
 	PinnedCollections []PinnedCollection `json:"pinnedCollections"`
 }
 
 type PinnedCollection struct {
-	Name  string         `json:"name"`
-	Type  string         `json:"type"`
-	Icon  string         `json:"icon"`
-	Image models.UIImage `json:"image"`
+	Name     string          `json:"name"`
+	Type     string          `json:"type"`
+	Icon     string          `json:"icon"`
+	Count    int             `json:"count"`
+	KeyAsset *models.PHAsset `json:"keyAsset"`
+	MemoryId int             `json:"memoryId,omitempty"`
 }
 
-func GetPinned(folder string) {
-
-	var file = "data.txt"
-	var uiImages = cache.ReadOfFile(folder, file)
-	var count = len(uiImages)
+// smartCollections are the pinned collections computed from asset properties
+var smartCollections = []struct {
+	name      string
+	kind      string
+	icon      string
+	condition string
+}{
+	{"Favourite", "favourite", "icons8-favourite-60", "is_favorite = true"},
+	{"Map", "map", "icons8-albums-50", "latitude IS NOT NULL"},
+	{"Videos", "videos", "icons8-video-60", "media_type = 'video'"},
+	{"Trips", "trips", "icons8-trip-50", "cardinality(trips) > 0"},
+}
 
-	if count > 10 {
-		count = 10
-	}
+// GetPinnedCollections builds the pinned collections of the user: the smart collections that are not
+// empty followed by the memories generated for the current week
+func GetPinnedCollections(db *gorm.DB, userId int, language string) (PinnedCollectionDTO, error) {
+	dto := PinnedCollectionDTO{PinnedCollections: []PinnedCollection{}}
 
-	var index = 0
-	var nameIndex = 0
+	for _, smart := range smartCollections {
+		query := db.Model(&models.PHAsset{}).Scopes(timelineAssets(userId)).Where(smart.condition).
+			Session(&gorm.Session{})
 
-	for i := 0; i < count; i++ {
-		if nameIndex >= len(utils.FackNames) {
-			nameIndex = 0
+		var count int64
+		if err := query.Count(&count).Error; err != nil {
+			return dto, err
 		}
-
-		var pinned = PinnedCollection{}
-		pinned.Name = utils.FackNames[nameIndex]
-		pinned.Image = uiImages[index]
-
-		if index == 2 {
-			pinned.Name = "Favourite"
-			pinned.Type = "favourite"
-			pinned.Icon = "icons8-favourite-60"
-			pinned.Image.Named = "chat_19"
-		} else if index == 3 {
-			pinned.Name = "Map"
-			pinned.Type = "map"
-			pinned.Icon = "icons8-albums-50"
-			pinned.Image.Named = "Screenshot from 2024-08-08 01-04-57"
-		} else if index == 0 {
-			pinned.Name = "Camera"
-			pinned.Type = "trips"
-			pinned.Icon = "camera_photo_51"
-			pinned.Image.Named = "IMG_20141015_185832"
-		} else if index == 1 {
-			pinned.Name = "Screenshots"
-			pinned.Type = "trips"
-			pinned.Icon = "screenshot_60"
-			pinned.Image.Named = "all_84"
-		} else if index == 4 {
-			pinned.Name = "Videos"
-			pinned.Type = "videos"
-			pinned.Icon = "icons8-video-60"
-			pinned.Image.Named = "IMG_20141015_185832"
-		} else if index == 5 {
-			pinned.Name = "الکترونیک"
-			pinned.Type = "album"
-			pinned.Icon = "all_84"
-			pinned.Image.Named = "b44b4f5b11b6d88022746825379a323f0badc1c2_1697894819 (1)"
-		} else if index == 6 {
-			pinned.Name = "Telegram"
-			pinned.Type = "album"
-			pinned.Icon = ""
-			pinned.Image.Named = "021"
-		} else if index == 7 {
-			pinned.Name = "Trips"
-			pinned.Type = "icons8-trip-50"
-			pinned.Icon = "icons8-albums-50"
-			pinned.Image.Named = "IMG_20141015_185832"
+		if count == 0 {
+			continue
 		}
 
-		pinnedCollectionDTO.PinnedCollections = append(pinnedCollectionDTO.PinnedCollections, pinned)
+		var keyAsset models.PHAsset
+		if err := query.Order("creation_date DESC").First(&keyAsset).Error; err != nil {
+			return dto, err
+		}
 
-		nameIndex++
-		index++
+		dto.PinnedCollections = append(dto.PinnedCollections, PinnedCollection{
+			Name:     smart.name,
+			Type:     smart.kind,
+			Icon:     smart.icon,
+			Count:    int(count),
+			KeyAsset: &keyAsset,
+		})
 	}
 
-	index = 0
-}
-
-func GetPinnedGallery(folder string) {
-
-	var file = "data.txt"
-	var photos = cache.ReadOfFile(folder, file)
-	var count = len(photos)
-
-	var index = 0
-	var nameIndex = 0
+	memories, err := weekMemories(db, userId)
+	if err != nil {
+		return dto, err
+	}
 
-	for i := 0; i < count; i++ {
-		if nameIndex >= len(utils.FackNames) {
-			nameIndex = 0
+	now := time.Now()
+	for _, memory := range memories {
+		pinned := PinnedCollection{
+			Name:     utils.YearsAgoTitle(now.Year()-memory.Year, language),
+			Type:     "memory",
+			Icon:     "icons8-calendar-60",
+			Count:    len(memory.AssetIds),
+			MemoryId: memory.ID,
 		}
 
-		var pinned = PinnedCollection{}
-		pinned.Name = utils.FackNames[nameIndex]
-		pinned.Image = photos[index]
-
-		pinnedCollectionDTO2.PinnedCollections = append(pinnedCollectionDTO2.PinnedCollections, pinned)
+		var keyAsset models.PHAsset
+		if db.Where("user_id = ?", userId).First(&keyAsset, memory.KeyAssetId).Error == nil {
+			pinned.KeyAsset = &keyAsset
+		}
 
-		nameIndex++
-		index++
+		dto.PinnedCollections = append(dto.PinnedCollections, pinned)
 	}
 
-	index = 0
+	return dto, nil
 }
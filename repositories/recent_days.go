@@ -1,44 +1,100 @@
 package repositories
 
 import (
-	"github.com/mahdi-cpp/PhotoKit/cache"
 	"github.com/mahdi-cpp/PhotoKit/models"
+	"github.com/mahdi-cpp/PhotoKit/utils"
+	"gorm.io/gorm"
+	"time"
 )
 
-var recentDaysDTO RecentDaysDTO
+const recentDayCovers = 4 // assets shown for each day
 
 type RecentDaysDTO struct {
-	RecentDays []RecentDays `json:"recentDays"`
+	RecentDays []RecentDay `json:"recentDays"`
+	NextBefore string      `json:"nextBefore"` // date of the oldest day of the page, empty on the last page
 }
 
-type RecentDays struct {
+// RecentDay is the assets taken on one day
+type RecentDay struct {
+	Date   string           `json:"date"` // 2006-01-02
 	Name   string           `json:"name"`
-	Images []models.UIImage `json:"images"`
+	Count  int              `json:"count"`
+	Covers []models.PHAsset `json:"covers"`
 }
 
-func GetRecently(folder string) {
+// GetRecentDays groups the user's assets of the last days by the day they were taken, newest first.
+// A page holds at most limit days older than before, when before is not zero.
+func GetRecentDays(db *gorm.DB, userId int, days int, before time.Time, limit int, language string) (RecentDaysDTO, error) {
 
-	var file = "data.txt"
-	var uiImages = cache.ReadOfFile(folder, file)
+	dto := RecentDaysDTO{RecentDays: []RecentDay{}}
 
-	recentDaysDTO = RecentDaysDTO{}
-	var count = ((len(uiImages) - 4) / 4) + 1
-	var index = 0
+	inRange := func(tx *gorm.DB) *gorm.DB {
+		tx = tx.Where("user_id = ? AND is_hidden = ? AND duplicate_of = 0", userId, false)
+		if days > 0 {
+			year, month, day := time.Now().AddDate(0, 0, -days).Date()
+			tx = tx.Where("creation_date >= ?", time.Date(year, month, day, 0, 0, 0, 0, time.Local))
+		}
+		if !before.IsZero() {
+			tx = tx.Where("creation_date < ?", before)
+		}
+		return tx
+	}
+
+	var counts []struct {
+		Day   time.Time
+		Count int
+	}
+	result := db.Model(&models.PHAsset{}).
+		Select("creation_date::date AS day, COUNT(*) AS count").
+		Scopes(inRange).
+		Group("day").
+		Order("day DESC").
+		Limit(limit + 1).
+		Scan(&counts)
+	if result.Error != nil {
+		return dto, result.Error
+	}
+	if len(counts) == 0 {
+		return dto, nil
+	}
 
-	if count > 25 {
-		count = 25
+	if len(counts) > limit {
+		counts = counts[:limit]
+		dto.NextBefore = counts[limit-1].Day.Format("2006-01-02")
 	}
 
-	for i := 0; i < count; i++ {
-		var album = RecentDays{}
+	// Favorites first then the newest assets of each day, in one query
+	oldest := counts[len(counts)-1].Day
+	ranked := db.Model(&models.PHAsset{}).
+		Select("*, ROW_NUMBER() OVER (PARTITION BY creation_date::date ORDER BY is_favorite DESC, creation_date DESC) AS cover_rank").
+		Scopes(inRange).
+		Where("creation_date >= ?", oldest)
 
-		for j := 0; j < 4; j++ {
-			var image models.UIImage
-			image = uiImages[index+j]
-			album.Images = append(album.Images, image)
-		}
+	var covers []models.PHAsset
+	result = db.Table("(?) AS ranked", ranked).
+		Where("cover_rank <= ?", recentDayCovers).
+		Order("creation_date DESC").
+		Find(&covers)
+	if result.Error != nil {
+		return dto, result.Error
+	}
 
-		recentDaysDTO.RecentDays = append(recentDaysDTO.RecentDays, album)
-		index += 4
+	coversByDay := make(map[string][]models.PHAsset)
+	for _, cover := range covers {
+		day := cover.CreationDate.Format("2006-01-02")
+		coversByDay[day] = append(coversByDay[day], cover)
 	}
+
+	now := time.Now()
+	for _, count := range counts {
+		day := count.Day.Format("2006-01-02")
+		dto.RecentDays = append(dto.RecentDays, RecentDay{
+			Date:   day,
+			Name:   utils.DayTitle(count.Day, now, language),
+			Count:  count.Count,
+			Covers: coversByDay[day],
+		})
+	}
+
+	return dto, nil
 }
@@ -0,0 +1,318 @@
+package repositories
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"github.com/lib/pq"
+	"github.com/mahdi-cpp/PhotoKit/models"
+	"github.com/mahdi-cpp/PhotoKit/storage"
+	"github.com/mahdi-cpp/PhotoKit/utils"
+	"golang.org/x/crypto/bcrypt"
+	"log"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// shareTokenBytes is the entropy of a share link token
+const shareTokenBytes = 24
+
+var (
+	ShareUnlockWindow     = 15 * time.Minute // period the wrong passwords are counted over
+	ShareUnlockTokenLimit = 10               // wrong passwords a link takes per window
+	ShareUnlockIpLimit    = 30               // wrong passwords one IP address can give per window, for any links
+)
+
+var (
+	ErrShareLinkGone        = errors.New("share link expired or revoked")
+	ErrShareLinkPassword    = errors.New("wrong share link password")
+	ErrShareLinkOriginals   = errors.New("share link does not allow originals")
+	ErrShareLinkRateLimited = errors.New("too many wrong passwords, try again later")
+)
+
+// ShareLinkSummary is a share link as its owner sees it
+type ShareLinkSummary struct {
+	models.ShareLink
+	HasPassword bool `json:"hasPassword"`
+	AssetCount  int  `json:"assetCount"`
+}
+
+// PublicAsset is what a share link tells about an asset, without its location, camera or
+// anything else that stays private
+type PublicAsset struct {
+	ID           int       `json:"id"`
+	MediaType    string    `json:"mediaType"`
+	Format       string    `json:"format"`
+	PixelWidth   int       `json:"pixelWidth"`
+	PixelHeight  int       `json:"pixelHeight"`
+	Duration     float64   `json:"duration"`
+	CreationDate time.Time `json:"creationDate"`
+}
+
+type ShareLinkRepository struct {
+	db *gorm.DB
+}
+
+func NewShareLinkRepository(db *gorm.DB) *ShareLinkRepository {
+
+	// Auto migrate the ShareLink models
+	err := db.AutoMigrate(&models.ShareLink{}, &models.ShareUnlockFailure{})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return &ShareLinkRepository{db: db}
+}
+
+// CreateShareLink creates a share link to an album of the user or to a selection of their assets
+func (r *ShareLinkRepository) CreateShareLink(userId int, req models.CreateShareLinkRequest) (*models.ShareLink, error) {
+	if (req.AlbumId == 0) == (len(req.AssetIds) == 0) {
+		return nil, errors.New("share either an album or assets")
+	}
+	if req.ExpiresAt != nil && req.ExpiresAt.Before(time.Now()) {
+		return nil, errors.New("expiry is in the past")
+	}
+
+	link := models.ShareLink{
+		UserId:         userId,
+		Named:          req.Named,
+		AlbumId:        req.AlbumId,
+		AllowOriginals: req.AllowOriginals,
+	}
+	// The column has no time zone and reads back as UTC, store the expiry in UTC
+	if req.ExpiresAt != nil {
+		expiresAt := req.ExpiresAt.UTC()
+		link.ExpiresAt = &expiresAt
+	}
+
+	if req.AlbumId != 0 {
+		var album models.Album
+		result := r.db.Where("user_id = ?", userId).First(&album, req.AlbumId)
+		if result.Error != nil {
+			return nil, errors.New("album not found")
+		}
+		if link.Named == "" {
+			link.Named = album.Named
+		}
+	} else {
+		var count int64
+		result := r.db.Model(&models.PHAsset{}).Where("user_id = ? AND id IN ?", userId, req.AssetIds).Count(&count)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if int(count) != len(req.AssetIds) {
+			return nil, errors.New("asset not found")
+		}
+		for _, assetId := range req.AssetIds {
+			link.AssetIds = append(link.AssetIds, int32(assetId))
+		}
+	}
+
+	if req.Password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, err
+		}
+		link.PasswordHash = string(hash)
+	}
+
+	token := make([]byte, shareTokenBytes)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+	link.Token = base64.RawURLEncoding.EncodeToString(token)
+
+	if err := r.db.Create(&link).Error; err != nil {
+		return nil, err
+	}
+
+	return &link, nil
+}
+
+// ListShareLinks retrieves the share links of the user, newest first
+func (r *ShareLinkRepository) ListShareLinks(userId int) ([]ShareLinkSummary, error) {
+	var links []models.ShareLink
+	result := r.db.Where("user_id = ?", userId).Order("created_at desc").Find(&links)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	summaries := make([]ShareLinkSummary, 0, len(links))
+	for _, link := range links {
+		var count int64
+		if err := r.db.Model(&models.PHAsset{}).Scopes(linkAssets(link)).Count(&count).Error; err != nil {
+			return nil, err
+		}
+
+		summaries = append(summaries, ShareLinkSummary{
+			ShareLink:   link,
+			HasPassword: link.PasswordHash != "",
+			AssetCount:  int(count),
+		})
+	}
+
+	return summaries, nil
+}
+
+// RevokeShareLink stops a share link from working, for good
+func (r *ShareLinkRepository) RevokeShareLink(userId, id int) error {
+	result := r.db.Model(&models.ShareLink{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userId).
+		Update("revoked_at", time.Now().UTC())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("share link not found")
+	}
+
+	return nil
+}
+
+// OpenShareLink retrieves the link of a token when it is still valid and, for a protected link,
+// the grant from UnlockShareLink or else the password matches. Only a given password is run
+// through bcrypt, and only while the link and the client at clientIp have not given too many
+// wrong ones.
+func (r *ShareLinkRepository) OpenShareLink(token, password, grant, clientIp string) (*models.ShareLink, error) {
+	var link models.ShareLink
+	result := r.db.Where("token = ?", token).First(&link)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, errors.New("share link not found")
+		}
+		return nil, result.Error
+	}
+
+	if link.RevokedAt != nil || (link.ExpiresAt != nil && link.ExpiresAt.Before(time.Now())) {
+		return nil, ErrShareLinkGone
+	}
+	if link.PasswordHash == "" {
+		return &link, nil
+	}
+
+	if grant != "" && utils.CheckShareGrant(grant, link.Token, link.PasswordHash) == nil {
+		return &link, nil
+	}
+	if password == "" {
+		return nil, ErrShareLinkPassword
+	}
+
+	if err := r.checkUnlockFailures(link.Token, clientIp); err != nil {
+		return nil, err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(link.PasswordHash), []byte(password)) != nil {
+		failure := models.ShareUnlockFailure{Token: link.Token, ClientIp: clientIp}
+		if err := r.db.Create(&failure).Error; err != nil {
+			return nil, err
+		}
+		return nil, ErrShareLinkPassword
+	}
+
+	return &link, nil
+}
+
+// checkUnlockFailures returns ErrShareLinkRateLimited when the link or the client gave too many
+// wrong passwords lately
+func (r *ShareLinkRepository) checkUnlockFailures(token, clientIp string) error {
+	since := time.Now().Add(-ShareUnlockWindow)
+
+	var ofToken int64
+	result := r.db.Model(&models.ShareUnlockFailure{}).
+		Where("token = ? AND created_at > ?", token, since).
+		Count(&ofToken)
+	if result.Error != nil {
+		return result.Error
+	}
+	if ofToken >= int64(ShareUnlockTokenLimit) {
+		return ErrShareLinkRateLimited
+	}
+
+	var fromIp int64
+	result = r.db.Model(&models.ShareUnlockFailure{}).
+		Where("client_ip = ? AND created_at > ?", clientIp, since).
+		Count(&fromIp)
+	if result.Error != nil {
+		return result.Error
+	}
+	if fromIp >= int64(ShareUnlockIpLimit) {
+		return ErrShareLinkRateLimited
+	}
+
+	return nil
+}
+
+// UnlockShareLink checks the password of a share link once and returns a signed grant to it that
+// expires after utils.ShareGrantTTL
+func (r *ShareLinkRepository) UnlockShareLink(token, password, clientIp string) (string, time.Time, error) {
+	link, err := r.OpenShareLink(token, password, "", clientIp)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	grant, expiresAt := utils.IssueShareGrant(link.Token, link.PasswordHash)
+	return grant, expiresAt, nil
+}
+
+// ViewShareLink opens a share link, counts the view and lists its assets, oldest first
+func (r *ShareLinkRepository) ViewShareLink(token, password, grant, clientIp string) (*models.ShareLink, []PublicAsset, error) {
+	link, err := r.OpenShareLink(token, password, grant, clientIp)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = r.db.Model(link).Update("view_count", gorm.Expr("view_count + 1")).Error
+	if err != nil {
+		return nil, nil, err
+	}
+	link.ViewCount++
+
+	assets := []PublicAsset{}
+	result := r.db.Model(&models.PHAsset{}).Scopes(linkAssets(*link)).
+		Order("creation_date").
+		Find(&assets)
+	if result.Error != nil {
+		return nil, nil, result.Error
+	}
+
+	return link, assets, nil
+}
+
+// SharedAssetKey returns the blob of an asset of a share link: its original, when the link
+// allows them, or one of its thumbnails. A protected link needs a grant from UnlockShareLink.
+func (r *ShareLinkRepository) SharedAssetKey(token, grant string, assetId int, original bool, size int) (string, error) {
+	link, err := r.OpenShareLink(token, "", grant, "")
+	if err != nil {
+		return "", err
+	}
+	if original && !link.AllowOriginals {
+		return "", ErrShareLinkOriginals
+	}
+	if !original && !slices.Contains(ThumbnailSizes, size) {
+		return "", errors.New("invalid thumbnail size")
+	}
+
+	var asset models.PHAsset
+	result := r.db.Scopes(linkAssets(*link)).First(&asset, assetId)
+	if result.Error != nil {
+		return "", errors.New("asset not found")
+	}
+
+	if original {
+		return storage.Keys.Original(asset), nil
+	}
+	return storage.Keys.Thumbnail(asset, size), nil
+}
+
+// linkAssets scopes a query to the visible assets of a share link, the assets of an album are
+// the ones in it now
+func linkAssets(link models.ShareLink) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		db = db.Where("user_id = ? AND is_hidden = ?", link.UserId, false)
+		if link.AlbumId != 0 {
+			return db.Where("albums @> ?", pq.Int32Array{int32(link.AlbumId)})
+		}
+		return db.Where("id = ANY(?)", link.AssetIds)
+	}
+}
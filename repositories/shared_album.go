@@ -1,81 +1,54 @@
 package repositories
 
 import (
-	"github.com/mahdi-cpp/PhotoKit/cache"
 	"github.com/mahdi-cpp/PhotoKit/models"
-	"github.com/mahdi-cpp/PhotoKit/utils"
+	"time"
 )
 
-var shareAlbumDTO ShareAlbumDTO
-
 type ShareAlbumDTO struct {
-	Albums []ShareAlbum `json:"albums"`
+	Albums []SharedAlbumSummary `json:"albums"`
 }
 
-type ShareAlbum struct {
-	Avatar    models.UIImage   `json:"avatar"`
-	Username  string           `json:"username"`
-	AlbumName string           `json:"albumName"`
-	Images    []models.UIImage `json:"images"`
+// SharedAlbumSummary is a shared album as seen by one of its members, Role is theirs
+type SharedAlbumSummary struct {
+	models.SharedAlbum
+	Owner       SharedAlbumUser `json:"owner"`
+	Role        string          `json:"role"`
+	AssetCount  int             `json:"assetCount"`
+	MemberCount int             `json:"memberCount"`
+	KeyAsset    *models.PHAsset `json:"keyAsset"`
 }
 
-func GetShareAlbums(folder string) ShareAlbumDTO {
-
-	var file = "data.txt"
-	var uiImages = cache.ReadOfFile(folder, file)
-	var shareAlbumDTO ShareAlbumDTO
-
-	var count = len(uiImages) / 6
-	var index = 0
-	var nameIndex = 0
-
-	if count > 25 {
-		count = 25
-	}
-
-	for i := 0; i < count; i++ {
-		var shareAlbum = ShareAlbum{}
-		if nameIndex+1 >= len(utils.FackNames) {
-			nameIndex = 0
-		}
-
-		var avatar = models.UIImage{}
-		switch i {
-		case 0:
-			avatar.Named = "chat_78"
-			break
-		case 1:
-			avatar.Named = "chat_8"
-			break
-		case 2:
-			avatar.Named = "chat_23"
-			break
-		case 3:
-			avatar.Named = "chat_16"
-			break
-		case 4:
-			avatar.Named = "chat_47"
-			break
-		default:
-			avatar.Named = "chat_41"
-		}
-
-		avatar.Size.Width = 500
-		avatar.Size.Height = 500
-		shareAlbum.Avatar = avatar
-		shareAlbum.Username = "username5"
-		shareAlbum.AlbumName = utils.FackTrips[nameIndex]
+// SharedAlbumUser is the public profile of a member of a shared album
+type SharedAlbumUser struct {
+	UserId     int    `json:"userId"`
+	Username   string `json:"username"`
+	FirstName  string `json:"firstName"`
+	LastName   string `json:"lastName"`
+	AvatarURL  string `json:"avatarUrl"`
+	Role       string `json:"role"`
+	IsAccepted bool   `json:"isAccepted"`
+}
 
-		for j := 0; j < 5; j++ {
-			var image models.UIImage
-			image = uiImages[index+2+j]
-			shareAlbum.Images = append(shareAlbum.Images, image)
-		}
+// SharedAsset is an asset of a shared album with who added it and the reactions of the members
+type SharedAsset struct {
+	models.PHAsset
+	ContributorId int       `json:"contributorId"`
+	AddedAt       time.Time `json:"addedAt"`
+	LikeCount     int       `json:"likeCount"`
+	CommentCount  int       `json:"commentCount"`
+	IsLiked       bool      `json:"isLiked"`
+}
 
-		shareAlbumDTO.Albums = append(shareAlbumDTO.Albums, shareAlbum)
-		index += 6
-		nameIndex += 1
+// newSharedAlbumUser makes the profile of a member, user is empty when the member has no account
+func newSharedAlbumUser(userId int, user models.User, role string, isAccepted bool) SharedAlbumUser {
+	return SharedAlbumUser{
+		UserId:     userId,
+		Username:   user.Username,
+		FirstName:  user.FirstName,
+		LastName:   user.LastName,
+		AvatarURL:  user.AvatarURL,
+		Role:       role,
+		IsAccepted: isAccepted,
 	}
-
-	return shareAlbumDTO
 }
@@ -0,0 +1,742 @@
+package repositories
+
+import (
+	"errors"
+	"github.com/mahdi-cpp/PhotoKit/models"
+	"log"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// sharedRoleOwner is the role of the creator of a shared album, not stored with the members
+const sharedRoleOwner = "owner"
+
+var (
+	ErrSharedAlbumForbidden = errors.New("not allowed in this shared album")
+	ErrAssetNotSharable     = errors.New("asset cannot be added to a shared album")
+)
+
+type SharedAlbumRepository struct {
+	db *gorm.DB
+}
+
+func NewSharedAlbumRepository(db *gorm.DB) *SharedAlbumRepository {
+
+	// Auto migrate the SharedAlbum models
+	err := db.AutoMigrate(&models.SharedAlbum{}, &models.SharedAlbumMember{}, &models.SharedAlbumAsset{},
+		&models.SharedAlbumComment{}, &models.SharedAlbumLike{})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return &SharedAlbumRepository{db: db}
+}
+
+// CreateSharedAlbum creates a new shared album owned by its user
+func (r *SharedAlbumRepository) CreateSharedAlbum(album *models.SharedAlbum) error {
+	if album == nil {
+		return errors.New("shared album cannot be nil")
+	}
+
+	return r.db.Create(album).Error
+}
+
+// access retrieves a shared album with the role of the user in it, the album is not found for
+// users who are neither its owner nor an accepted member
+func (r *SharedAlbumRepository) access(userId, id int) (*models.SharedAlbum, string, error) {
+	var album models.SharedAlbum
+	result := r.db.First(&album, id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, "", errors.New("shared album not found")
+		}
+		return nil, "", result.Error
+	}
+	if album.UserId == userId {
+		return &album, sharedRoleOwner, nil
+	}
+
+	var member models.SharedAlbumMember
+	result = r.db.Where("shared_album_id = ? AND user_id = ? AND is_accepted = ?", id, userId, true).First(&member)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, "", errors.New("shared album not found")
+		}
+		return nil, "", result.Error
+	}
+
+	return &album, member.Role, nil
+}
+
+// ownerAccess retrieves a shared album the user owns
+func (r *SharedAlbumRepository) ownerAccess(userId, id int) (*models.SharedAlbum, error) {
+	album, role, err := r.access(userId, id)
+	if err != nil {
+		return nil, err
+	}
+	if role != sharedRoleOwner {
+		return nil, ErrSharedAlbumForbidden
+	}
+
+	return album, nil
+}
+
+// GetSharedAlbum retrieves a shared album of the user with its members, the owner first
+func (r *SharedAlbumRepository) GetSharedAlbum(userId, id int) (*SharedAlbumSummary, []SharedAlbumUser, error) {
+	album, role, err := r.access(userId, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	summaries, err := r.summaries([]models.SharedAlbum{*album}, map[int]string{album.ID: role})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var members []models.SharedAlbumMember
+	result := r.db.Where("shared_album_id = ?", id).Order("created_at").Find(&members)
+	if result.Error != nil {
+		return nil, nil, result.Error
+	}
+
+	userIds := []int{album.UserId}
+	for _, member := range members {
+		userIds = append(userIds, member.UserId)
+	}
+	users, err := r.users(userIds)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	views := []SharedAlbumUser{newSharedAlbumUser(album.UserId, users[album.UserId], sharedRoleOwner, true)}
+	for _, member := range members {
+		views = append(views, newSharedAlbumUser(member.UserId, users[member.UserId], member.Role, member.IsAccepted))
+	}
+
+	return &summaries[0], views, nil
+}
+
+// ListSharedAlbums retrieves the shared albums the user owns or accepted to join, newest first
+func (r *SharedAlbumRepository) ListSharedAlbums(userId int) ([]SharedAlbumSummary, error) {
+	return r.listByMembership(userId, true)
+}
+
+// ListInvitations retrieves the shared albums the user is invited to and did not accept yet
+func (r *SharedAlbumRepository) ListInvitations(userId int) ([]SharedAlbumSummary, error) {
+	return r.listByMembership(userId, false)
+}
+
+func (r *SharedAlbumRepository) listByMembership(userId int, isAccepted bool) ([]SharedAlbumSummary, error) {
+	var members []models.SharedAlbumMember
+	result := r.db.Where("user_id = ? AND is_accepted = ?", userId, isAccepted).Find(&members)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	roles := make(map[int]string, len(members))
+	albumIds := make([]int, 0, len(members))
+	for _, member := range members {
+		roles[member.SharedAlbumId] = member.Role
+		albumIds = append(albumIds, member.SharedAlbumId)
+	}
+
+	query := r.db.Where("id IN ?", albumIds)
+	if isAccepted {
+		query = r.db.Where("user_id = ? OR id IN ?", userId, albumIds)
+	} else if len(albumIds) == 0 {
+		return []SharedAlbumSummary{}, nil
+	}
+
+	var albums []models.SharedAlbum
+	result = query.Order("created_at desc").Find(&albums)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	for _, album := range albums {
+		if album.UserId == userId {
+			roles[album.ID] = sharedRoleOwner
+		}
+	}
+
+	return r.summaries(albums, roles)
+}
+
+// summaries adds the owners, counts and key assets to shared albums
+func (r *SharedAlbumRepository) summaries(albums []models.SharedAlbum, roles map[int]string) ([]SharedAlbumSummary, error) {
+	summaries := make([]SharedAlbumSummary, 0, len(albums))
+	if len(albums) == 0 {
+		return summaries, nil
+	}
+
+	albumIds := make([]int, 0, len(albums))
+	ownerIds := make([]int, 0, len(albums))
+	for _, album := range albums {
+		albumIds = append(albumIds, album.ID)
+		ownerIds = append(ownerIds, album.UserId)
+	}
+
+	owners, err := r.users(ownerIds)
+	if err != nil {
+		return nil, err
+	}
+
+	var counts []struct {
+		SharedAlbumId int
+		Count         int
+	}
+
+	result := r.db.Model(&models.SharedAlbumAsset{}).
+		Select("shared_album_id, COUNT(*) as count").
+		Where("shared_album_id IN ?", albumIds).
+		Group("shared_album_id").
+		Scan(&counts)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	assetCounts := make(map[int]int, len(counts))
+	for _, c := range counts {
+		assetCounts[c.SharedAlbumId] = c.Count
+	}
+
+	counts = nil
+	result = r.db.Model(&models.SharedAlbumMember{}).
+		Select("shared_album_id, COUNT(*) as count").
+		Where("shared_album_id IN ? AND is_accepted = ?", albumIds, true).
+		Group("shared_album_id").
+		Scan(&counts)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	memberCounts := make(map[int]int, len(counts))
+	for _, c := range counts {
+		memberCounts[c.SharedAlbumId] = c.Count
+	}
+
+	for _, album := range albums {
+		summary := SharedAlbumSummary{
+			SharedAlbum: album,
+			Owner:       newSharedAlbumUser(album.UserId, owners[album.UserId], sharedRoleOwner, true),
+			Role:        roles[album.ID],
+			AssetCount:  assetCounts[album.ID],
+			MemberCount: memberCounts[album.ID] + 1, // the owner
+		}
+
+		if summary.AssetCount > 0 {
+			keyAsset, err := r.keyAsset(album)
+			if err == nil {
+				summary.KeyAsset = keyAsset
+			}
+		}
+
+		summaries = append(summaries, summary)
+	}
+
+	return summaries, nil
+}
+
+// users retrieves users by ID
+func (r *SharedAlbumRepository) users(ids []int) (map[int]models.User, error) {
+	var users []models.User
+	result := r.db.Where("id IN ?", ids).Find(&users)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	byId := make(map[int]models.User, len(users))
+	for _, user := range users {
+		byId[user.ID] = user
+	}
+
+	return byId, nil
+}
+
+// keyAsset returns the chosen key photo, falling back to the asset added last. The key asset is
+// cleared when it leaves the album.
+func (r *SharedAlbumRepository) keyAsset(album models.SharedAlbum) (*models.PHAsset, error) {
+	inAlbum := r.db.Where("shared_album_id = ?", album.ID)
+
+	assetId := album.KeyAssetId
+	if assetId == 0 {
+		var shared models.SharedAlbumAsset
+		result := inAlbum.Order("created_at desc").First(&shared)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		assetId = shared.AssetId
+	}
+
+	var asset models.PHAsset
+	result := r.db.First(&asset, assetId)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return &asset, nil
+}
+
+// RenameSharedAlbum changes the name of a shared album, only its owner can
+func (r *SharedAlbumRepository) RenameSharedAlbum(userId, id int, named string) error {
+	album, err := r.ownerAccess(userId, id)
+	if err != nil {
+		return err
+	}
+
+	return r.db.Model(album).Update("named", named).Error
+}
+
+// DeleteSharedAlbum deletes a shared album with its members, assets and reactions, only its
+// owner can. The assets themselves stay in the libraries of their contributors.
+func (r *SharedAlbumRepository) DeleteSharedAlbum(userId, id int) error {
+	album, err := r.ownerAccess(userId, id)
+	if err != nil {
+		return err
+	}
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for _, model := range []interface{}{&models.SharedAlbumLike{}, &models.SharedAlbumComment{},
+			&models.SharedAlbumAsset{}, &models.SharedAlbumMember{}} {
+			if err := tx.Where("shared_album_id = ?", id).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Delete(album).Error
+	})
+}
+
+// InviteMember invites a user to a shared album with a role, only its owner can
+func (r *SharedAlbumRepository) InviteMember(userId, id int, memberId int, role string) (*models.SharedAlbumMember, error) {
+	album, err := r.ownerAccess(userId, id)
+	if err != nil {
+		return nil, err
+	}
+	if memberId == album.UserId {
+		return nil, errors.New("the owner is already a member")
+	}
+
+	var count int64
+	result := r.db.Model(&models.User{}).Where("id = ?", memberId).Count(&count)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if count == 0 {
+		return nil, errors.New("user not found")
+	}
+
+	member := models.SharedAlbumMember{
+		SharedAlbumId: id,
+		UserId:        memberId,
+		Role:          role,
+		InvitedBy:     userId,
+	}
+
+	result = r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&member)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errors.New("user already invited")
+	}
+
+	return &member, nil
+}
+
+// UpdateMemberRole changes the role of a member, only the owner can
+func (r *SharedAlbumRepository) UpdateMemberRole(userId, id, memberId int, role string) error {
+	if _, err := r.ownerAccess(userId, id); err != nil {
+		return err
+	}
+
+	result := r.db.Model(&models.SharedAlbumMember{}).
+		Where("shared_album_id = ? AND user_id = ?", id, memberId).
+		Update("role", role)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("member not found")
+	}
+
+	return nil
+}
+
+// AcceptInvitation makes an invited user a member of the shared album
+func (r *SharedAlbumRepository) AcceptInvitation(userId, id int) error {
+	result := r.db.Model(&models.SharedAlbumMember{}).
+		Where("shared_album_id = ? AND user_id = ?", id, userId).
+		Update("is_accepted", true)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("invitation not found")
+	}
+
+	return nil
+}
+
+// RemoveMember removes a member, or declines an invitation, the owner can remove anybody and
+// members only themselves. The assets they added leave the album with them.
+func (r *SharedAlbumRepository) RemoveMember(userId, id, memberId int) error {
+	if userId != memberId {
+		if _, err := r.ownerAccess(userId, id); err != nil {
+			return err
+		}
+	}
+
+	var member models.SharedAlbumMember
+	result := r.db.Where("shared_album_id = ? AND user_id = ?", id, memberId).First(&member)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return errors.New("member not found")
+		}
+		return result.Error
+	}
+
+	var assetIds []int
+	result = r.db.Model(&models.SharedAlbumAsset{}).
+		Where("shared_album_id = ? AND contributor_id = ?", id, memberId).
+		Pluck("asset_id", &assetIds)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := removeSharedAssets(tx, id, assetIds); err != nil {
+			return err
+		}
+
+		return tx.Delete(&member).Error
+	})
+}
+
+// AddAssets adds assets of the user to a shared album, members with the viewer role cannot and
+// assets that do not allow sharing are refused
+func (r *SharedAlbumRepository) AddAssets(userId, id int, assetIds []int) (int64, error) {
+	_, role, err := r.access(userId, id)
+	if err != nil {
+		return 0, err
+	}
+	if role == models.SharedRoleViewer {
+		return 0, ErrSharedAlbumForbidden
+	}
+
+	var assets []models.PHAsset
+	result := r.db.Select("id", "can_add_to_shared_album").
+		Where("user_id = ? AND id IN ?", userId, assetIds).
+		Find(&assets)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if len(assets) != len(assetIds) {
+		return 0, errors.New("asset not found")
+	}
+
+	shared := make([]models.SharedAlbumAsset, 0, len(assets))
+	for _, asset := range assets {
+		if !asset.CanAddToSharedAlbum {
+			return 0, ErrAssetNotSharable
+		}
+		shared = append(shared, models.SharedAlbumAsset{
+			SharedAlbumId: id,
+			AssetId:       asset.ID,
+			ContributorId: userId,
+		})
+	}
+
+	result = r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&shared)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	return result.RowsAffected, nil
+}
+
+// RemoveAssets removes assets from a shared album, the owner can remove any asset and
+// contributors only the ones they added
+func (r *SharedAlbumRepository) RemoveAssets(userId, id int, assetIds []int) (int64, error) {
+	_, role, err := r.access(userId, id)
+	if err != nil {
+		return 0, err
+	}
+	if role == models.SharedRoleViewer {
+		return 0, ErrSharedAlbumForbidden
+	}
+
+	query := r.db.Model(&models.SharedAlbumAsset{}).Where("shared_album_id = ? AND asset_id IN ?", id, assetIds)
+	if role != sharedRoleOwner {
+		query = query.Where("contributor_id = ?", userId)
+	}
+
+	var removable []int
+	if result := query.Pluck("asset_id", &removable); result.Error != nil {
+		return 0, result.Error
+	}
+	if len(removable) != len(assetIds) {
+		return 0, ErrSharedAlbumForbidden
+	}
+
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		return removeSharedAssets(tx, id, removable)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return int64(len(removable)), nil
+}
+
+// SetKeyAsset chooses the asset shown as the shared album cover, only the owner can
+func (r *SharedAlbumRepository) SetKeyAsset(userId, id, assetId int) error {
+	album, err := r.ownerAccess(userId, id)
+	if err != nil {
+		return err
+	}
+	if err := r.checkAsset(id, assetId); err != nil {
+		return err
+	}
+
+	return r.db.Model(album).Update("key_asset_id", assetId).Error
+}
+
+// GetSharedAlbumAssets retrieves the assets of a shared album with pagination, the ones added
+// last first
+func (r *SharedAlbumRepository) GetSharedAlbumAssets(userId, id, limit, offset int) ([]SharedAsset, error) {
+	if _, _, err := r.access(userId, id); err != nil {
+		return nil, err
+	}
+
+	var shared []models.SharedAlbumAsset
+	result := r.db.Where("shared_album_id = ?", id).
+		Order("created_at desc").
+		Limit(limit).Offset(offset).
+		Find(&shared)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	assetIds := make([]int, 0, len(shared))
+	for _, s := range shared {
+		assetIds = append(assetIds, s.AssetId)
+	}
+
+	var assets []models.PHAsset
+	if result := r.db.Where("id IN ?", assetIds).Find(&assets); result.Error != nil {
+		return nil, result.Error
+	}
+	assetById := make(map[int]models.PHAsset, len(assets))
+	for _, asset := range assets {
+		assetById[asset.ID] = asset
+	}
+
+	likes, err := r.countByAsset(&models.SharedAlbumLike{}, id, assetIds)
+	if err != nil {
+		return nil, err
+	}
+	comments, err := r.countByAsset(&models.SharedAlbumComment{}, id, assetIds)
+	if err != nil {
+		return nil, err
+	}
+
+	var liked []int
+	result = r.db.Model(&models.SharedAlbumLike{}).
+		Where("shared_album_id = ? AND user_id = ? AND asset_id IN ?", id, userId, assetIds).
+		Pluck("asset_id", &liked)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	likedByUser := make(map[int]bool, len(liked))
+	for _, assetId := range liked {
+		likedByUser[assetId] = true
+	}
+
+	sharedAssets := make([]SharedAsset, 0, len(shared))
+	for _, s := range shared {
+		asset, exists := assetById[s.AssetId]
+		if !exists {
+			continue
+		}
+		sharedAssets = append(sharedAssets, SharedAsset{
+			PHAsset:       asset,
+			ContributorId: s.ContributorId,
+			AddedAt:       s.CreatedAt,
+			LikeCount:     likes[s.AssetId],
+			CommentCount:  comments[s.AssetId],
+			IsLiked:       likedByUser[s.AssetId],
+		})
+	}
+
+	return sharedAssets, nil
+}
+
+// countByAsset counts the likes or comments of assets of a shared album
+func (r *SharedAlbumRepository) countByAsset(model interface{}, id int, assetIds []int) (map[int]int, error) {
+	var counts []struct {
+		AssetId int
+		Count   int
+	}
+	result := r.db.Model(model).
+		Select("asset_id, COUNT(*) as count").
+		Where("shared_album_id = ? AND asset_id IN ?", id, assetIds).
+		Group("asset_id").
+		Scan(&counts)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	countByAsset := make(map[int]int, len(counts))
+	for _, c := range counts {
+		countByAsset[c.AssetId] = c.Count
+	}
+
+	return countByAsset, nil
+}
+
+// checkAsset verifies that an asset is in a shared album
+func (r *SharedAlbumRepository) checkAsset(id, assetId int) error {
+	var count int64
+	result := r.db.Model(&models.SharedAlbumAsset{}).
+		Where("shared_album_id = ? AND asset_id = ?", id, assetId).
+		Count(&count)
+	if result.Error != nil {
+		return result.Error
+	}
+	if count == 0 {
+		return errors.New("asset not in shared album")
+	}
+
+	return nil
+}
+
+// AddComment comments an asset of a shared album, every member can
+func (r *SharedAlbumRepository) AddComment(userId, id, assetId int, text string) (*models.SharedAlbumComment, error) {
+	if _, _, err := r.access(userId, id); err != nil {
+		return nil, err
+	}
+	if err := r.checkAsset(id, assetId); err != nil {
+		return nil, err
+	}
+
+	comment := models.SharedAlbumComment{
+		SharedAlbumId: id,
+		AssetId:       assetId,
+		UserId:        userId,
+		Text:          text,
+	}
+	if err := r.db.Create(&comment).Error; err != nil {
+		return nil, err
+	}
+
+	return &comment, nil
+}
+
+// GetComments retrieves the comments of an asset of a shared album, oldest first
+func (r *SharedAlbumRepository) GetComments(userId, id, assetId int) ([]models.SharedAlbumComment, error) {
+	if _, _, err := r.access(userId, id); err != nil {
+		return nil, err
+	}
+
+	comments := []models.SharedAlbumComment{}
+	result := r.db.Where("shared_album_id = ? AND asset_id = ?", id, assetId).
+		Order("created_at").
+		Find(&comments)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return comments, nil
+}
+
+// DeleteComment deletes a comment, its author and the owner of the album can
+func (r *SharedAlbumRepository) DeleteComment(userId, id, commentId int) error {
+	_, role, err := r.access(userId, id)
+	if err != nil {
+		return err
+	}
+
+	var comment models.SharedAlbumComment
+	result := r.db.Where("shared_album_id = ?", id).First(&comment, commentId)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return errors.New("comment not found")
+		}
+		return result.Error
+	}
+	if comment.UserId != userId && role != sharedRoleOwner {
+		return ErrSharedAlbumForbidden
+	}
+
+	return r.db.Delete(&comment).Error
+}
+
+// LikeAsset likes an asset of a shared album, liking twice is the same as once
+func (r *SharedAlbumRepository) LikeAsset(userId, id, assetId int) error {
+	if _, _, err := r.access(userId, id); err != nil {
+		return err
+	}
+	if err := r.checkAsset(id, assetId); err != nil {
+		return err
+	}
+
+	like := models.SharedAlbumLike{
+		SharedAlbumId: id,
+		AssetId:       assetId,
+		UserId:        userId,
+	}
+
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&like).Error
+}
+
+// UnlikeAsset takes back the like of the user on an asset of a shared album
+func (r *SharedAlbumRepository) UnlikeAsset(userId, id, assetId int) error {
+	if _, _, err := r.access(userId, id); err != nil {
+		return err
+	}
+
+	return r.db.Where("shared_album_id = ? AND asset_id = ? AND user_id = ?", id, assetId, userId).
+		Delete(&models.SharedAlbumLike{}).Error
+}
+
+// UnshareAssets removes assets from every shared album, for assets that are deleted or no longer
+// allowed in shared albums
+func UnshareAssets(db *gorm.DB, assetIds []int) error {
+	var shared []models.SharedAlbumAsset
+	result := db.Where("asset_id IN ?", assetIds).Find(&shared)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	assetsByAlbum := make(map[int][]int)
+	for _, s := range shared {
+		assetsByAlbum[s.SharedAlbumId] = append(assetsByAlbum[s.SharedAlbumId], s.AssetId)
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for albumId, ids := range assetsByAlbum {
+			if err := removeSharedAssets(tx, albumId, ids); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// removeSharedAssets deletes assets of a shared album with their reactions and clears the key
+// asset when it is one of them
+func removeSharedAssets(tx *gorm.DB, id int, assetIds []int) error {
+	if len(assetIds) == 0 {
+		return nil
+	}
+
+	for _, model := range []interface{}{&models.SharedAlbumLike{}, &models.SharedAlbumComment{}, &models.SharedAlbumAsset{}} {
+		if err := tx.Where("shared_album_id = ? AND asset_id IN ?", id, assetIds).Delete(model).Error; err != nil {
+			return err
+		}
+	}
+
+	return tx.Model(&models.SharedAlbum{}).
+		Where("id = ? AND key_asset_id IN ?", id, assetIds).
+		Update("key_asset_id", 0).Error
+}
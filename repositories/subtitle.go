@@ -26,7 +26,7 @@ func GetSubtitle() (*SubtitleDTO, error) {
 	fmt.Println("aliali45")
 
 	if err != nil {
-		fmt.Printf("Can not read File", err)
+		fmt.Println("Can not read File", err)
 		return nil, err
 	}
 
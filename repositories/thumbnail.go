@@ -0,0 +1,176 @@
+package repositories
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/disintegration/imaging"
+	"github.com/mahdi-cpp/PhotoKit/models"
+	"github.com/mahdi-cpp/PhotoKit/storage"
+	"image"
+	"slices"
+	"strconv"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// ThumbnailCache holds the rendered thumbnails, main replaces it from the configuration
+var ThumbnailCache storage.BlobStore = storage.NewLocalStore("/var/cloud/applications/PhotoKit/cache/thumbnails/")
+
+// MaxThumbnailSize is the largest width or height a thumbnail can be rendered at
+const MaxThumbnailSize = 2048
+
+// thumbnailBuckets are the widths and heights thumbnails are rendered at, requested sizes are
+// rounded up to one of them. This bounds the variants an asset can have in the disk cache, and
+// keeps the widths made at ingest so they are served as they are.
+var thumbnailBuckets = []int{70, 135, 270, 540, 1080, MaxThumbnailSize}
+
+// Fits and formats of a ThumbnailSpec
+const (
+	FitContain = "contain" // the whole image inside the box
+	FitCover   = "cover"   // the box filled, the image cropped around its center
+
+	ThumbnailJPEG = "jpeg"
+	ThumbnailPNG  = "png"
+)
+
+var ErrThumbnailSpec = errors.New("invalid thumbnail size, fit or format")
+
+// ThumbnailSpec is a variant of a thumbnail, a width or height of 0 follows the aspect ratio
+type ThumbnailSpec struct {
+	Width  int
+	Height int
+	Fit    string
+	Format string
+}
+
+// renders collapses concurrent renders of the same variant into one
+var renders singleflight.Group
+
+// Validate fills in the default fit and format, checks the spec and rounds its sizes up to the
+// sizes thumbnails are rendered at
+func (s *ThumbnailSpec) Validate() error {
+	if s.Fit == "" {
+		s.Fit = FitContain
+	}
+	if s.Format == "" || s.Format == "jpg" {
+		s.Format = ThumbnailJPEG
+	}
+
+	switch {
+	case s.Width < 0 || s.Height < 0 || s.Width > MaxThumbnailSize || s.Height > MaxThumbnailSize:
+		return ErrThumbnailSpec
+	case s.Width == 0 && s.Height == 0:
+		return ErrThumbnailSpec
+	case s.Fit != FitContain && s.Fit != FitCover:
+		return ErrThumbnailSpec
+	case s.Fit == FitCover && (s.Width == 0 || s.Height == 0):
+		return ErrThumbnailSpec
+	case s.Format != ThumbnailJPEG && s.Format != ThumbnailPNG:
+		return ErrThumbnailSpec
+	}
+
+	s.Width, s.Height = snapThumbnailSize(s.Width), snapThumbnailSize(s.Height)
+	return nil
+}
+
+// snapThumbnailSize rounds a width or height up to the next bucket, 0 stays 0
+func snapThumbnailSize(size int) int {
+	if size == 0 {
+		return 0
+	}
+	for _, bucket := range thumbnailBuckets {
+		if size <= bucket {
+			return bucket
+		}
+	}
+	return MaxThumbnailSize
+}
+
+// RenderThumbnail returns the store and key of a thumbnail variant of an asset, rendering it from
+// the original when it is not cached yet. The fixed widths made at ingest are pre-warmed variants.
+func RenderThumbnail(asset models.PHAsset, spec ThumbnailSpec) (storage.BlobStore, string, error) {
+	if err := spec.Validate(); err != nil {
+		return nil, "", err
+	}
+
+	if spec.Height == 0 && spec.Fit == FitContain && spec.Format == ThumbnailJPEG && slices.Contains(ThumbnailSizes, spec.Width) {
+		key := storage.Keys.Thumbnail(asset, spec.Width)
+		if _, err := storage.Blobs.Stat(key); err == nil {
+			return storage.Blobs, key, nil
+		}
+	}
+
+	key := thumbnailCacheKey(asset, spec)
+	if _, err := ThumbnailCache.Stat(key); err == nil {
+		return ThumbnailCache, key, nil
+	}
+
+	_, err, _ := renders.Do(key, func() (interface{}, error) {
+		return nil, renderThumbnail(asset, spec, key)
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return ThumbnailCache, key, nil
+}
+
+// thumbnailCacheKey addresses a variant by the content of its original, assets with the same
+// content share their variants and an original that changes gets new ones
+func thumbnailCacheKey(asset models.PHAsset, spec ThumbnailSpec) string {
+	content := asset.ContentHash
+	if content == "" {
+		content = strconv.Itoa(asset.UserId) + "/" + asset.URL
+	}
+
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%d|%d|%s|%s", content, spec.Width, spec.Height, spec.Fit, spec.Format)))
+	key := hex.EncodeToString(sum[:])
+
+	return key[:2] + "/" + key + "." + spec.Format
+}
+
+func renderThumbnail(asset models.PHAsset, spec ThumbnailSpec, key string) error {
+	src, err := assetSource(asset)
+	if err != nil {
+		return err
+	}
+
+	var dst image.Image
+	if spec.Fit == FitCover {
+		dst = imaging.Fill(src, spec.Width, spec.Height, imaging.Center, imaging.Lanczos)
+	} else {
+		dst = containImage(src, spec.Width, spec.Height)
+	}
+
+	format := imaging.JPEG
+	if spec.Format == ThumbnailPNG {
+		format = imaging.PNG
+	}
+
+	var buf bytes.Buffer
+	if err := imaging.Encode(&buf, dst, format, imaging.JPEGQuality(85)); err != nil {
+		return err
+	}
+
+	return storage.PutBytes(ThumbnailCache, key, buf.Bytes(), "image/"+spec.Format)
+}
+
+// containImage scales an image into a box without enlarging it
+func containImage(src image.Image, width, height int) image.Image {
+	bounds := src.Bounds()
+	if (width == 0 || bounds.Dx() <= width) && (height == 0 || bounds.Dy() <= height) {
+		return src
+	}
+
+	switch {
+	case width == 0:
+		return imaging.Resize(src, 0, height, imaging.Lanczos)
+	case height == 0:
+		return imaging.Resize(src, width, 0, imaging.Lanczos)
+	default:
+		return imaging.Fit(src, width, height, imaging.Lanczos)
+	}
+}
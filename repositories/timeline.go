@@ -0,0 +1,81 @@
+package repositories
+
+import (
+	"github.com/mahdi-cpp/PhotoKit/models"
+	"github.com/mahdi-cpp/PhotoKit/utils"
+	"gorm.io/gorm"
+)
+
+var bucketFormats = map[string]string{
+	utils.GranularityYear:  "YYYY",
+	utils.GranularityMonth: "YYYY-MM",
+	utils.GranularityDay:   "YYYY-MM-DD",
+}
+
+type TimelineDTO struct {
+	Granularity string                 `json:"granularity"`
+	Buckets     []utils.TimelineBucket `json:"buckets"`
+}
+
+// TimelinePage is a page of the assets of one bucket, newest first
+type TimelinePage struct {
+	Bucket     string           `json:"bucket"`
+	Assets     []models.PHAsset `json:"assets"`
+	NextCursor string           `json:"nextCursor"` // empty on the last page
+}
+
+func timelineAssets(userId int) func(tx *gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		return tx.Where("user_id = ? AND is_hidden = ? AND duplicate_of = 0", userId, false)
+	}
+}
+
+// GetTimeline counts the user's assets per year, month or day, newest first
+func GetTimeline(db *gorm.DB, userId int, granularity string) ([]utils.TimelineBucket, error) {
+	buckets := []utils.TimelineBucket{}
+	result := db.Model(&models.PHAsset{}).
+		Select("to_char(creation_date, ?) AS bucket, COUNT(*) AS count", bucketFormats[granularity]).
+		Scopes(timelineAssets(userId)).
+		Group("bucket").
+		Order("bucket DESC").
+		Scan(&buckets)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return buckets, nil
+}
+
+// GetBucketAssets retrieves the assets of one bucket, the cursor is the NextCursor of the previous page
+func GetBucketAssets(db *gorm.DB, userId int, bucket string, cursor string, limit int) (TimelinePage, error) {
+	page := TimelinePage{Bucket: bucket, Assets: []models.PHAsset{}}
+
+	_, start, end, err := utils.ParseBucket(bucket)
+	if err != nil {
+		return page, err
+	}
+
+	query := db.Scopes(timelineAssets(userId)).
+		Where("creation_date >= ? AND creation_date < ?", start, end)
+
+	if cursor != "" {
+		date, id, err := utils.DecodeCursor(cursor)
+		if err != nil {
+			return page, err
+		}
+		query = query.Where("(creation_date, id) < (?, ?)", date, id)
+	}
+
+	result := query.Order("creation_date DESC, id DESC").Limit(limit + 1).Find(&page.Assets)
+	if result.Error != nil {
+		return page, result.Error
+	}
+
+	if len(page.Assets) > limit {
+		page.Assets = page.Assets[:limit]
+		last := page.Assets[limit-1]
+		page.NextCursor = utils.EncodeCursor(last.CreationDate, last.ID)
+	}
+
+	return page, nil
+}
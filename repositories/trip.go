@@ -1,51 +1,196 @@
 package repositories
 
 import (
-	"github.com/mahdi-cpp/PhotoKit/cache"
+	"errors"
+	"github.com/lib/pq"
 	"github.com/mahdi-cpp/PhotoKit/models"
-	"github.com/mahdi-cpp/PhotoKit/utils"
+	"gorm.io/gorm"
+	"log"
 )
 
-var tripDTO TropeDTO
-var tripBigDTO TropeDTO
+type TripDTO struct {
+	Trips []TripSummary `json:"trips"`
+}
+
+// TripSummary is a trip with the data needed to render it in a list
+type TripSummary struct {
+	models.Trip
+	AssetCount int             `json:"assetCount"`
+	KeyAsset   *models.PHAsset `json:"keyAsset"`
+}
 
-type TropeDTO struct {
-	Trips []Trip `json:"trips"`
+type TripRepository struct {
+	db *gorm.DB
 }
 
-type Trip struct {
-	Name  string         `json:"name"`
-	Date  string         `json:"date"`
-	Image models.UIImage `json:"image"`
+func NewTripRepository(db *gorm.DB) *TripRepository {
+
+	// Auto migrate the Trip models
+	err := db.AutoMigrate(&models.Trip{})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return &TripRepository{db: db}
+}
+
+// DetectTrips runs the trip detection for a user
+func (r *TripRepository) DetectTrips(userId int) ([]models.Trip, error) {
+	return DetectTrips(r.db, userId)
+}
+
+// GetTripByID retrieves a trip of the user
+func (r *TripRepository) GetTripByID(userId, id int) (*models.Trip, error) {
+	var trip models.Trip
+	result := r.db.Where("user_id = ?", userId).First(&trip, id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, errors.New("trip not found")
+		}
+		return nil, result.Error
+	}
+
+	return &trip, nil
 }
 
-func GetTrips(folder string) {
+// ConfirmTrip keeps a suggested trip, later detections no longer replace it
+func (r *TripRepository) ConfirmTrip(userId, id int) error {
+	result := r.db.Model(&models.Trip{}).
+		Where("id = ? AND user_id = ?", id, userId).
+		Update("is_confirmed", true)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("trip not found")
+	}
 
-	var file = "data.txt"
-	var uiImages = cache.ReadOfFile(folder, file)
-	var count = len(uiImages)
+	return nil
+}
 
-	if count > 50 {
-		count = 50
+// RenameTrip changes the name of a trip, which also confirms it
+func (r *TripRepository) RenameTrip(userId, id int, named string) error {
+	result := r.db.Model(&models.Trip{}).
+		Where("id = ? AND user_id = ?", id, userId).
+		Updates(map[string]interface{}{"named": named, "is_confirmed": true})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("trip not found")
 	}
 
-	var index = 0
-	var nameIndex = 0
+	return nil
+}
+
+// MergeTrips merges the other trips into the first one, which is confirmed
+func (r *TripRepository) MergeTrips(userId int, ids []int) (*models.Trip, error) {
+	if len(ids) < 2 {
+		return nil, errors.New("at least two trips are needed")
+	}
 
-	for i := 0; i < count; i++ {
-		var trip = Trip{}
+	var trips []models.Trip
+	result := r.db.Where("user_id = ? AND id IN ?", userId, ids).Find(&trips)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if len(trips) != len(ids) {
+		return nil, errors.New("trip not found")
+	}
 
-		if nameIndex >= len(utils.FackTrips) {
-			nameIndex = 0
+	var target *models.Trip
+	for i := range trips {
+		if trips[i].ID == ids[0] {
+			target = &trips[i]
 		}
+	}
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		for _, trip := range trips {
+			if trip.ID == target.ID {
+				continue
+			}
+			if trip.StartDate.Before(target.StartDate) {
+				target.StartDate = trip.StartDate
+			}
+			if trip.EndDate.After(target.EndDate) {
+				target.EndDate = trip.EndDate
+			}
+
+			// Move the assets, skipping those that already are in the target
+			err := tx.Model(&models.PHAsset{}).
+				Where("user_id = ? AND trips @> ?", userId, pq.Int32Array{int32(trip.ID)}).
+				Update("trips", gorm.Expr("CASE WHEN trips @> ? THEN array_remove(trips, ?) ELSE array_replace(trips, ?, ?) END",
+					pq.Int32Array{int32(target.ID)}, trip.ID, trip.ID, target.ID)).Error
+			if err != nil {
+				return err
+			}
+
+			if err := tx.Delete(&models.Trip{}, trip.ID).Error; err != nil {
+				return err
+			}
+		}
+
+		target.IsConfirmed = true
+		return tx.Model(target).Updates(map[string]interface{}{
+			"start_date":   target.StartDate,
+			"end_date":     target.EndDate,
+			"is_confirmed": true,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return target, nil
+}
+
+// GetTripAssets retrieves the assets of a trip with pagination
+func (r *TripRepository) GetTripAssets(userId, id, limit, offset int) ([]models.PHAsset, error) {
+	var assets []models.PHAsset
+	result := r.db.Where("user_id = ? AND trips @> ?", userId, pq.Int32Array{int32(id)}).
+		Order("creation_date").
+		Limit(limit).Offset(offset).
+		Find(&assets)
+	if result.Error != nil {
+		return nil, result.Error
+	}
 
-		trip.Name = utils.FackTrips[nameIndex]
-		trip.Image = uiImages[index]
-		tripDTO.Trips = append(tripDTO.Trips, trip)
+	return assets, nil
+}
+
+// ListTrips retrieves the trips of a user, newest first, with their asset counts and key photos
+func (r *TripRepository) ListTrips(userId int) ([]TripSummary, error) {
+	var trips []models.Trip
+	result := r.db.Where("user_id = ?", userId).Order("start_date desc").Find(&trips)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	countByTrip, err := tripGroup.countAssets(r.db, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	chosen := make(map[int]int, len(trips))
+	for _, trip := range trips {
+		chosen[trip.ID] = trip.KeyAssetId
+	}
+	keyByTrip, err := tripGroup.keyAssets(r.db, userId, "creation_date", chosen)
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make([]TripSummary, 0, len(trips))
+	for _, trip := range trips {
+		summary := TripSummary{
+			Trip:       trip,
+			AssetCount: countByTrip[trip.ID],
+			KeyAsset:   keyByTrip[trip.ID],
+		}
 
-		nameIndex++
-		index++
+		summaries = append(summaries, summary)
 	}
 
-	index = 0
+	return summaries, nil
 }
@@ -0,0 +1,285 @@
+package repositories
+
+import (
+	"github.com/lib/pq"
+	"github.com/mahdi-cpp/PhotoKit/models"
+	"github.com/mahdi-cpp/PhotoKit/utils"
+	"gorm.io/gorm"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	homeCellDegrees = 0.1            // about 11 km, the grid used to find the home location
+	homeRadiusKm    = 50.0           // photos closer than this to home are not part of a trip
+	tripMaxGap      = 48 * time.Hour // a longer pause between away photos starts a new trip
+	tripHopGap      = 12 * time.Hour // with a pause this long, a far jump also starts a new trip
+	tripHopKm       = 1000.0         // the far jump
+	tripMinAssets   = 5              // smaller clusters are not suggested
+	tripMaxDuration = 60 * 24 * time.Hour
+)
+
+type tripAsset struct {
+	ID           int
+	CreationDate time.Time
+	Latitude     float64
+	Longitude    float64
+	City         string
+	Country      string
+	IsFavorite   bool
+}
+
+// DetectTrips groups the user's geotagged assets taken away from home into trips and replaces the
+// suggestions of the previous detection, confirmed trips and their assets are left untouched
+func DetectTrips(db *gorm.DB, userId int) ([]models.Trip, error) {
+
+	var assets []tripAsset
+	result := db.Model(&models.PHAsset{}).
+		Select("id, creation_date, latitude, longitude, city, country, is_favorite").
+		Where("user_id = ? AND latitude IS NOT NULL AND longitude IS NOT NULL AND duplicate_of = 0", userId).
+		Order("creation_date").
+		Scan(&assets)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	var confirmed []models.Trip
+	result = db.Where("user_id = ? AND is_confirmed = ?", userId, true).Find(&confirmed)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	var trips []models.Trip
+	var tripAssetIds [][]int // the located assets of every trip
+	if homeLat, homeLng, ok := inferHome(assets); ok {
+		for _, cluster := range clusterAwayAssets(assets, homeLat, homeLng) {
+			if len(cluster) < tripMinAssets || overlapsTrips(cluster, confirmed) {
+				continue
+			}
+			trips = append(trips, newTrip(userId, cluster))
+
+			ids := make([]int, len(cluster))
+			for i, asset := range cluster {
+				ids[i] = asset.ID
+			}
+			tripAssetIds = append(tripAssetIds, ids)
+		}
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var suggested []int
+		err := tx.Model(&models.Trip{}).
+			Where("user_id = ? AND is_confirmed = ?", userId, false).
+			Pluck("id", &suggested).Error
+		if err != nil {
+			return err
+		}
+
+		for _, id := range suggested {
+			err := tx.Model(&models.PHAsset{}).
+				Where("user_id = ? AND trips @> ?", userId, pq.Int32Array{int32(id)}).
+				Update("trips", gorm.Expr("array_remove(trips, ?)", id)).Error
+			if err != nil {
+				return err
+			}
+		}
+		if len(suggested) > 0 {
+			if err := tx.Delete(&models.Trip{}, suggested).Error; err != nil {
+				return err
+			}
+		}
+
+		for i := range trips {
+			if err := tx.Create(&trips[i]).Error; err != nil {
+				return err
+			}
+
+			// Assets without a location taken during the trip belong to it too, located ones only
+			// when they were taken away from home
+			err := tx.Model(&models.PHAsset{}).
+				Where("user_id = ?", userId).
+				Where("id IN ? OR (latitude IS NULL AND creation_date BETWEEN ? AND ?)", tripAssetIds[i], trips[i].StartDate, trips[i].EndDate).
+				Update("trips", gorm.Expr("array_append(COALESCE(trips, '{}'), ?)", trips[i].ID)).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return trips, nil
+}
+
+// inferHome returns the center of the grid cell where photos were taken on the most distinct days
+func inferHome(assets []tripAsset) (float64, float64, bool) {
+	type cell struct{ lat, lng int }
+	days := make(map[cell]map[string]bool)
+	for _, asset := range assets {
+		c := cell{
+			lat: int(math.Floor(asset.Latitude / homeCellDegrees)),
+			lng: int(math.Floor(asset.Longitude / homeCellDegrees)),
+		}
+		if days[c] == nil {
+			days[c] = make(map[string]bool)
+		}
+		days[c][asset.CreationDate.Format("2006-01-02")] = true
+	}
+
+	var home cell
+	var homeDays = 0
+	for c, d := range days {
+		if len(d) > homeDays || (len(d) == homeDays && (c.lat < home.lat || (c.lat == home.lat && c.lng < home.lng))) {
+			home = c
+			homeDays = len(d)
+		}
+	}
+	if homeDays == 0 {
+		return 0, 0, false
+	}
+
+	return (float64(home.lat) + 0.5) * homeCellDegrees, (float64(home.lng) + 0.5) * homeCellDegrees, true
+}
+
+// clusterAwayAssets splits the time ordered assets into runs taken away from home
+func clusterAwayAssets(assets []tripAsset, homeLat, homeLng float64) [][]tripAsset {
+	var clusters [][]tripAsset
+	var current []tripAsset
+
+	for _, asset := range assets {
+		if utils.HaversineKm(homeLat, homeLng, asset.Latitude, asset.Longitude) <= homeRadiusKm {
+			// Back home
+			if current != nil {
+				clusters = append(clusters, current)
+				current = nil
+			}
+			continue
+		}
+
+		if current != nil {
+			first := current[0]
+			last := current[len(current)-1]
+			gap := asset.CreationDate.Sub(last.CreationDate)
+			hop := utils.HaversineKm(last.Latitude, last.Longitude, asset.Latitude, asset.Longitude)
+			if gap > tripMaxGap || (gap > tripHopGap && hop > tripHopKm) || asset.CreationDate.Sub(first.CreationDate) > tripMaxDuration {
+				clusters = append(clusters, current)
+				current = nil
+			}
+		}
+		current = append(current, asset)
+	}
+	if current != nil {
+		clusters = append(clusters, current)
+	}
+
+	return clusters
+}
+
+func overlapsTrips(cluster []tripAsset, trips []models.Trip) bool {
+	start := cluster[0].CreationDate
+	end := cluster[len(cluster)-1].CreationDate
+	for _, trip := range trips {
+		if !start.After(trip.EndDate) && !end.Before(trip.StartDate) {
+			return true
+		}
+	}
+	return false
+}
+
+func newTrip(userId int, cluster []tripAsset) models.Trip {
+	var latitude, longitude float64
+	for _, asset := range cluster {
+		latitude += asset.Latitude
+		longitude += asset.Longitude
+	}
+	latitude /= float64(len(cluster))
+	longitude /= float64(len(cluster))
+
+	city, country := tripPlace(cluster, latitude, longitude)
+
+	// The first favorite is the cover, otherwise the photo in the middle of the trip
+	keyAsset := cluster[len(cluster)/2]
+	for _, asset := range cluster {
+		if asset.IsFavorite {
+			keyAsset = asset
+			break
+		}
+	}
+
+	return models.Trip{
+		UserId:     userId,
+		Named:      tripName(cluster, city, country),
+		City:       city,
+		Country:    country,
+		Latitude:   latitude,
+		Longitude:  longitude,
+		StartDate:  cluster[0].CreationDate,
+		EndDate:    cluster[len(cluster)-1].CreationDate,
+		KeyAssetId: keyAsset.ID,
+	}
+}
+
+// tripPlace returns the city and country where most of the photos of the trip were taken
+func tripPlace(cluster []tripAsset, latitude, longitude float64) (string, string) {
+	cities := countBy(cluster, func(asset tripAsset) string { return asset.City })
+	if len(cities) == 0 {
+		if city := utils.NearestCity(latitude, longitude); city != nil {
+			return city.Name, city.Country
+		}
+		return "", ""
+	}
+
+	for _, asset := range cluster {
+		if asset.City == cities[0] {
+			return asset.City, asset.Country
+		}
+	}
+	return cities[0], ""
+}
+
+// tripName names a trip after its city, its two main cities or its country when it covered more
+func tripName(cluster []tripAsset, city, country string) string {
+	cities := countBy(cluster, func(asset tripAsset) string { return asset.City })
+	countries := countBy(cluster, func(asset tripAsset) string { return asset.Country })
+
+	switch {
+	case len(cities) == 2 && len(countries) <= 1:
+		return cities[0] + " & " + cities[1]
+	case len(cities) > 2 && len(countries) == 1:
+		return countries[0]
+	case len(countries) > 1:
+		return strings.Join(countries[:min(len(countries), 2)], " & ")
+	case city != "":
+		return city
+	case country != "":
+		return country
+	}
+	return "Trip " + cluster[0].CreationDate.Format("January 2006")
+}
+
+// countBy returns the non empty keys of the assets, most frequent first
+func countBy(cluster []tripAsset, key func(tripAsset) string) []string {
+	counts := make(map[string]int)
+	for _, asset := range cluster {
+		if k := key(asset); k != "" {
+			counts[k]++
+		}
+	}
+
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+
+	return keys
+}
@@ -15,8 +15,8 @@ type UserRepository struct {
 
 func NewUserRepository(db *gorm.DB) *UserRepository {
 
-	// Auto migrate the User models
-	err := db.AutoMigrate(&models.User{})
+	// Auto migrate the User and RefreshToken models
+	err := db.AutoMigrate(&models.User{}, &models.RefreshToken{})
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -121,17 +121,19 @@ func (r *UserRepository) UpdateUser(id int, updatedUser *models.User) error {
 	return nil
 }
 
-// DeleteUser deletes a user by their ID
+// DeleteUser deletes a user by their ID and signs them out everywhere
 func (r *UserRepository) DeleteUser(id int) error {
-	result := r.db.Delete(&models.User{}, id)
-	if result.Error != nil {
-		return result.Error
-	}
-	if result.RowsAffected == 0 {
-		return errors.New("user not found")
-	}
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&models.User{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("user not found")
+		}
 
-	return nil
+		return tx.Where("user_id = ?", id).Delete(&models.RefreshToken{}).Error
+	})
 }
 
 // ListUsers retrieves a list of users with pagination
@@ -1,121 +1,361 @@
 package routes
 
 import (
-	"crypto/md5"
+	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/mahdi-cpp/PhotoKit/cache"
-	"io"
+	"github.com/mahdi-cpp/PhotoKit/models"
+	"github.com/mahdi-cpp/PhotoKit/repositories"
+	"github.com/mahdi-cpp/PhotoKit/storage"
+	"github.com/mahdi-cpp/PhotoKit/utils"
+	"gorm.io/gorm"
 	"net/http"
 	"os"
+	"path"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 )
 
-func AddDownloadRoutes(rg *gin.RouterGroup) {
+// AddDownloadRoutes serves the files of the signed-in user and of the shared albums they are in
+func AddDownloadRoutes(rg *gin.RouterGroup, db *gorm.DB) {
 	route := rg.Group("/download")
-	apiOriginalDownload(route)
-	apiDownloadThumb(route)
+	apiOriginalDownload(route, db)
+	apiDownloadThumb(route, db)
+	apiAssetDownload(route, db)
 	apiIcon(route)
 }
 
-func apiOriginalDownload(route *gin.RouterGroup) {
+// AddSharedDownloadRoutes serves the files of public share links, it needs no account
+func AddSharedDownloadRoutes(rg *gin.RouterGroup, db *gorm.DB) {
+	route := rg.Group("/download")
+	apiShareLink(route, repositories.NewShareLinkRepository(db))
+}
+
+func apiOriginalDownload(route *gin.RouterGroup, db *gorm.DB) {
 
 	route.GET("/:filename", func(c *gin.Context) {
 
+		userId, err := utils.GetUserID(c)
+		if err != nil {
+			c.AbortWithStatusJSON(400, gin.H{"error": "Invalid user ID"})
+			return
+		}
+
 		filename := c.Param("filename")
-		filepath, err := cache.SearchFile(filename)
+		key, legacy, err := resolveFile(db, userId, filename)
 		if err != nil {
 			c.AbortWithStatusJSON(404, gin.H{"error": "File not found"})
 			return
 		}
 
-		fileSize, err := getFileSize(filepath)
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
+		if legacy != "" {
+			utils.ServeFile(c, legacy, "", utils.CacheRevalidate)
+			return
+		}
+
+		c.Header("Content-Type", utils.ContentTypeOf(strings.TrimPrefix(path.Ext(key), ".")))
+		utils.ServeBlob(c, storage.Blobs, key, "", utils.CacheRevalidate)
+	})
+}
+
+// resolveFile finds a file by name among the blobs of the assets the user can see, then in the legacy
+// folders of the user. It returns the key of the blob or the path of the legacy file.
+func resolveFile(db *gorm.DB, userId int, filename string) (key string, legacy string, err error) {
+	key, err = repositories.UserAssetFile(db, userId, filename)
+	if errors.Is(err, repositories.ErrFileNotFound) {
+		if legacy, ok := storage.Keys.Legacy(userId, filename); ok {
+			return "", legacy, nil
+		}
+	}
+	return key, "", err
+}
+
+// apiAssetDownload serves the files of an asset by its ID, the original or a thumbnail of a fixed
+// width
+func apiAssetDownload(route *gin.RouterGroup, db *gorm.DB) {
+
+	route.GET("/asset/:id", func(c *gin.Context) {
+		userId, err := utils.GetUserID(c)
+		if err != nil {
+			utils.SendError(c, http.StatusBadRequest, "Invalid user ID")
+			return
+		}
+
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			utils.SendError(c, http.StatusBadRequest, "Invalid asset ID")
+			return
+		}
+
+		asset, err := repositories.UserAsset(db, userId, id)
 		if err != nil {
-			c.AbortWithStatusJSON(500, gin.H{"error": "Failed to get file size"})
+			utils.SendError(c, http.StatusNotFound, err.Error())
 			return
 		}
 
-		//etag, err := generateETag(filepath)
-		//if err != nil {
-		//	c.AbortWithStatusJSON(500, gin.H{"error": "Failed to generate ETag"})
-		//	return
-		//}
+		// The content hash names the bytes of the original, assets from before hashing fall back
+		// to the ETag of the store
+		etag := ""
+		if asset.ContentHash != "" {
+			etag = utils.ContentETag(asset.ContentHash)
+		}
+
+		key := storage.Keys.Original(asset)
+		c.Header("Content-Type", utils.ContentTypeOf(asset.Format))
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", path.Base(key)))
+		utils.ServeBlob(c, storage.Blobs, key, etag, utils.CacheRevalidate)
+	})
 
-		//c.Header("Content-Type", "mage/jpeg")
-		//c.Header("Content-Encoding", "identity") // Disable compression
-		//c.Next()
-		c.Header("Content-Length", fmt.Sprintf("%d", fileSize))
-		//.Header("ETag", etag)
-		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
-		c.Header("Accept-Ranges", "bytes")
-		c.File(filepath)
+	route.GET("/asset/:id/thumbnail/:size", func(c *gin.Context) {
+		userId, err := utils.GetUserID(c)
+		if err != nil {
+			utils.SendError(c, http.StatusBadRequest, "Invalid user ID")
+			return
+		}
+
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			utils.SendError(c, http.StatusBadRequest, "Invalid asset ID")
+			return
+		}
+
+		size, err := strconv.Atoi(c.Param("size"))
+		if err != nil || !slices.Contains(repositories.ThumbnailSizes, size) {
+			utils.SendError(c, http.StatusBadRequest, "Invalid size")
+			return
+		}
+
+		asset, err := repositories.UserAsset(db, userId, id)
+		if err != nil {
+			utils.SendError(c, http.StatusNotFound, err.Error())
+			return
+		}
+
+		serveThumbnail(c, storage.Keys.Thumbnail(asset, size), "")
 	})
 }
 
-func getFileSize(filepath string) (int64, error) {
-	fileInfo, err := os.Stat(filepath)
-	if err != nil {
-		return 0, err
+// serveThumbnail serves a thumbnail of a fixed width, a blob or a legacy file, through the
+// thumbnail cache. Thumbnails are made once at ingest, so clients keep them for good and their
+// ETag is the hash of their bytes. Last-Modified comes from the store, the cache keeps bytes only.
+func serveThumbnail(c *gin.Context, key string, legacy string) {
+	load := func() ([]byte, error) {
+		return storage.ReadBlob(storage.Blobs, key)
+	}
+	modTime := func() time.Time {
+		info, err := storage.Blobs.Stat(key)
+		if err != nil {
+			return time.Time{}
+		}
+		return info.ModTime
+	}
+	if legacy != "" {
+		key = legacy
+		load = func() ([]byte, error) {
+			return os.ReadFile(legacy)
+		}
+		modTime = func() time.Time {
+			info, err := os.Stat(legacy)
+			if err != nil {
+				return time.Time{}
+			}
+			return info.ModTime()
+		}
 	}
-	return fileInfo.Size(), nil
-}
 
-func generateETag(filepath string) (string, error) {
-	file, err := os.Open(filepath)
+	imgData, err := cache.LoadThumbCash(key, load)
 	if err != nil {
-		return "", err
+		utils.SendError(c, http.StatusNotFound, "File not found")
+		return
 	}
-	defer file.Close()
 
-	hash := md5.New()
-	if _, err := io.Copy(hash, file); err != nil {
-		return "", err
-	}
+	utils.ServeBytes(c, "image/jpeg", imgData, modTime(), bytesETag(imgData), utils.CacheImmutable)
+}
 
-	return hex.EncodeToString(hash.Sum(nil)), nil
+// bytesETag returns a strong ETag from the SHA-256 of content held in memory
+func bytesETag(data []byte) string {
+	sum := sha256.Sum256(data)
+	return utils.ContentETag(hex.EncodeToString(sum[:]))
 }
 
-func apiDownloadThumb(route *gin.RouterGroup) {
+func apiDownloadThumb(route *gin.RouterGroup, db *gorm.DB) {
 
 	route.GET("/thumbnail/:filename", func(c *gin.Context) {
 
 		filename := c.Param("filename")
 
 		if strings.Contains(filename, "png") {
-			imgData, exists := cache.GetIconCash(filename)
-			if exists {
-				c.Data(http.StatusOK, "image/png", imgData) // Adjust MIME type as necessary
-			}
+			serveIcon(c, filename)
 			return
 		}
 
-		imgData, exists := cache.GetThumbCash(filename)
-		if exists {
-			fmt.Println("RAM")
-			c.Data(http.StatusOK, "image/jpeg", imgData) // Adjust MIME type as necessary
-		} else {
-
-			filepath, err := cache.SearchFile(filename)
-			if err != nil {
-				fmt.Println("SearchFile error", err)
-				return
-			}
+		userId, err := utils.GetUserID(c)
+		if err != nil {
+			c.AbortWithStatusJSON(400, gin.H{"error": "Invalid user ID"})
+			return
+		}
 
-			fmt.Println("SSD")
-			c.File(filepath)
-			cache.AddThumbCash(filepath, filename)
+		// Resolve first so the cache only serves thumbnails of the user's assets
+		key, legacy, err := resolveFile(db, userId, filename)
+		if err != nil {
+			c.AbortWithStatusJSON(404, gin.H{"error": "File not found"})
+			return
 		}
+
+		serveThumbnail(c, key, legacy)
 	})
 }
 
 func apiIcon(route *gin.RouterGroup) {
 
 	route.GET("/icons/:filename", func(c *gin.Context) {
-		filename := c.Param("filename")
-		imgData, exists := cache.GetIconCash(filename)
-		if exists {
-			c.Data(http.StatusOK, "image/png", imgData) // Adjust MIME type as necessary
+		serveIcon(c, c.Param("filename"))
+	})
+}
+
+// iconsModTime stands in for the modification time of the icons, they ship with the server and
+// only change with a restart
+var iconsModTime = time.Now()
+
+// serveIcon serves an icon from the icon cache, its ETag is the hash of its bytes
+func serveIcon(c *gin.Context, filename string) {
+	imgData, exists := cache.GetIconCash(filename)
+	if !exists {
+		utils.SendError(c, http.StatusNotFound, "File not found")
+		return
+	}
+
+	utils.ServeBytes(c, "image/png", imgData, iconsModTime, bytesETag(imgData), "public, max-age=86400")
+}
+
+// apiShareLink serves the assets of public share links, people without an account only reach the
+// assets of the link. A protected link is unlocked once with its password, in the
+// X-Share-Password header or the body, for a grant that comes back in the X-Share-Grant header
+// or the cookie set by the unlock.
+func apiShareLink(route *gin.RouterGroup, shareLinkRepo *repositories.ShareLinkRepository) {
+
+	route.POST("/shared/:token/unlock", func(c *gin.Context) {
+		password := sharePassword(c)
+		if password == "" {
+			var req models.UnlockShareLinkRequest
+			if err := c.ShouldBindJSON(&req); err != nil {
+				utils.SendError(c, http.StatusBadRequest, "Password required")
+				return
+			}
+			password = req.Password
+		}
+
+		grant, expiresAt, err := shareLinkRepo.UnlockShareLink(c.Param("token"), password, c.ClientIP())
+		if err != nil {
+			sendShareLinkError(c, err)
+			return
+		}
+
+		// Browsers send the cookie with the image requests of the link, which cannot carry headers
+		c.SetSameSite(http.SameSiteLaxMode)
+		c.SetCookie(shareGrantCookie, grant, int(time.Until(expiresAt).Seconds()),
+			strings.TrimSuffix(c.Request.URL.Path, "/unlock"), "", c.Request.TLS != nil, true)
+
+		c.JSON(http.StatusOK, gin.H{
+			"grant":     grant,
+			"expiresAt": expiresAt,
+		})
+	})
+
+	route.GET("/shared/:token", func(c *gin.Context) {
+		link, assets, err := shareLinkRepo.ViewShareLink(c.Param("token"), sharePassword(c), shareGrant(c), c.ClientIP())
+		if err != nil {
+			sendShareLinkError(c, err)
+			return
 		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"named":          link.Named,
+			"allowOriginals": link.AllowOriginals,
+			"expiresAt":      link.ExpiresAt,
+			"assets":         assets,
+		})
 	})
+
+	route.GET("/shared/:token/thumbnail/:assetId", func(c *gin.Context) {
+		assetId, err := strconv.Atoi(c.Param("assetId"))
+		if err != nil {
+			utils.SendError(c, http.StatusBadRequest, "Invalid asset ID")
+			return
+		}
+
+		size, err := strconv.Atoi(c.DefaultQuery("size", strconv.Itoa(repositories.ThumbnailSizes[0])))
+		if err != nil {
+			utils.SendError(c, http.StatusBadRequest, "Invalid size")
+			return
+		}
+
+		key, err := shareLinkRepo.SharedAssetKey(c.Param("token"), shareGrant(c), assetId, false, size)
+		if err != nil {
+			sendShareLinkError(c, err)
+			return
+		}
+
+		// Links can be revoked, so shared thumbnails are only kept for a while
+		c.Header("Content-Type", "image/jpeg")
+		utils.ServeBlob(c, storage.Blobs, key, "", "private, max-age=3600")
+	})
+
+	route.GET("/shared/:token/original/:assetId", func(c *gin.Context) {
+		assetId, err := strconv.Atoi(c.Param("assetId"))
+		if err != nil {
+			utils.SendError(c, http.StatusBadRequest, "Invalid asset ID")
+			return
+		}
+
+		key, err := shareLinkRepo.SharedAssetKey(c.Param("token"), shareGrant(c), assetId, true, 0)
+		if err != nil {
+			sendShareLinkError(c, err)
+			return
+		}
+
+		c.Header("Content-Type", utils.ContentTypeOf(strings.TrimPrefix(path.Ext(key), ".")))
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%d%s\"", assetId, path.Ext(key)))
+		utils.ServeBlob(c, storage.Blobs, key, "", utils.CacheRevalidate)
+	})
+}
+
+// sharePassword reads the password of a protected link from its header only, query strings end
+// up in access logs
+func sharePassword(c *gin.Context) string {
+	return c.GetHeader("X-Share-Password")
+}
+
+// shareGrantCookie holds the grant to a protected link, scoped to the paths of the link
+const shareGrantCookie = "share_grant"
+
+// shareGrant reads the grant from UnlockShareLink, from its header or its cookie
+func shareGrant(c *gin.Context) string {
+	if grant := c.GetHeader("X-Share-Grant"); grant != "" {
+		return grant
+	}
+	grant, _ := c.Cookie(shareGrantCookie)
+	return grant
+}
+
+func sendShareLinkError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repositories.ErrShareLinkGone):
+		utils.SendError(c, http.StatusGone, err.Error())
+	case errors.Is(err, repositories.ErrShareLinkPassword):
+		utils.SendError(c, http.StatusUnauthorized, err.Error())
+	case errors.Is(err, repositories.ErrShareLinkRateLimited):
+		c.Header("Retry-After", strconv.Itoa(int(repositories.ShareUnlockWindow.Seconds())))
+		utils.SendError(c, http.StatusTooManyRequests, err.Error())
+	case errors.Is(err, repositories.ErrShareLinkOriginals):
+		utils.SendError(c, http.StatusForbidden, err.Error())
+	default:
+		utils.SendError(c, http.StatusNotFound, err.Error())
+	}
 }
@@ -0,0 +1,26 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/mahdi-cpp/PhotoKit/controllers"
+	"github.com/mahdi-cpp/PhotoKit/repositories"
+	"gorm.io/gorm"
+)
+
+func SetupAlbumRoutes(rg *gin.RouterGroup, db *gorm.DB) {
+
+	albumRepo := repositories.NewAlbumRepository(db)
+	albumController := controllers.NewAlbumController(albumRepo)
+
+	albumRoutes := rg.Group("/albums")
+	{
+		albumRoutes.GET("/", albumController.ListAlbums)
+		albumRoutes.POST("/", albumController.CreateAlbum)
+		albumRoutes.GET("/:id", albumController.GetAlbum)
+		albumRoutes.PUT("/:id", albumController.RenameAlbum)
+		albumRoutes.DELETE("/:id", albumController.DeleteAlbum)
+		albumRoutes.POST("/:id/assets", albumController.AddAssets)
+		albumRoutes.DELETE("/:id/assets", albumController.RemoveAssets)
+		albumRoutes.PUT("/:id/key", albumController.SetKeyAsset)
+	}
+}
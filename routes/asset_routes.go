@@ -6,19 +6,25 @@ import (
 	"gorm.io/gorm"
 )
 
-func SetupAssetRoutes(router *gin.Engine, db *gorm.DB) {
+func SetupAssetRoutes(rg *gin.RouterGroup, db *gorm.DB) {
 	assetController := controllers.NewAssetController(db)
 
-	assetRoutes := router.Group("/v1/assets")
+	assetRoutes := rg.Group("/assets")
 	{
 		assetRoutes.GET("/", assetController.ListAssets)
 		assetRoutes.POST("/", assetController.CreateAsset)
+		assetRoutes.POST("/upload", assetController.UploadAssets)
 		assetRoutes.GET("/:id", assetController.GetAsset)
 		assetRoutes.PUT("/:id", assetController.UpdateAsset)
 		assetRoutes.DELETE("/:id", assetController.DeleteAsset)
 		assetRoutes.PATCH("/:id/favorite", assetController.ToggleFavorite)
+		assetRoutes.GET("/:id/metadata", assetController.GetMetadata)
+		assetRoutes.GET("/:id/thumb", assetController.GetThumbnail)
 
 		assetRoutes.GET("/cameras", assetController.ListCameras)
 		assetRoutes.GET("/cameras2", assetController.ListCamerasWithImages)
+		assetRoutes.GET("/duplicates", assetController.ListDuplicates)
+		assetRoutes.GET("/similar", assetController.ListSimilarGroups)
+		assetRoutes.GET("/similar/:id", assetController.FindSimilar)
 	}
 }
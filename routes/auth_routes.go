@@ -0,0 +1,24 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/mahdi-cpp/PhotoKit/controllers"
+	"github.com/mahdi-cpp/PhotoKit/repositories"
+	"gorm.io/gorm"
+)
+
+func SetupAuthRoutes(rg *gin.RouterGroup, db *gorm.DB) {
+
+	authRepo := repositories.NewAuthRepository(db)
+	authController := controllers.NewAuthController(authRepo)
+
+	authRoutes := rg.Group("/auth")
+	{
+		authRoutes.POST("/register", authController.Register)
+		authRoutes.POST("/login", authController.Login)
+		authRoutes.POST("/refresh", authController.Refresh)
+		authRoutes.POST("/logout", authController.Logout)
+		authRoutes.POST("/otp/request", authController.RequestOtp)
+		authRoutes.POST("/otp/verify", authController.VerifyOtp)
+	}
+}
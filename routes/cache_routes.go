@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/mahdi-cpp/PhotoKit/cache"
+	"net/http"
+)
+
+func AddCacheRoutes(rg *gin.RouterGroup) {
+
+	route := rg.Group("/cache")
+
+	// Hits, misses and evictions of the thumbnail and icon caches
+	route.GET("/stats", func(context *gin.Context) {
+		context.JSON(http.StatusOK, cache.Stats())
+	})
+}
@@ -0,0 +1,29 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/mahdi-cpp/PhotoKit/controllers"
+	"github.com/mahdi-cpp/PhotoKit/repositories"
+	"gorm.io/gorm"
+)
+
+func SetupPersonRoutes(rg *gin.RouterGroup, db *gorm.DB) {
+
+	personRepo := repositories.NewPersonRepository(db)
+	personController := controllers.NewPersonController(personRepo)
+
+	personRoutes := rg.Group("/persons")
+	{
+		personRoutes.GET("/", personController.ListPersons)
+		personRoutes.POST("/", personController.CreatePerson)
+		personRoutes.POST("/merge", personController.MergePersons)
+		personRoutes.GET("/suggestions", personController.GetSuggestions)
+		personRoutes.GET("/:id", personController.GetPerson)
+		personRoutes.PUT("/:id", personController.UpdatePerson)
+		personRoutes.POST("/:id/regions", personController.TagRegion)
+		personRoutes.DELETE("/:id/regions/:regionId", personController.UntagRegion)
+		personRoutes.POST("/:id/faces", personController.AssignRegions)
+		personRoutes.PUT("/:id/key", personController.SetKeyFace)
+		personRoutes.GET("/:id/face", personController.GetKeyFace)
+	}
+}
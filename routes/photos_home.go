@@ -3,10 +3,20 @@ package routes
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/mahdi-cpp/PhotoKit/repositories"
+	"github.com/mahdi-cpp/PhotoKit/utils"
+	"gorm.io/gorm"
 	"net/http"
+	"strconv"
+	"time"
 )
 
-func AddPhotosHomeRoutes(rg *gin.RouterGroup) {
+func AddPhotosHomeRoutes(rg *gin.RouterGroup, db *gorm.DB) {
+
+	albumRepo := repositories.NewAlbumRepository(db)
+	tripRepo := repositories.NewTripRepository(db)
+	memoryRepo := repositories.NewMemoryRepository(db)
+	personRepo := repositories.NewPersonRepository(db)
+	sharedAlbumRepo := repositories.NewSharedAlbumRepository(db)
 
 	route := rg.Group("/photos")
 
@@ -18,23 +28,288 @@ func AddPhotosHomeRoutes(rg *gin.RouterGroup) {
 	//	context.JSON(http.StatusOK, repositories.RestLibrary())
 	//})
 	route.GET("/collections", func(context *gin.Context) {
-		context.JSON(http.StatusOK, repositories.RestCollections())
+		userId, err := utils.GetUserID(context)
+		if err != nil {
+			utils.SendError(context, http.StatusBadRequest, "Invalid user ID")
+			return
+		}
+
+		recentDays, err := repositories.GetRecentDays(db, userId, 30, time.Time{}, 10, utils.GetLanguage(context))
+		if err != nil {
+			utils.SendError(context, http.StatusInternalServerError, "Failed to fetch recent days")
+			return
+		}
+
+		people, err := personRepo.ListPersons(userId, false)
+		if err != nil {
+			utils.SendError(context, http.StatusInternalServerError, "Failed to fetch people")
+			return
+		}
+
+		pinned, err := repositories.GetPinnedCollections(db, userId, utils.GetLanguage(context))
+		if err != nil {
+			utils.SendError(context, http.StatusInternalServerError, "Failed to fetch pinned collections")
+			return
+		}
+
+		albums, err := albumRepo.ListAlbums(userId)
+		if err != nil {
+			utils.SendError(context, http.StatusInternalServerError, "Failed to fetch albums")
+			return
+		}
+
+		sharedAlbums, err := sharedAlbumRepo.ListSharedAlbums(userId)
+		if err != nil {
+			utils.SendError(context, http.StatusInternalServerError, "Failed to fetch shared albums")
+			return
+		}
+
+		trips, err := tripRepo.ListTrips(userId)
+		if err != nil {
+			utils.SendError(context, http.StatusInternalServerError, "Failed to fetch trips")
+			return
+		}
+
+		context.JSON(http.StatusOK, repositories.RestCollections(recentDays, people, pinned, albums, sharedAlbums, trips))
 	})
 
 	route.GET("/recent", func(context *gin.Context) {
-		context.JSON(http.StatusOK, repositories.RestRecentDays())
+		userId, err := utils.GetUserID(context)
+		if err != nil {
+			utils.SendError(context, http.StatusBadRequest, "Invalid user ID")
+			return
+		}
+
+		days, err := strconv.Atoi(context.DefaultQuery("days", "30"))
+		if err != nil || days < 0 {
+			utils.SendError(context, http.StatusBadRequest, "Invalid days")
+			return
+		}
+
+		limit, err := strconv.Atoi(context.DefaultQuery("limit", "10"))
+		if err != nil || limit <= 0 || limit > 100 {
+			utils.SendError(context, http.StatusBadRequest, "Invalid limit")
+			return
+		}
+
+		// Paging into older days, before is the nextBefore of the previous page
+		var before time.Time
+		if value := context.Query("before"); value != "" {
+			before, err = time.Parse("2006-01-02", value)
+			if err != nil {
+				utils.SendError(context, http.StatusBadRequest, "Invalid before date")
+				return
+			}
+		}
+
+		recentDays, err := repositories.GetRecentDays(db, userId, days, before, limit, utils.GetLanguage(context))
+		if err != nil {
+			utils.SendError(context, http.StatusInternalServerError, "Failed to fetch recent days")
+			return
+		}
+
+		context.JSON(http.StatusOK, repositories.RestRecentDays(recentDays))
+	})
+
+	route.GET("/timeline", func(context *gin.Context) {
+		userId, err := utils.GetUserID(context)
+		if err != nil {
+			utils.SendError(context, http.StatusBadRequest, "Invalid user ID")
+			return
+		}
+
+		granularity := context.DefaultQuery("granularity", utils.GranularityMonth)
+		if !utils.ValidGranularity(granularity) {
+			utils.SendError(context, http.StatusBadRequest, "Invalid granularity, expected year, month or day")
+			return
+		}
+
+		buckets, err := repositories.GetTimeline(db, userId, granularity)
+		if err != nil {
+			utils.SendError(context, http.StatusInternalServerError, "Failed to fetch timeline")
+			return
+		}
+
+		context.JSON(http.StatusOK, repositories.RestTimeline(granularity, buckets))
+	})
+
+	route.GET("/timeline/:bucket", func(context *gin.Context) {
+		userId, err := utils.GetUserID(context)
+		if err != nil {
+			utils.SendError(context, http.StatusBadRequest, "Invalid user ID")
+			return
+		}
+
+		bucket := context.Param("bucket")
+		if _, _, _, err := utils.ParseBucket(bucket); err != nil {
+			utils.SendError(context, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		cursor := context.Query("cursor")
+		if cursor != "" {
+			if _, _, err := utils.DecodeCursor(cursor); err != nil {
+				utils.SendError(context, http.StatusBadRequest, err.Error())
+				return
+			}
+		}
+
+		limit, err := strconv.Atoi(context.DefaultQuery("limit", "100"))
+		if err != nil || limit <= 0 || limit > 500 {
+			utils.SendError(context, http.StatusBadRequest, "Invalid limit")
+			return
+		}
+
+		page, err := repositories.GetBucketAssets(db, userId, bucket, cursor, limit)
+		if err != nil {
+			utils.SendError(context, http.StatusInternalServerError, "Failed to fetch timeline assets")
+			return
+		}
+
+		context.JSON(http.StatusOK, repositories.RestTimelineBucket(page))
 	})
 
 	route.GET("/pinned", func(context *gin.Context) {
-		context.JSON(http.StatusOK, repositories.RestPinnedCollections())
+		userId, err := utils.GetUserID(context)
+		if err != nil {
+			utils.SendError(context, http.StatusBadRequest, "Invalid user ID")
+			return
+		}
+
+		pinned, err := repositories.GetPinnedCollections(db, userId, utils.GetLanguage(context))
+		if err != nil {
+			utils.SendError(context, http.StatusInternalServerError, "Failed to fetch pinned collections")
+			return
+		}
+
+		context.JSON(http.StatusOK, repositories.RestPinnedCollections(pinned))
+	})
+
+	route.GET("/memories/today", func(context *gin.Context) {
+		userId, err := utils.GetUserID(context)
+		if err != nil {
+			utils.SendError(context, http.StatusBadRequest, "Invalid user ID")
+			return
+		}
+
+		// The client may send its own date, the server's day can differ from the user's
+		date := time.Now()
+		if value := context.Query("date"); value != "" {
+			date, err = time.Parse("2006-01-02", value)
+			if err != nil {
+				utils.SendError(context, http.StatusBadRequest, "Invalid date")
+				return
+			}
+		}
+
+		memories, err := memoryRepo.GetOnThisDay(userId, date, utils.GetLanguage(context))
+		if err != nil {
+			utils.SendError(context, http.StatusInternalServerError, "Failed to fetch memories")
+			return
+		}
+
+		context.JSON(http.StatusOK, repositories.RestMemories(date.Format("2006-01-02"), memories))
+	})
+
+	route.GET("/memories/:id", func(context *gin.Context) {
+		userId, err := utils.GetUserID(context)
+		if err != nil {
+			utils.SendError(context, http.StatusBadRequest, "Invalid user ID")
+			return
+		}
+
+		id, err := strconv.Atoi(context.Param("id"))
+		if err != nil {
+			utils.SendError(context, http.StatusBadRequest, "Invalid memory ID")
+			return
+		}
+
+		memory, assets, err := memoryRepo.GetMemory(userId, id)
+		if err != nil {
+			utils.SendError(context, http.StatusNotFound, "Memory not found")
+			return
+		}
+
+		context.JSON(http.StatusOK, gin.H{
+			"memory": memory,
+			"assets": assets,
+		})
 	})
 
 	route.GET("/trips", func(context *gin.Context) {
-		context.JSON(http.StatusOK, repositories.RestTrips())
+		userId, err := utils.GetUserID(context)
+		if err != nil {
+			utils.SendError(context, http.StatusBadRequest, "Invalid user ID")
+			return
+		}
+
+		trips, err := tripRepo.ListTrips(userId)
+		if err != nil {
+			utils.SendError(context, http.StatusInternalServerError, "Failed to fetch trips")
+			return
+		}
+
+		context.JSON(http.StatusOK, repositories.RestTrips(trips))
 	})
 
 	route.GET("/albums", func(context *gin.Context) {
-		context.JSON(http.StatusOK, repositories.RestAlbums())
+		userId, err := utils.GetUserID(context)
+		if err != nil {
+			utils.SendError(context, http.StatusBadRequest, "Invalid user ID")
+			return
+		}
+
+		albums, err := albumRepo.ListAlbums(userId)
+		if err != nil {
+			utils.SendError(context, http.StatusInternalServerError, "Failed to fetch albums")
+			return
+		}
+
+		context.JSON(http.StatusOK, repositories.RestAlbums(albums))
+	})
+
+	route.GET("/shared-albums", func(context *gin.Context) {
+		userId, err := utils.GetUserID(context)
+		if err != nil {
+			utils.SendError(context, http.StatusBadRequest, "Invalid user ID")
+			return
+		}
+
+		sharedAlbums, err := sharedAlbumRepo.ListSharedAlbums(userId)
+		if err != nil {
+			utils.SendError(context, http.StatusInternalServerError, "Failed to fetch shared albums")
+			return
+		}
+
+		context.JSON(http.StatusOK, repositories.RestSharedAlbums(sharedAlbums))
+	})
+
+	route.GET("/map", func(context *gin.Context) {
+		userId, err := utils.GetUserID(context)
+		if err != nil {
+			utils.SendError(context, http.StatusBadRequest, "Invalid user ID")
+			return
+		}
+
+		box, err := utils.ParseBoundingBox(context.Query("bbox"))
+		if err != nil {
+			utils.SendError(context, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		zoom, err := strconv.Atoi(context.DefaultQuery("zoom", "0"))
+		if err != nil {
+			utils.SendError(context, http.StatusBadRequest, "Invalid zoom")
+			return
+		}
+
+		clusters, err := repositories.GetMapClusters(db, userId, box, zoom)
+		if err != nil {
+			utils.SendError(context, http.StatusInternalServerError, "Failed to fetch map")
+			return
+		}
+
+		context.JSON(http.StatusOK, repositories.RestMap(zoom, clusters))
 	})
 
 	route.GET("/camera", func(context *gin.Context) {
@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/mahdi-cpp/PhotoKit/controllers"
+	"github.com/mahdi-cpp/PhotoKit/repositories"
+	"gorm.io/gorm"
+)
+
+func SetupShareLinkRoutes(rg *gin.RouterGroup, db *gorm.DB) {
+
+	shareLinkRepo := repositories.NewShareLinkRepository(db)
+	shareLinkController := controllers.NewShareLinkController(shareLinkRepo)
+
+	shareLinkRoutes := rg.Group("/share-links")
+	{
+		shareLinkRoutes.GET("/", shareLinkController.ListShareLinks)
+		shareLinkRoutes.POST("/", shareLinkController.CreateShareLink)
+		shareLinkRoutes.DELETE("/:id", shareLinkController.RevokeShareLink)
+	}
+}
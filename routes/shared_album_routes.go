@@ -0,0 +1,36 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/mahdi-cpp/PhotoKit/controllers"
+	"github.com/mahdi-cpp/PhotoKit/repositories"
+	"gorm.io/gorm"
+)
+
+func SetupSharedAlbumRoutes(rg *gin.RouterGroup, db *gorm.DB) {
+
+	sharedAlbumRepo := repositories.NewSharedAlbumRepository(db)
+	sharedAlbumController := controllers.NewSharedAlbumController(sharedAlbumRepo)
+
+	sharedAlbumRoutes := rg.Group("/shared-albums")
+	{
+		sharedAlbumRoutes.GET("/", sharedAlbumController.ListSharedAlbums)
+		sharedAlbumRoutes.POST("/", sharedAlbumController.CreateSharedAlbum)
+		sharedAlbumRoutes.GET("/invitations", sharedAlbumController.ListInvitations)
+		sharedAlbumRoutes.GET("/:id", sharedAlbumController.GetSharedAlbum)
+		sharedAlbumRoutes.PUT("/:id", sharedAlbumController.RenameSharedAlbum)
+		sharedAlbumRoutes.DELETE("/:id", sharedAlbumController.DeleteSharedAlbum)
+		sharedAlbumRoutes.POST("/:id/accept", sharedAlbumController.AcceptInvitation)
+		sharedAlbumRoutes.POST("/:id/members", sharedAlbumController.InviteMember)
+		sharedAlbumRoutes.PUT("/:id/members/:memberId", sharedAlbumController.UpdateMember)
+		sharedAlbumRoutes.DELETE("/:id/members/:memberId", sharedAlbumController.RemoveMember)
+		sharedAlbumRoutes.POST("/:id/assets", sharedAlbumController.AddAssets)
+		sharedAlbumRoutes.DELETE("/:id/assets", sharedAlbumController.RemoveAssets)
+		sharedAlbumRoutes.PUT("/:id/key", sharedAlbumController.SetKeyAsset)
+		sharedAlbumRoutes.GET("/:id/assets/:assetId/comments", sharedAlbumController.GetComments)
+		sharedAlbumRoutes.POST("/:id/assets/:assetId/comments", sharedAlbumController.AddComment)
+		sharedAlbumRoutes.POST("/:id/assets/:assetId/like", sharedAlbumController.LikeAsset)
+		sharedAlbumRoutes.DELETE("/:id/assets/:assetId/like", sharedAlbumController.UnlikeAsset)
+		sharedAlbumRoutes.DELETE("/:id/comments/:commentId", sharedAlbumController.DeleteComment)
+	}
+}
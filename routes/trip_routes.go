@@ -0,0 +1,24 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/mahdi-cpp/PhotoKit/controllers"
+	"github.com/mahdi-cpp/PhotoKit/repositories"
+	"gorm.io/gorm"
+)
+
+func SetupTripRoutes(rg *gin.RouterGroup, db *gorm.DB) {
+
+	tripRepo := repositories.NewTripRepository(db)
+	tripController := controllers.NewTripController(tripRepo)
+
+	tripRoutes := rg.Group("/trips")
+	{
+		tripRoutes.GET("/", tripController.ListTrips)
+		tripRoutes.POST("/detect", tripController.DetectTrips)
+		tripRoutes.POST("/merge", tripController.MergeTrips)
+		tripRoutes.GET("/:id", tripController.GetTrip)
+		tripRoutes.PUT("/:id", tripController.RenameTrip)
+		tripRoutes.POST("/:id/confirm", tripController.ConfirmTrip)
+	}
+}
@@ -7,15 +7,14 @@ import (
 	"gorm.io/gorm"
 )
 
-func SetupUserRoutes(router *gin.Engine, db *gorm.DB) {
+func SetupUserRoutes(rg *gin.RouterGroup, db *gorm.DB) {
 
 	userRepo := repositories.NewUserRepository(db)
 	userController := controllers.NewUserController(userRepo)
 
-	userRoutes := router.Group("/users")
+	userRoutes := rg.Group("/users")
 	{
 		userRoutes.GET("/", userController.ListUsers)
-		userRoutes.POST("/", userController.CreateUser)
 		userRoutes.GET("/:id", userController.GetUser)
 		userRoutes.PUT("/:id", userController.UpdateUser)
 		userRoutes.DELETE("/:id", userController.DeleteUser)
@@ -8,26 +8,13 @@ import (
 	"github.com/mahdi-cpp/PhotoKit/models"
 	"io"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 )
 
-const (
-	AssetsBaseDir = "/var/cloud/applications/PhotoKit/Assets/" // Directory to store JSON files
-)
-
-// Ensure directory exists
-func init() {
-	if _, err := os.Stat(AssetsBaseDir); os.IsNotExist(err) {
-		os.MkdirAll(AssetsBaseDir, 0755)
-	}
-}
-
-// SaveAsset saves a PHAsset to a JSON file
-func SaveAsset(asset *models.PHAsset, userId string) error {
-
-	// Create filename based on ID and creation date
-	filename := filepath.Join(AssetsBaseDir+userId, asset.URL+".json")
+// SaveAsset saves a PHAsset to its JSON sidecar
+func SaveAsset(asset *models.PHAsset) error {
 
 	// Convert to JSON
 	data, err := json.MarshalIndent(asset, "", "  ")
@@ -35,67 +22,47 @@ func SaveAsset(asset *models.PHAsset, userId string) error {
 		return err
 	}
 
-	// Write to file
-	return os.WriteFile(filename, data, 0644)
+	return PutBytes(Blobs, Keys.Sidecar(*asset), data, "application/json")
 }
 
-// LoadAsset loads a PHAsset from JSON file by ID
+// LoadAsset loads a PHAsset from its JSON sidecar by ID
 func LoadAsset(id int) (*models.PHAsset, error) {
 
-	files, err := os.ReadDir(AssetsBaseDir)
+	assets, err := LoadAllAssets()
 	if err != nil {
 		return nil, err
 	}
 
-	for _, file := range files {
-		if file.IsDir() {
-			continue
-		}
-
-		if file.IsDir() {
-			continue
-		}
-
-		// Read file
-		data, err := os.ReadFile(filepath.Join(AssetsBaseDir, file.Name()))
-		if err != nil {
-			continue // Skip files we can't read
-		}
-
-		var asset models.PHAsset
-		if err := json.Unmarshal(data, &asset); err != nil {
-			continue // Skip invalid JSON
-		}
-
-		if asset.ID == id {
-			return &asset, nil
+	for i := range assets {
+		if assets[i].ID == id {
+			return &assets[i], nil
 		}
 	}
 
 	return nil, os.ErrNotExist
 }
 
-// LoadAllAssets loads all PHAssets from JSON files
+// LoadAllAssets loads all PHAssets from their JSON sidecars
 func LoadAllAssets() ([]models.PHAsset, error) {
-	files, err := os.ReadDir(AssetsBaseDir)
+	blobs, err := Blobs.List("")
 	if err != nil {
 		return nil, err
 	}
 
 	var assets []models.PHAsset
-	for _, file := range files {
-		if file.IsDir() {
+	for _, blob := range blobs {
+		if path.Ext(blob.Key) != ".json" {
 			continue
 		}
 
-		data, err := os.ReadFile(filepath.Join(AssetsBaseDir, file.Name()))
+		data, err := ReadBlob(Blobs, blob.Key)
 		if err != nil {
-			continue
+			continue // Skip files we can't read
 		}
 
 		var asset models.PHAsset
 		if err := json.Unmarshal(data, &asset); err != nil {
-			continue
+			continue // Skip invalid JSON
 		}
 
 		assets = append(assets, asset)
@@ -104,34 +71,14 @@ func LoadAllAssets() ([]models.PHAsset, error) {
 	return assets, nil
 }
 
-// DeleteAsset removes a PHAsset JSON file
+// DeleteAsset removes the JSON sidecar of a PHAsset
 func DeleteAsset(id int) error {
-	files, err := os.ReadDir(AssetsBaseDir)
+	asset, err := LoadAsset(id)
 	if err != nil {
 		return err
 	}
 
-	for _, file := range files {
-		if file.IsDir() {
-			continue
-		}
-
-		data, err := os.ReadFile(filepath.Join(AssetsBaseDir, file.Name()))
-		if err != nil {
-			continue
-		}
-
-		var asset models.PHAsset
-		if err := json.Unmarshal(data, &asset); err != nil {
-			continue
-		}
-
-		if asset.ID == id {
-			return os.Remove(filepath.Join(AssetsBaseDir, file.Name()))
-		}
-	}
-
-	return os.ErrNotExist
+	return Blobs.Delete(Keys.Sidecar(*asset))
 }
 
 func FileInDirectoryExists(dir, filename string) bool {
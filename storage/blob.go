@@ -0,0 +1,124 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// BlobInfo describes a stored blob
+type BlobInfo struct {
+	Key     string
+	Size    int64
+	ModTime time.Time
+	ETag    string // strong and quoted
+}
+
+// BlobStore holds the files of the library under slash separated keys such as
+// "<userId>/<url>.<format>". Deleting a missing blob is not an error.
+type BlobStore interface {
+	Put(key string, r io.Reader, size int64, contentType string) error
+	Get(key string) (io.ReadCloser, BlobInfo, error)
+	Stat(key string) (BlobInfo, error)
+	Delete(key string) error
+	List(prefix string) ([]BlobInfo, error)
+	// Presign returns a URL clients can download the blob from without credentials until it expires
+	Presign(key string, expires time.Duration) (string, error)
+}
+
+var (
+	ErrBlobNotFound       = errors.New("blob not found")
+	ErrBlobKey            = errors.New("invalid blob key")
+	ErrPresignUnsupported = errors.New("blob store cannot presign URLs")
+)
+
+// Blobs holds the originals, sidecars, thumbnails and face crops, main replaces it from the
+// configuration
+var Blobs BlobStore = NewLocalStore("/var/cloud/applications/PhotoKit/Assets/")
+
+// checkKey rejects keys that would leave the root of a store
+func checkKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || path.Clean(key) != key || key == ".." || strings.HasPrefix(key, "../") {
+		return ErrBlobKey
+	}
+	return nil
+}
+
+// PutFile stores a local file as a blob
+func PutFile(store BlobStore, key string, filePath string, contentType string) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	return store.Put(key, file, info.Size(), contentType)
+}
+
+// PutBytes stores a blob held in memory
+func PutBytes(store BlobStore, key string, data []byte, contentType string) error {
+	return store.Put(key, bytes.NewReader(data), int64(len(data)), contentType)
+}
+
+// ReadBlob returns the content of a blob
+func ReadBlob(store BlobStore, key string) ([]byte, error) {
+	body, _, err := store.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	return io.ReadAll(body)
+}
+
+// Fetch returns a local file with the content of a blob for code that reads files by path, such
+// as the EXIF and video readers. Blobs of a local store are used in place, others are downloaded
+// to a temporary file that release removes.
+func Fetch(store BlobStore, key string) (filePath string, release func(), err error) {
+	if local, ok := store.(*LocalStore); ok {
+		filePath, err = local.Path(key)
+		if err != nil {
+			return "", nil, err
+		}
+		if _, err := os.Stat(filePath); err != nil {
+			if os.IsNotExist(err) {
+				return "", nil, ErrBlobNotFound
+			}
+			return "", nil, err
+		}
+		return filePath, func() {}, nil
+	}
+
+	body, _, err := store.Get(key)
+	if err != nil {
+		return "", nil, err
+	}
+	defer body.Close()
+
+	// Readers tell formats apart by the extension
+	tmp, err := os.CreateTemp("", "blob-*"+path.Ext(key))
+	if err != nil {
+		return "", nil, err
+	}
+	release = func() { os.Remove(tmp.Name()) }
+
+	_, err = io.Copy(tmp, body)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		release()
+		return "", nil, err
+	}
+
+	return tmp.Name(), release, nil
+}
@@ -0,0 +1,180 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// LocalStore keeps blobs as files under a root directory, a key is the path of its file
+type LocalStore struct {
+	Root string
+}
+
+// NewLocalStore returns a store of the files under root
+func NewLocalStore(root string) *LocalStore {
+	return &LocalStore{Root: filepath.Clean(root)}
+}
+
+// Path returns the file of a key
+func (s *LocalStore) Path(key string) (string, error) {
+	if err := checkKey(key); err != nil {
+		return "", err
+	}
+	return filepath.Join(s.Root, filepath.FromSlash(key)), nil
+}
+
+// Put writes the blob to a temporary file next to its file and renames it into place, readers
+// never see a partly written blob
+func (s *LocalStore) Put(key string, r io.Reader, size int64, contentType string) error {
+	filePath, err := s.Path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(filePath), ".put-*")
+	if err != nil {
+		return err
+	}
+
+	written, err := io.Copy(tmp, r)
+	if err == nil && size >= 0 && written != size {
+		err = fmt.Errorf("wrote %d of %d bytes", written, size)
+	}
+	if err == nil {
+		err = tmp.Chmod(0644)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), filePath)
+}
+
+// Get opens the file of a blob, the reader is an *os.File and can seek
+func (s *LocalStore) Get(key string) (io.ReadCloser, BlobInfo, error) {
+	filePath, err := s.Path(key)
+	if err != nil {
+		return nil, BlobInfo{}, err
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, BlobInfo{}, localError(err)
+	}
+
+	info, err := file.Stat()
+	if err != nil || info.IsDir() {
+		file.Close()
+		return nil, BlobInfo{}, ErrBlobNotFound
+	}
+
+	return file, localInfo(key, info), nil
+}
+
+func (s *LocalStore) Stat(key string) (BlobInfo, error) {
+	filePath, err := s.Path(key)
+	if err != nil {
+		return BlobInfo{}, err
+	}
+
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return BlobInfo{}, localError(err)
+	}
+	if info.IsDir() {
+		return BlobInfo{}, ErrBlobNotFound
+	}
+
+	return localInfo(key, info), nil
+}
+
+func (s *LocalStore) Delete(key string) error {
+	filePath, err := s.Path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// List returns the blobs whose keys start with prefix
+func (s *LocalStore) List(prefix string) ([]BlobInfo, error) {
+	// Walk only the directory the prefix points into
+	dir := s.Root
+	if cut := strings.LastIndex(prefix, "/"); cut >= 0 {
+		var err error
+		if dir, err = s.Path(prefix[:cut]); err != nil {
+			return nil, err
+		}
+	}
+
+	var blobs []BlobInfo
+	err := filepath.WalkDir(dir, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && filePath == dir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".put-") {
+			return nil
+		}
+
+		relative, err := filepath.Rel(s.Root, filePath)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(relative)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return nil // removed while walking
+		}
+		blobs = append(blobs, localInfo(key, info))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return blobs, nil
+}
+
+// Presign is not supported, the server serves the files of a local store itself
+func (s *LocalStore) Presign(key string, expires time.Duration) (string, error) {
+	return "", ErrPresignUnsupported
+}
+
+func localInfo(key string, info os.FileInfo) BlobInfo {
+	return BlobInfo{
+		Key:     key,
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+		ETag:    fmt.Sprintf(`"%x-%x"`, info.ModTime().UnixNano(), info.Size()),
+	}
+}
+
+func localError(err error) error {
+	if os.IsNotExist(err) {
+		return ErrBlobNotFound
+	}
+	return err
+}
@@ -0,0 +1,406 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// S3Config addresses a bucket of an S3 compatible service such as MinIO
+type S3Config struct {
+	Endpoint  string // "https://s3.eu-central-1.amazonaws.com" or "http://minio:9000"
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	// PathStyle addresses the bucket in the path instead of the host name, most self-hosted
+	// services need it
+	PathStyle bool
+}
+
+// S3Store keeps blobs as the objects of a bucket, requests are signed with AWS Signature
+// Version 4
+type S3Store struct {
+	config   S3Config
+	endpoint *url.URL
+	client   *http.Client
+}
+
+// NewS3Store returns a store of the objects of a bucket
+func NewS3Store(config S3Config) (*S3Store, error) {
+	endpoint, err := url.Parse(strings.TrimSuffix(config.Endpoint, "/"))
+	if err != nil || endpoint.Host == "" || (endpoint.Scheme != "http" && endpoint.Scheme != "https") {
+		return nil, fmt.Errorf("invalid S3 endpoint: %q", config.Endpoint)
+	}
+	if config.Bucket == "" {
+		return nil, errors.New("S3 bucket not set")
+	}
+	if config.Region == "" {
+		config.Region = "us-east-1"
+	}
+
+	return &S3Store{
+		config:   config,
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: 5 * time.Minute},
+	}, nil
+}
+
+func (s *S3Store) Put(key string, r io.Reader, size int64, contentType string) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+
+	// Objects are uploaded in one request, which needs the length up front
+	if size < 0 {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		return PutBytes(s, key, data, contentType)
+	}
+
+	// A body of length 0 would go out chunked
+	body := io.NopCloser(r)
+	if size == 0 {
+		body = http.NoBody
+	}
+
+	request, err := http.NewRequest(http.MethodPut, s.objectURL(key, nil).String(), body)
+	if err != nil {
+		return err
+	}
+	request.ContentLength = size
+	if contentType != "" {
+		request.Header.Set("Content-Type", contentType)
+	}
+
+	response, err := s.do(request)
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+	return nil
+}
+
+func (s *S3Store) Get(key string) (io.ReadCloser, BlobInfo, error) {
+	if err := checkKey(key); err != nil {
+		return nil, BlobInfo{}, err
+	}
+
+	request, err := http.NewRequest(http.MethodGet, s.objectURL(key, nil).String(), nil)
+	if err != nil {
+		return nil, BlobInfo{}, err
+	}
+
+	response, err := s.do(request)
+	if err != nil {
+		return nil, BlobInfo{}, err
+	}
+
+	return response.Body, s3Info(key, response), nil
+}
+
+func (s *S3Store) Stat(key string) (BlobInfo, error) {
+	if err := checkKey(key); err != nil {
+		return BlobInfo{}, err
+	}
+
+	request, err := http.NewRequest(http.MethodHead, s.objectURL(key, nil).String(), nil)
+	if err != nil {
+		return BlobInfo{}, err
+	}
+
+	response, err := s.do(request)
+	if err != nil {
+		return BlobInfo{}, err
+	}
+	response.Body.Close()
+
+	return s3Info(key, response), nil
+}
+
+func (s *S3Store) Delete(key string) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+
+	request, err := http.NewRequest(http.MethodDelete, s.objectURL(key, nil).String(), nil)
+	if err != nil {
+		return err
+	}
+
+	response, err := s.do(request)
+	if errors.Is(err, ErrBlobNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+	return nil
+}
+
+// listResult is the response of ListObjectsV2
+type listResult struct {
+	Contents []struct {
+		Key          string
+		LastModified time.Time
+		ETag         string
+		Size         int64
+	}
+	IsTruncated           bool
+	NextContinuationToken string
+}
+
+// List returns the objects whose keys start with prefix, following the pages of the listing
+func (s *S3Store) List(prefix string) ([]BlobInfo, error) {
+	var blobs []BlobInfo
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		request, err := http.NewRequest(http.MethodGet, s.bucketURL(query).String(), nil)
+		if err != nil {
+			return nil, err
+		}
+
+		response, err := s.do(request)
+		if err != nil {
+			return nil, err
+		}
+
+		var result listResult
+		err = xml.NewDecoder(response.Body).Decode(&result)
+		response.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("decode object listing: %w", err)
+		}
+
+		for _, object := range result.Contents {
+			blobs = append(blobs, BlobInfo{
+				Key:     object.Key,
+				Size:    object.Size,
+				ModTime: object.LastModified,
+				ETag:    object.ETag,
+			})
+		}
+
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return blobs, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// Presign returns a GET URL of the object signed in its query, valid for up to seven days
+func (s *S3Store) Presign(key string, expires time.Duration) (string, error) {
+	if err := checkKey(key); err != nil {
+		return "", err
+	}
+	if expires <= 0 || expires > 7*24*time.Hour {
+		return "", errors.New("presigned URLs expire within seven days")
+	}
+
+	return s.presign(key, expires, time.Now().UTC()), nil
+}
+
+// presign signs the GET URL of an object at a given time
+func (s *S3Store) presign(key string, expires time.Duration, now time.Time) string {
+	query := url.Values{
+		"X-Amz-Algorithm":     {"AWS4-HMAC-SHA256"},
+		"X-Amz-Credential":    {s.config.AccessKey + "/" + s.scope(now)},
+		"X-Amz-Date":          {now.Format(amzDateFormat)},
+		"X-Amz-Expires":       {strconv.Itoa(int(expires.Seconds()))},
+		"X-Amz-SignedHeaders": {"host"},
+	}
+	target := s.objectURL(key, query)
+
+	canonical := strings.Join([]string{
+		http.MethodGet,
+		target.EscapedPath(),
+		canonicalQuery(query),
+		"host:" + target.Host + "\n",
+		"host",
+		unsignedPayload,
+	}, "\n")
+
+	query.Set("X-Amz-Signature", s.signature(now, canonical))
+	target.RawQuery = canonicalQuery(query)
+	return target.String()
+}
+
+const (
+	amzDateFormat   = "20060102T150405Z"
+	unsignedPayload = "UNSIGNED-PAYLOAD"
+)
+
+// do signs and sends a request, answers other than 2xx become errors
+func (s *S3Store) do(request *http.Request) (*http.Response, error) {
+	s.sign(request, time.Now().UTC())
+
+	response, err := s.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode >= 200 && response.StatusCode < 300 {
+		return response, nil
+	}
+	defer response.Body.Close()
+
+	// The error document names the cause, HEAD answers have no body. A missing bucket is not a
+	// missing blob.
+	var failure struct {
+		Code    string
+		Message string
+	}
+	xml.NewDecoder(io.LimitReader(response.Body, 64<<10)).Decode(&failure)
+	if failure.Code == "NoSuchKey" || (response.StatusCode == http.StatusNotFound && failure.Code == "") {
+		return nil, ErrBlobNotFound
+	}
+	return nil, fmt.Errorf("S3 %s %s: %s %s %s", request.Method, request.URL.Path, response.Status, failure.Code, failure.Message)
+}
+
+// sign adds the Authorization header. Payloads are not hashed, TLS protects them on the way.
+func (s *S3Store) sign(request *http.Request, now time.Time) {
+	request.Header.Set("X-Amz-Date", now.Format(amzDateFormat))
+	request.Header.Set("X-Amz-Content-Sha256", unsignedPayload)
+
+	headers := map[string]string{
+		"host":                 request.URL.Host,
+		"x-amz-content-sha256": unsignedPayload,
+		"x-amz-date":           now.Format(amzDateFormat),
+	}
+	if contentType := request.Header.Get("Content-Type"); contentType != "" {
+		headers["content-type"] = contentType
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(headers[name]) + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonical := strings.Join([]string{
+		request.Method,
+		request.URL.EscapedPath(),
+		canonicalQuery(request.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		unsignedPayload,
+	}, "\n")
+
+	request.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.config.AccessKey, s.scope(now), signedHeaders, s.signature(now, canonical)))
+}
+
+// scope is the date, region and service the signing key is derived for
+func (s *S3Store) scope(now time.Time) string {
+	return now.Format("20060102") + "/" + s.config.Region + "/s3/aws4_request"
+}
+
+func (s *S3Store) signature(now time.Time, canonicalRequest string) string {
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + now.Format(amzDateFormat) + "\n" + s.scope(now) + "\n" + hex.EncodeToString(hash[:])
+
+	key := hmacSHA256([]byte("AWS4"+s.config.SecretKey), now.Format("20060102"))
+	key = hmacSHA256(key, s.config.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// bucketURL addresses the bucket, in the path or as a subdomain of the endpoint
+func (s *S3Store) bucketURL(query url.Values) *url.URL {
+	target := *s.endpoint
+	if s.config.PathStyle {
+		target.Path = s.endpoint.Path + "/" + s.config.Bucket + "/"
+	} else {
+		target.Host = s.config.Bucket + "." + s.endpoint.Host
+		target.Path = s.endpoint.Path + "/"
+	}
+	target.RawPath = ""
+	target.RawQuery = canonicalQuery(query)
+	return &target
+}
+
+// objectURL addresses an object, its key escaped the way the signature expects
+func (s *S3Store) objectURL(key string, query url.Values) *url.URL {
+	target := s.bucketURL(query)
+	target.Path += key
+	target.RawPath = escapePath(target.Path)
+	return target
+}
+
+// canonicalQuery encodes a query sorted by name with the escaping of the signature
+func canonicalQuery(query url.Values) string {
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var pairs []string
+	for _, name := range names {
+		values := append([]string(nil), query[name]...)
+		sort.Strings(values)
+		for _, value := range values {
+			pairs = append(pairs, escape(name)+"="+escape(value))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+func escapePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = escape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// escape percent-encodes everything but the unreserved characters of RFC 3986
+func escape(value string) string {
+	var escaped strings.Builder
+	for _, b := range []byte(value) {
+		if 'A' <= b && b <= 'Z' || 'a' <= b && b <= 'z' || '0' <= b && b <= '9' || b == '-' || b == '_' || b == '.' || b == '~' {
+			escaped.WriteByte(b)
+		} else {
+			fmt.Fprintf(&escaped, "%%%02X", b)
+		}
+	}
+	return escaped.String()
+}
+
+func s3Info(key string, response *http.Response) BlobInfo {
+	info := BlobInfo{Key: key, Size: response.ContentLength, ETag: response.Header.Get("ETag")}
+	if modTime, err := http.ParseTime(response.Header.Get("Last-Modified")); err == nil {
+		info.ModTime = modTime
+	}
+	return info
+}
@@ -0,0 +1,238 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testBlobStore runs the behavior every BlobStore shares
+func testBlobStore(t *testing.T, store BlobStore) {
+	t.Run("PutGet", func(t *testing.T) {
+		data := []byte("original bytes")
+		if err := PutBytes(store, "1/photo.jpg", data, "image/jpeg"); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+
+		body, info, err := store.Get("1/photo.jpg")
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		got, err := io.ReadAll(body)
+		body.Close()
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Errorf("Get = %q, want %q", got, data)
+		}
+		if info.Key != "1/photo.jpg" || info.Size != int64(len(data)) {
+			t.Errorf("Get info = %+v", info)
+		}
+	})
+
+	t.Run("Stat", func(t *testing.T) {
+		before := time.Now().Add(-time.Minute)
+		if err := PutBytes(store, "1/stat.jpg", []byte("12345"), "image/jpeg"); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+
+		info, err := store.Stat("1/stat.jpg")
+		if err != nil {
+			t.Fatalf("Stat: %v", err)
+		}
+		if info.Key != "1/stat.jpg" || info.Size != 5 {
+			t.Errorf("Stat = %+v", info)
+		}
+		if info.ModTime.Before(before) {
+			t.Errorf("ModTime = %v, want after %v", info.ModTime, before)
+		}
+		if len(info.ETag) < 3 || !strings.HasPrefix(info.ETag, `"`) || !strings.HasSuffix(info.ETag, `"`) {
+			t.Errorf("ETag = %q, want a quoted strong ETag", info.ETag)
+		}
+
+		// A new content gets a new ETag
+		if err := PutBytes(store, "1/stat.jpg", []byte("other content"), "image/jpeg"); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+		changed, err := store.Stat("1/stat.jpg")
+		if err != nil {
+			t.Fatalf("Stat: %v", err)
+		}
+		if changed.Size != 13 || changed.ETag == info.ETag {
+			t.Errorf("Stat after overwrite = %+v, was %+v", changed, info)
+		}
+	})
+
+	t.Run("PutSizes", func(t *testing.T) {
+		if err := store.Put("1/empty", bytes.NewReader(nil), 0, ""); err != nil {
+			t.Fatalf("Put empty: %v", err)
+		}
+		if data, err := ReadBlob(store, "1/empty"); err != nil || len(data) != 0 {
+			t.Errorf("ReadBlob empty = %q, %v", data, err)
+		}
+
+		// A reader of unknown length
+		if err := store.Put("1/unknown", io.MultiReader(strings.NewReader("abc"), strings.NewReader("def")), -1, ""); err != nil {
+			t.Fatalf("Put unknown size: %v", err)
+		}
+		if data, err := ReadBlob(store, "1/unknown"); err != nil || string(data) != "abcdef" {
+			t.Errorf("ReadBlob unknown size = %q, %v", data, err)
+		}
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		if _, _, err := store.Get("1/missing.jpg"); !errors.Is(err, ErrBlobNotFound) {
+			t.Errorf("Get missing = %v, want ErrBlobNotFound", err)
+		}
+		if _, err := store.Stat("1/missing.jpg"); !errors.Is(err, ErrBlobNotFound) {
+			t.Errorf("Stat missing = %v, want ErrBlobNotFound", err)
+		}
+		if _, _, err := Fetch(store, "1/missing.jpg"); !errors.Is(err, ErrBlobNotFound) {
+			t.Errorf("Fetch missing = %v, want ErrBlobNotFound", err)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		if err := PutBytes(store, "1/delete.jpg", []byte("x"), ""); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+		if err := store.Delete("1/delete.jpg"); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if _, err := store.Stat("1/delete.jpg"); !errors.Is(err, ErrBlobNotFound) {
+			t.Errorf("Stat deleted = %v, want ErrBlobNotFound", err)
+		}
+		if err := store.Delete("1/delete.jpg"); err != nil {
+			t.Errorf("Delete missing = %v, want nil", err)
+		}
+	})
+
+	t.Run("List", func(t *testing.T) {
+		keys := []string{"2/a.jpg", "2/b.jpg", "2/thumbnails/a_270.jpg", "2/thumbnails/b_270.jpg", "2/c.mov", "20/a.jpg"}
+		for _, key := range keys {
+			if err := PutBytes(store, key, []byte(key), ""); err != nil {
+				t.Fatalf("Put %s: %v", key, err)
+			}
+		}
+
+		tests := []struct {
+			prefix string
+			want   []string
+		}{
+			{"2/", []string{"2/a.jpg", "2/b.jpg", "2/c.mov", "2/thumbnails/a_270.jpg", "2/thumbnails/b_270.jpg"}},
+			{"2/thumbnails/", []string{"2/thumbnails/a_270.jpg", "2/thumbnails/b_270.jpg"}},
+			{"2/thumbnails/a", []string{"2/thumbnails/a_270.jpg"}},
+			{"2/b", []string{"2/b.jpg"}},
+			{"3/", nil},
+			{"2/missing/", nil},
+		}
+		for _, tt := range tests {
+			blobs, err := store.List(tt.prefix)
+			if err != nil {
+				t.Errorf("List(%q): %v", tt.prefix, err)
+				continue
+			}
+
+			var got []string
+			for _, blob := range blobs {
+				got = append(got, blob.Key)
+				if blob.Size != int64(len(blob.Key)) {
+					t.Errorf("List(%q) size of %s = %d", tt.prefix, blob.Key, blob.Size)
+				}
+			}
+			sort.Strings(got)
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("List(%q) = %v, want %v", tt.prefix, got, tt.want)
+			}
+		}
+	})
+
+	t.Run("Fetch", func(t *testing.T) {
+		if err := PutBytes(store, "1/fetch.heic", []byte("heic"), ""); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+
+		filePath, release, err := Fetch(store, "1/fetch.heic")
+		if err != nil {
+			t.Fatalf("Fetch: %v", err)
+		}
+		if !strings.HasSuffix(filePath, ".heic") {
+			t.Errorf("Fetch path %q lost the extension", filePath)
+		}
+		if data, err := os.ReadFile(filePath); err != nil || string(data) != "heic" {
+			t.Errorf("fetched file = %q, %v", data, err)
+		}
+		release()
+	})
+
+	t.Run("InvalidKeys", func(t *testing.T) {
+		for _, key := range []string{"", "/1/a.jpg", "..", "../a.jpg", "1/../../a.jpg", "1//a.jpg", "1/./a.jpg", "1/"} {
+			if err := PutBytes(store, key, []byte("x"), ""); !errors.Is(err, ErrBlobKey) {
+				t.Errorf("Put(%q) = %v, want ErrBlobKey", key, err)
+			}
+			if _, _, err := store.Get(key); !errors.Is(err, ErrBlobKey) {
+				t.Errorf("Get(%q) = %v, want ErrBlobKey", key, err)
+			}
+			if _, err := store.Stat(key); !errors.Is(err, ErrBlobKey) {
+				t.Errorf("Stat(%q) = %v, want ErrBlobKey", key, err)
+			}
+			if err := store.Delete(key); !errors.Is(err, ErrBlobKey) {
+				t.Errorf("Delete(%q) = %v, want ErrBlobKey", key, err)
+			}
+		}
+	})
+}
+
+func TestLocalStore(t *testing.T) {
+	store := NewLocalStore(t.TempDir())
+	testBlobStore(t, store)
+
+	t.Run("Presign", func(t *testing.T) {
+		if _, err := store.Presign("1/photo.jpg", time.Minute); !errors.Is(err, ErrPresignUnsupported) {
+			t.Errorf("Presign = %v, want ErrPresignUnsupported", err)
+		}
+	})
+
+	t.Run("NoPartialFiles", func(t *testing.T) {
+		// A failed write leaves neither the blob nor its temporary file
+		err := store.Put("1/partial.jpg", strings.NewReader("short"), 100, "")
+		if err == nil {
+			t.Fatal("Put of a short reader succeeded")
+		}
+		if _, err := store.Stat("1/partial.jpg"); !errors.Is(err, ErrBlobNotFound) {
+			t.Errorf("Stat = %v, want ErrBlobNotFound", err)
+		}
+		blobs, err := store.List("1/")
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		for _, blob := range blobs {
+			if strings.Contains(blob.Key, "partial") || strings.Contains(blob.Key, ".put-") {
+				t.Errorf("List has %s", blob.Key)
+			}
+		}
+	})
+
+	t.Run("FetchInPlace", func(t *testing.T) {
+		if err := PutBytes(store, "1/in-place.jpg", []byte("x"), ""); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+		filePath, release, err := Fetch(store, "1/in-place.jpg")
+		if err != nil {
+			t.Fatalf("Fetch: %v", err)
+		}
+		release()
+		if want, _ := store.Path("1/in-place.jpg"); filePath != want {
+			t.Errorf("Fetch = %q, want the blob's own file %q", filePath, want)
+		}
+		if _, err := os.Stat(filePath); err != nil {
+			t.Errorf("release removed the blob: %v", err)
+		}
+	})
+}
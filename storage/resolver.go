@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"github.com/mahdi-cpp/PhotoKit/models"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+// Resolver maps assets to the keys of their blobs in Blobs, where every user has a prefix with the
+// originals, their JSON sidecars and the thumbnails and faces:
+//
+//	<userId>/<url>.<format>
+//	<userId>/thumbnail/<url>_<size>.jpg
+//	<userId>/faces/<regionId>.jpg
+//
+// Files of folders from before the asset library are found by name through an index of the
+// folders of every user.
+type Resolver struct {
+	mu     sync.RWMutex
+	legacy map[int]map[string]string // user ID → file name → path in their legacy folders
+}
+
+// Keys resolves the blobs of the server
+var Keys = NewResolver()
+
+// NewResolver returns a resolver with an empty legacy index
+func NewResolver() *Resolver {
+	return &Resolver{legacy: make(map[int]map[string]string)}
+}
+
+// UserDir returns the prefix of the blobs of a user
+func (r *Resolver) UserDir(userId int) string {
+	return strconv.Itoa(userId) + "/"
+}
+
+// Original returns the key of the original file of an asset
+func (r *Resolver) Original(asset models.PHAsset) string {
+	return r.UserDir(asset.UserId) + asset.URL + "." + asset.Format
+}
+
+// Sidecar returns the key of the JSON sidecar of an asset
+func (r *Resolver) Sidecar(asset models.PHAsset) string {
+	return r.UserDir(asset.UserId) + asset.URL + ".json"
+}
+
+// ThumbnailDir returns the prefix of the thumbnails made at ingest
+func (r *Resolver) ThumbnailDir(userId int) string {
+	return r.UserDir(userId) + "thumbnail/"
+}
+
+// Thumbnail returns the key of the thumbnail of an asset made at ingest in one of the fixed widths
+func (r *Resolver) Thumbnail(asset models.PHAsset, size int) string {
+	return r.ThumbnailDir(asset.UserId) + asset.URL + "_" + strconv.Itoa(size) + ".jpg"
+}
+
+// FaceDir returns the prefix of the key face crops of a user
+func (r *Resolver) FaceDir(userId int) string {
+	return r.UserDir(userId) + "faces/"
+}
+
+// Face returns the key of the key face crop made from a region
+func (r *Resolver) Face(userId int, regionId int) string {
+	return r.FaceDir(userId) + strconv.Itoa(regionId) + ".jpg"
+}
+
+// IndexLegacy indexes the files directly in the legacy folders of every user by name, replacing
+// the index. When folders of a user hold files of the same name the first folder wins. It returns
+// the number of files.
+func (r *Resolver) IndexLegacy(folders map[int][]string) int {
+	index := make(map[int]map[string]string)
+	files, shadowed := 0, 0
+	for userId, userFolders := range folders {
+		names := make(map[string]string)
+		for _, folder := range userFolders {
+			entries, err := os.ReadDir(folder)
+			if err != nil {
+				log.Printf("Failed to index legacy folder %s: %v", folder, err)
+				continue
+			}
+
+			for _, entry := range entries {
+				if entry.IsDir() {
+					continue
+				}
+				if _, ok := names[entry.Name()]; ok {
+					shadowed++
+					continue
+				}
+				names[entry.Name()] = filepath.Join(folder, entry.Name())
+			}
+		}
+		index[userId] = names
+		files += len(names)
+	}
+
+	if shadowed > 0 {
+		log.Printf("%d legacy files are shadowed by files of the same name in earlier folders", shadowed)
+	}
+
+	r.mu.Lock()
+	r.legacy = index
+	r.mu.Unlock()
+
+	return files
+}
+
+// Legacy returns the path of a file of the legacy folders of a user
+func (r *Resolver) Legacy(userId int, filename string) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	path, ok := r.legacy[userId][filepath.Base(filename)]
+	return path, ok
+}
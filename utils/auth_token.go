@@ -0,0 +1,107 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AccessTokenTTL is how long an access token is accepted, clients refresh it before
+var AccessTokenTTL = 15 * time.Minute
+
+var ErrInvalidToken = errors.New("invalid or expired token")
+
+// authSecret signs the access tokens, SetAuthSecret replaces the random one of a fresh process
+var authSecret = randomSecret()
+
+type accessClaims struct {
+	Subject   string `json:"sub"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+	Type      string `json:"typ"`
+}
+
+var accessTokenHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// SetAuthSecret sets the key of the access tokens, tokens signed with another key stop working
+func SetAuthSecret(secret string) {
+	if secret == "" {
+		log.Println("Warning: AUTH_SECRET is not set, access tokens will not survive a restart")
+		return
+	}
+	authSecret = []byte(secret)
+}
+
+func randomSecret() []byte {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		log.Fatal(err)
+	}
+	return secret
+}
+
+// IssueAccessToken signs an HS256 JWT for the user that expires after AccessTokenTTL
+func IssueAccessToken(userId int) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(AccessTokenTTL)
+
+	claims, err := json.Marshal(accessClaims{
+		Subject:   strconv.Itoa(userId),
+		IssuedAt:  now.Unix(),
+		ExpiresAt: expiresAt.Unix(),
+		Type:      "access",
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	unsigned := accessTokenHeader + "." + base64.RawURLEncoding.EncodeToString(claims)
+	return unsigned + "." + signToken(unsigned), expiresAt, nil
+}
+
+// ParseAccessToken checks the signature and expiry of an access token and returns its user
+func ParseAccessToken(token string) (int, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != accessTokenHeader {
+		return 0, ErrInvalidToken
+	}
+
+	signature := signToken(parts[0] + "." + parts[1])
+	if subtle.ConstantTimeCompare([]byte(signature), []byte(parts[2])) != 1 {
+		return 0, ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return 0, ErrInvalidToken
+	}
+
+	var claims accessClaims
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Type != "access" {
+		return 0, ErrInvalidToken
+	}
+	if time.Now().Unix() >= claims.ExpiresAt {
+		return 0, ErrInvalidToken
+	}
+
+	userId, err := strconv.Atoi(claims.Subject)
+	if err != nil || userId <= 0 {
+		return 0, ErrInvalidToken
+	}
+
+	return userId, nil
+}
+
+func signToken(unsigned string) string {
+	mac := hmac.New(sha256.New, authSecret)
+	mac.Write([]byte(unsigned))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -49,20 +49,57 @@ func PhotoHasExifData(filePath string) bool {
 
 func ReadExifData(filePath string) (bool, string) {
 
+	entries, err := ReadExifEntries(filePath)
+	if err != nil {
+		fmt.Println("ReadExifEntries: ", err)
+		if len(entries) == 0 {
+			return false, ""
+		}
+	}
+
+	if printAsJsonArg == true {
+		data, err := json.MarshalIndent(entries, "", "    ")
+		//log.PanicIf(err)
+		fmt.Println("Panic 8: ", err)
+		fmt.Println(string(data))
+	} else {
+		for _, entry := range entries {
+
+			//fmt.Printf("IFD-PATH=[%s] ID=(0x%04x) NAME=[%s] COUNT=(%d) TYPE=[%s] VALUE=[%s]\n",
+			//	entry.IfdPath,
+			//	entry.TagId,
+			//	entry.TagName,
+			//	entry.UnitCount,
+			//	entry.TagTypeName,
+			//	entry.ValueString)
+
+			if strings.Contains(entry.TagName, "Orientation") {
+				//fmt.Println("description value: " + entry.ValueString)
+				return true, entry.ValueString
+			}
+		}
+	}
+
+	return false, ""
+}
+
+// ReadExifEntries returns every tag of every IFD in the EXIF data of a file
+func ReadExifEntries(filePath string) ([]IfdEntry, error) {
+
 	f, err := os.Open(filePath)
 	if err != nil {
-		fmt.Println("Open file: ", err)
-		return false, ""
+		return nil, err
 	}
+	defer f.Close()
+
 	data, err := ioutil.ReadAll(f)
 	if err != nil {
-		return false, ""
+		return nil, err
 	}
 
 	rawExif, err := exif.SearchAndExtractExif(data)
 	if err != nil {
-		fmt.Println("SearchAndExtractExif: ", err)
-		return false, ""
+		return nil, err
 	}
 
 	// Run the parse.
@@ -82,20 +119,8 @@ func ReadExifData(filePath string) (bool, string) {
 
 		it, err := ti.Get(ifdPath, tagId)
 		if err != nil {
-			if err == nil {
-				fmt.Println("Panic 5: ", exif.ErrTagNotFound)
-				fmt.Printf("WARNING: Unknown tag: [%s] (%04x)\n", ifdPath, tagId)
-				return nil
-			} else {
-				fmt.Println("Panic 5: ", err)
-			}
-
-			//if log.Is(err, exif.ErrTagNotFound) {
-			//	fmt.Printf("WARNING: Unknown tag: [%s] (%04x)\n", ifdPath, tagId)
-			//	return nil
-			//} else {
-			//	//log.Panic(err)
-			//}
+			// Unknown and vendor tags are skipped
+			return nil
 		}
 
 		valueString := ""
@@ -139,35 +164,11 @@ func ReadExifData(filePath string) (bool, string) {
 	}
 
 	_, err = exif.Visit(exif.IfdStandard, im, ti, rawExif, visitor)
-	//log.PanicIf(err)
 	if err != nil {
-		fmt.Println("Panic 7: ", err)
+		return entries, err
 	}
 
-	if printAsJsonArg == true {
-		data, err := json.MarshalIndent(entries, "", "    ")
-		//log.PanicIf(err)
-		fmt.Println("Panic 8: ", err)
-		fmt.Println(string(data))
-	} else {
-		for _, entry := range entries {
-
-			//fmt.Printf("IFD-PATH=[%s] ID=(0x%04x) NAME=[%s] COUNT=(%d) TYPE=[%s] VALUE=[%s]\n",
-			//	entry.IfdPath,
-			//	entry.TagId,
-			//	entry.TagName,
-			//	entry.UnitCount,
-			//	entry.TagTypeName,
-			//	entry.ValueString)
-
-			if strings.Contains(entry.TagName, "Orientation") {
-				//fmt.Println("description value: " + entry.ValueString)
-				return true, entry.ValueString
-			}
-		}
-	}
-
-	return false, ""
+	return entries, nil
 }
 
 func getSubstringAfterLastDot(s string) string {
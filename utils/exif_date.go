@@ -0,0 +1,137 @@
+package utils
+
+import (
+	"github.com/dsoprea/go-exif/v3"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const exifDateLayout = "2006:01:02 15:04:05"
+
+// filenameDatePattern matches dates such as IMG_20141015_185832, 2014-10-15 18.58.32 or VID-20141015-WA0001
+var filenameDatePattern = regexp.MustCompile(`((?:19|20)\d{2})[-_.]?(0[1-9]|1[0-2])[-_.]?(0[1-9]|[12]\d|3[01])(?:[-_ T.]?([01]\d|2[0-3])[-_.:]?([0-5]\d)[-_.:]?([0-5]\d))?`)
+
+// GetCaptureDate returns the date a photo was taken from its EXIF DateTimeOriginal,
+// with the OffsetTimeOriginal time zone and SubSecTimeOriginal fraction when present.
+// DateTimeDigitized and DateTime are used when DateTimeOriginal is missing.
+func GetCaptureDate(filepath string) (time.Time, bool) {
+
+	data, err := ioutil.ReadFile(filepath)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	rawExif, err := exif.SearchAndExtractExif(data)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	tags, _, err := exif.GetFlatExifData(rawExif, nil)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	findTag := func(name string) string {
+		for _, tag := range tags {
+			if tag.TagName == name {
+				if value, ok := tag.Value.(string); ok {
+					return sanitizeString(value)
+				}
+				return ""
+			}
+		}
+		return ""
+	}
+
+	candidates := [][3]string{
+		{"DateTimeOriginal", "OffsetTimeOriginal", "SubSecTimeOriginal"},
+		{"DateTimeDigitized", "OffsetTimeDigitized", "SubSecTimeDigitized"},
+		{"DateTime", "OffsetTime", "SubSecTime"},
+	}
+
+	for _, names := range candidates {
+		if date, ok := parseExifDate(findTag(names[0]), findTag(names[1]), findTag(names[2])); ok {
+			return date, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+func parseExifDate(value, offset, subSec string) (time.Time, bool) {
+	if value == "" || strings.HasPrefix(value, "0000") {
+		return time.Time{}, false
+	}
+
+	location := time.Local
+	if offset != "" {
+		if t, err := time.Parse("-07:00", offset); err == nil {
+			_, seconds := t.Zone()
+			location = time.FixedZone(offset, seconds)
+		}
+	}
+
+	date, err := time.ParseInLocation(exifDateLayout, value, location)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	if subSec != "" {
+		if fraction, err := strconv.ParseFloat("0."+subSec, 64); err == nil {
+			date = date.Add(time.Duration(fraction * float64(time.Second)))
+		}
+	}
+
+	return date, true
+}
+
+// DateFromFilename returns the date written in a file name by cameras and messengers
+func DateFromFilename(named string) (time.Time, bool) {
+	match := filenameDatePattern.FindStringSubmatch(named)
+	if match == nil {
+		return time.Time{}, false
+	}
+
+	parts := make([]int, 6)
+	for i, part := range match[1:] {
+		if part != "" {
+			parts[i], _ = strconv.Atoi(part)
+		}
+	}
+
+	date := time.Date(parts[0], time.Month(parts[1]), parts[2], parts[3], parts[4], parts[5], 0, time.Local)
+
+	// Reject dates that were normalized, like February 30, and dates in the future
+	if date.Day() != parts[2] || date.After(time.Now()) {
+		return time.Time{}, false
+	}
+
+	return date, true
+}
+
+// ResolveCreationDate finds when an asset was created: from its EXIF, a date in its
+// file name, or the modification time of the file at mtimePath. The second result
+// is the source of the date, "exif", "filename", "mtime" or "" when none was found.
+func ResolveCreationDate(exifPath string, named string, mtimePath string) (time.Time, string) {
+	if exifPath != "" {
+		if date, ok := GetCaptureDate(exifPath); ok {
+			return date, "exif"
+		}
+	}
+
+	if date, ok := DateFromFilename(named); ok {
+		return date, "filename"
+	}
+
+	if mtimePath != "" {
+		if info, err := os.Stat(mtimePath); err == nil {
+			return info.ModTime(), "mtime"
+		}
+	}
+
+	return time.Time{}, ""
+}
@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"errors"
+	"github.com/dsoprea/go-exif/v3"
+	exifcommon "github.com/dsoprea/go-exif/v3/common"
+	"io/ioutil"
+)
+
+// GpsLocation is the position stored in the GPS IFD of an image
+type GpsLocation struct {
+	Latitude  float64
+	Longitude float64
+	Altitude  float64
+}
+
+// GetGpsLocation returns the latitude, longitude and altitude from the GPS IFD of an image file
+func GetGpsLocation(filepath string) (*GpsLocation, error) {
+
+	data, err := ioutil.ReadFile(filepath)
+	if err != nil {
+		return nil, err
+	}
+
+	rawExif, err := exif.SearchAndExtractExif(data)
+	if err != nil {
+		return nil, err
+	}
+
+	im, err := exifcommon.NewIfdMappingWithStandard()
+	if err != nil {
+		return nil, err
+	}
+
+	_, index, err := exif.Collect(im, exif.NewTagIndex(), rawExif)
+	if err != nil {
+		return nil, err
+	}
+
+	gpsIfd, err := index.RootIfd.ChildWithIfdPath(exifcommon.IfdGpsInfoStandardIfdIdentity)
+	if err != nil {
+		return nil, err
+	}
+
+	gpsInfo, err := gpsIfd.GpsInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	location := &GpsLocation{
+		Latitude:  gpsInfo.Latitude.Decimal(),
+		Longitude: gpsInfo.Longitude.Decimal(),
+		Altitude:  float64(gpsInfo.Altitude),
+	}
+
+	// Cameras without a fix write zeros
+	if location.Latitude == 0 && location.Longitude == 0 {
+		return nil, errors.New("no GPS fix")
+	}
+	if location.Latitude < -90 || location.Latitude > 90 || location.Longitude < -180 || location.Longitude > 180 {
+		return nil, errors.New("invalid GPS coordinates")
+	}
+
+	return location, nil
+}
@@ -0,0 +1,94 @@
+package utils
+
+import (
+	"github.com/dsoprea/go-exif/v3"
+	exifcommon "github.com/dsoprea/go-exif/v3/common"
+	"io/ioutil"
+)
+
+// ExifTechnical is the exposure information of an image, nil fields were not in the EXIF
+type ExifTechnical struct {
+	ExposureTime *float64 // seconds
+	FNumber      *float64
+	ISO          *int
+	FocalLength  *float64 // millimeters
+	LensModel    string
+	Flash        *int // raw Flash tag, bit 0 is set when the flash fired
+	WhiteBalance *int // 0 auto, 1 manual
+}
+
+// GetExifTechnical returns the exposure time, aperture, ISO, focal length, lens, flash and
+// white balance from the EXIF data of an image file
+func GetExifTechnical(filepath string) (*ExifTechnical, error) {
+
+	data, err := ioutil.ReadFile(filepath)
+	if err != nil {
+		return nil, err
+	}
+
+	rawExif, err := exif.SearchAndExtractExif(data)
+	if err != nil {
+		return nil, err
+	}
+
+	tags, _, err := exif.GetFlatExifData(rawExif, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	technical := &ExifTechnical{}
+	for _, tag := range tags {
+		switch tag.TagName {
+		case "ExposureTime":
+			technical.ExposureTime = rationalValue(tag.Value)
+		case "FNumber":
+			technical.FNumber = rationalValue(tag.Value)
+		case "FocalLength":
+			technical.FocalLength = rationalValue(tag.Value)
+		case "ISOSpeedRatings", "PhotographicSensitivity":
+			technical.ISO = shortValue(tag.Value)
+		case "Flash":
+			technical.Flash = shortValue(tag.Value)
+		case "WhiteBalance":
+			technical.WhiteBalance = shortValue(tag.Value)
+		case "LensModel":
+			if s, ok := tag.Value.(string); ok {
+				technical.LensModel = sanitizeString(s)
+			}
+		}
+	}
+
+	return technical, nil
+}
+
+func rationalValue(value interface{}) *float64 {
+	switch v := value.(type) {
+	case []exifcommon.Rational:
+		if len(v) > 0 && v[0].Denominator != 0 {
+			f := float64(v[0].Numerator) / float64(v[0].Denominator)
+			return &f
+		}
+	case []exifcommon.SignedRational:
+		if len(v) > 0 && v[0].Denominator != 0 {
+			f := float64(v[0].Numerator) / float64(v[0].Denominator)
+			return &f
+		}
+	}
+	return nil
+}
+
+func shortValue(value interface{}) *int {
+	switch v := value.(type) {
+	case []uint16:
+		if len(v) > 0 {
+			i := int(v[0])
+			return &i
+		}
+	case []uint32:
+		if len(v) > 0 {
+			i := int(v[0])
+			return &i
+		}
+	}
+	return nil
+}
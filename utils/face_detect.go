@@ -0,0 +1,286 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/disintegration/imaging"
+	"image"
+	"image/jpeg"
+	"math"
+	"os/exec"
+	"sort"
+	"time"
+)
+
+// DetectedFace is a face found in an image, as a bounding box normalized to the image: X and Y
+// are the top left corner, all values are 0 to 1. Embedding is empty when the detector cannot
+// tell faces apart.
+type DetectedFace struct {
+	X          float64   `json:"x"`
+	Y          float64   `json:"y"`
+	Width      float64   `json:"width"`
+	Height     float64   `json:"height"`
+	Confidence float64   `json:"confidence"`
+	Embedding  []float32 `json:"embedding,omitempty"`
+}
+
+// FaceDetector finds the faces of a decoded image
+type FaceDetector interface {
+	Detect(img image.Image) ([]DetectedFace, error)
+}
+
+// EmbeddingDistance returns the Euclidean distance between two embeddings, or +Inf when their
+// sizes differ
+func EmbeddingDistance(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return math.Inf(1)
+	}
+
+	var sum float64
+	for i := range a {
+		d := float64(a[i]) - float64(b[i])
+		sum += d * d
+	}
+	return math.Sqrt(sum)
+}
+
+// NormalizeEmbedding scales an embedding to unit length, so that distances between embeddings of
+// any detector are between 0 and 2
+func NormalizeEmbedding(embedding []float32) []float32 {
+	var sum float64
+	for _, v := range embedding {
+		sum += float64(v) * float64(v)
+	}
+	if sum == 0 {
+		return embedding
+	}
+
+	norm := math.Sqrt(sum)
+	normalized := make([]float32, len(embedding))
+	for i, v := range embedding {
+		normalized[i] = float32(float64(v) / norm)
+	}
+	return normalized
+}
+
+const (
+	skinDetectSize   = 160   // longest side of the image the skin mask is computed on
+	skinMinArea      = 0.005 // smallest face, relative to the image area
+	skinMinFill      = 0.4   // share of the bounding box covered by skin
+	skinMaxFaces     = 10
+	skinEmbedSize    = 16 // side of the grayscale patch used as embedding
+	skinMinAspect    = 0.8
+	skinMaxAspect    = 2.0
+	skinMinLuminance = 40
+)
+
+// SkinToneDetector is the reference FaceDetector: it looks for blobs of skin tone with the shape
+// of a face, and embeds them as a small contrast normalized grayscale patch. It needs no model
+// and is good enough to exercise the pipeline offline, not to recognize people reliably.
+type SkinToneDetector struct{}
+
+func (SkinToneDetector) Detect(img image.Image) ([]DetectedFace, error) {
+	small := imaging.Fit(img, skinDetectSize, skinDetectSize, imaging.Box)
+	width, height := small.Bounds().Dx(), small.Bounds().Dy()
+	if width == 0 || height == 0 {
+		return nil, nil
+	}
+
+	mask := make([]bool, width*height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			offset := small.PixOffset(x, y)
+			mask[y*width+x] = isSkin(small.Pix[offset], small.Pix[offset+1], small.Pix[offset+2])
+		}
+	}
+
+	faces := []DetectedFace{}
+	visited := make([]bool, width*height)
+	for start := range mask {
+		if !mask[start] || visited[start] {
+			continue
+		}
+
+		// Flood fill the blob, keeping its bounding box
+		area := 0
+		minX, minY, maxX, maxY := width, height, 0, 0
+		stack := []int{start}
+		visited[start] = true
+		for len(stack) > 0 {
+			p := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			x, y := p%width, p/width
+			area++
+			minX, minY = min(minX, x), min(minY, y)
+			maxX, maxY = max(maxX, x), max(maxY, y)
+
+			for _, n := range [4][2]int{{x - 1, y}, {x + 1, y}, {x, y - 1}, {x, y + 1}} {
+				if n[0] < 0 || n[1] < 0 || n[0] >= width || n[1] >= height {
+					continue
+				}
+				q := n[1]*width + n[0]
+				if mask[q] && !visited[q] {
+					visited[q] = true
+					stack = append(stack, q)
+				}
+			}
+		}
+
+		boxWidth, boxHeight := maxX-minX+1, maxY-minY+1
+		aspect := float64(boxHeight) / float64(boxWidth)
+		fill := float64(area) / float64(boxWidth*boxHeight)
+		if float64(area) < skinMinArea*float64(width*height) || aspect < skinMinAspect || aspect > skinMaxAspect || fill < skinMinFill {
+			continue
+		}
+
+		faces = append(faces, DetectedFace{
+			X:          float64(minX) / float64(width),
+			Y:          float64(minY) / float64(height),
+			Width:      float64(boxWidth) / float64(width),
+			Height:     float64(boxHeight) / float64(height),
+			Confidence: fill,
+		})
+	}
+
+	// Biggest faces first
+	sort.Slice(faces, func(i, j int) bool {
+		return faces[i].Width*faces[i].Height > faces[j].Width*faces[j].Height
+	})
+	if len(faces) > skinMaxFaces {
+		faces = faces[:skinMaxFaces]
+	}
+
+	for i := range faces {
+		faces[i].Embedding = patchEmbedding(img, faces[i])
+	}
+
+	return faces, nil
+}
+
+// isSkin tells whether a color is in the usual skin range of the YCbCr space
+func isSkin(r, g, b uint8) bool {
+	fr, fg, fb := float64(r), float64(g), float64(b)
+	luma := 0.299*fr + 0.587*fg + 0.114*fb
+	cb := 128 - 0.168736*fr - 0.331264*fg + 0.5*fb
+	cr := 128 + 0.5*fr - 0.418688*fg - 0.081312*fb
+	return luma > skinMinLuminance && cb >= 77 && cb <= 127 && cr >= 133 && cr <= 173
+}
+
+// patchEmbedding crops the face out of the full size image and turns it into a unit length
+// vector of mean subtracted grayscale values
+func patchEmbedding(img image.Image, face DetectedFace) []float32 {
+	bounds := img.Bounds()
+	rect := image.Rect(
+		int(face.X*float64(bounds.Dx())), int(face.Y*float64(bounds.Dy())),
+		int((face.X+face.Width)*float64(bounds.Dx())), int((face.Y+face.Height)*float64(bounds.Dy())),
+	).Add(bounds.Min).Intersect(bounds)
+	if rect.Empty() {
+		return nil
+	}
+
+	patch := imaging.Grayscale(imaging.Resize(CropImage(img, rect), skinEmbedSize, skinEmbedSize, imaging.Box))
+
+	embedding := make([]float32, 0, skinEmbedSize*skinEmbedSize)
+	var mean float32
+	for y := 0; y < skinEmbedSize; y++ {
+		for x := 0; x < skinEmbedSize; x++ {
+			v := float32(patch.Pix[patch.PixOffset(x, y)])
+			embedding = append(embedding, v)
+			mean += v
+		}
+	}
+	mean /= float32(len(embedding))
+	for i := range embedding {
+		embedding[i] -= mean
+	}
+
+	return NormalizeEmbedding(embedding)
+}
+
+// processDetectTimeout bounds a single call of an external detector
+const processDetectTimeout = time.Minute
+
+// processDetectSize is the longest side of the image sent to an external detector
+const processDetectSize = 1280
+
+// ProcessDetector runs an external local program for every image. The program gets a JSON
+// object on stdin:
+//
+//	{"image": "<base64 JPEG>", "width": 1280, "height": 960}
+//
+// and answers on stdout with the faces, normalized like DetectedFace:
+//
+//	{"faces": [{"x": 0.1, "y": 0.2, "width": 0.1, "height": 0.15, "confidence": 0.98, "embedding": [...]}]}
+//
+// or with {"error": "..."} when it fails.
+type ProcessDetector struct {
+	Command string
+	Args    []string
+}
+
+type processDetectRequest struct {
+	Image  string `json:"image"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+type processDetectResponse struct {
+	Faces []DetectedFace `json:"faces"`
+	Error string         `json:"error"`
+}
+
+// NewProcessDetector returns a detector running a command line, e.g. "python3 detect.py"
+func NewProcessDetector(command string, args ...string) *ProcessDetector {
+	return &ProcessDetector{Command: command, Args: args}
+}
+
+func (d *ProcessDetector) Detect(img image.Image) ([]DetectedFace, error) {
+	resized := imaging.Fit(img, processDetectSize, processDetectSize, imaging.Lanczos)
+
+	var encoded bytes.Buffer
+	if err := jpeg.Encode(&encoded, resized, &jpeg.Options{Quality: 90}); err != nil {
+		return nil, fmt.Errorf("encode image: %w", err)
+	}
+
+	request, err := json.Marshal(processDetectRequest{
+		Image:  base64.StdEncoding.EncodeToString(encoded.Bytes()),
+		Width:  resized.Bounds().Dx(),
+		Height: resized.Bounds().Dy(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), processDetectTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, d.Command, d.Args...)
+	cmd.Stdin = bytes.NewReader(request)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("run %s: %w: %s", d.Command, err, bytes.TrimSpace(stderr.Bytes()))
+	}
+
+	var response processDetectResponse
+	if err := json.Unmarshal(stdout.Bytes(), &response); err != nil {
+		return nil, fmt.Errorf("decode %s output: %w", d.Command, err)
+	}
+	if response.Error != "" {
+		return nil, errors.New(response.Error)
+	}
+
+	for i := range response.Faces {
+		if len(response.Faces[i].Embedding) > 0 {
+			response.Faces[i].Embedding = NormalizeEmbedding(response.Faces[i].Embedding)
+		}
+	}
+
+	return response.Faces, nil
+}
@@ -0,0 +1,256 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"math"
+	"os"
+	"strings"
+	"testing"
+)
+
+var (
+	testSkin       = color.RGBA{R: 220, G: 170, B: 140, A: 255}
+	testBackground = color.RGBA{R: 30, G: 60, B: 200, A: 255}
+	testEye        = color.RGBA{R: 20, G: 20, B: 20, A: 255}
+)
+
+// syntheticImage draws skin colored rectangles with two dark eyes on a blue background
+func syntheticImage(width, height int, faces ...image.Rectangle) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), &image.Uniform{C: testBackground}, image.Point{}, draw.Src)
+	for _, face := range faces {
+		draw.Draw(img, face, &image.Uniform{C: testSkin}, image.Point{}, draw.Src)
+
+		eye := image.Rect(0, 0, max(face.Dx()/8, 1), max(face.Dy()/10, 1))
+		top := face.Min.Y + face.Dy()/3
+		draw.Draw(img, eye.Add(image.Pt(face.Min.X+face.Dx()/4, top)), &image.Uniform{C: testEye}, image.Point{}, draw.Src)
+		draw.Draw(img, eye.Add(image.Pt(face.Min.X+face.Dx()*5/8, top)), &image.Uniform{C: testEye}, image.Point{}, draw.Src)
+	}
+	return img
+}
+
+func TestSkinToneDetectorDetect(t *testing.T) {
+	tests := []struct {
+		name  string
+		faces []image.Rectangle
+		want  []image.Rectangle // expected boxes, biggest first
+	}{
+		{
+			name: "no skin",
+		},
+		{
+			name:  "one face",
+			faces: []image.Rectangle{image.Rect(100, 60, 160, 140)},
+			want:  []image.Rectangle{image.Rect(100, 60, 160, 140)},
+		},
+		{
+			name:  "two faces biggest first",
+			faces: []image.Rectangle{image.Rect(20, 20, 60, 70), image.Rect(180, 40, 260, 140)},
+			want:  []image.Rectangle{image.Rect(180, 40, 260, 140), image.Rect(20, 20, 60, 70)},
+		},
+		{
+			name:  "too wide",
+			faces: []image.Rectangle{image.Rect(20, 100, 300, 140)},
+		},
+		{
+			name:  "too tall",
+			faces: []image.Rectangle{image.Rect(150, 0, 170, 240)},
+		},
+		{
+			name:  "too small",
+			faces: []image.Rectangle{image.Rect(150, 100, 154, 106)},
+		},
+	}
+
+	const width, height = 320, 240
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			faces, err := SkinToneDetector{}.Detect(syntheticImage(width, height, tt.faces...))
+			if err != nil {
+				t.Fatalf("Detect: %v", err)
+			}
+			if len(faces) != len(tt.want) {
+				t.Fatalf("got %d faces, want %d: %+v", len(faces), len(tt.want), faces)
+			}
+
+			for i, face := range faces {
+				want := tt.want[i]
+				got := [4]float64{face.X, face.Y, face.Width, face.Height}
+				expected := [4]float64{
+					float64(want.Min.X) / width, float64(want.Min.Y) / height,
+					float64(want.Dx()) / width, float64(want.Dy()) / height,
+				}
+				for j := range got {
+					if math.Abs(got[j]-expected[j]) > 0.02 {
+						t.Errorf("face %d box = %v, want %v", i, got, expected)
+						break
+					}
+				}
+
+				if face.Confidence < skinMinFill || face.Confidence > 1 {
+					t.Errorf("face %d confidence = %v", i, face.Confidence)
+				}
+				if len(face.Embedding) != skinEmbedSize*skinEmbedSize {
+					t.Errorf("face %d embedding has %d values", i, len(face.Embedding))
+				}
+				if norm := EmbeddingDistance(face.Embedding, make([]float32, len(face.Embedding))); math.Abs(norm-1) > 1e-4 {
+					t.Errorf("face %d embedding norm = %v", i, norm)
+				}
+			}
+		})
+	}
+}
+
+func TestSkinToneDetectorEmbedding(t *testing.T) {
+	// The same face at another place and scale embeds close, another pattern far
+	face := image.Rect(40, 40, 100, 120)
+	moved := image.Rect(180, 60, 270, 180)
+
+	a, err := SkinToneDetector{}.Detect(syntheticImage(320, 240, face))
+	if err != nil || len(a) != 1 {
+		t.Fatalf("Detect = %v, %v", a, err)
+	}
+	b, err := SkinToneDetector{}.Detect(syntheticImage(320, 240, moved))
+	if err != nil || len(b) != 1 {
+		t.Fatalf("Detect = %v, %v", b, err)
+	}
+
+	if d := EmbeddingDistance(a[0].Embedding, b[0].Embedding); d > 0.6 {
+		t.Errorf("distance of the same face = %v", d)
+	}
+
+	flipped := make([]float32, len(a[0].Embedding))
+	for i, v := range a[0].Embedding {
+		flipped[i] = -v
+	}
+	if d := EmbeddingDistance(a[0].Embedding, flipped); math.Abs(d-2) > 1e-4 {
+		t.Errorf("distance of opposite embeddings = %v, want 2", d)
+	}
+}
+
+func TestEmbeddingDistance(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []float32
+		want float64
+	}{
+		{"equal", []float32{1, 2, 3}, []float32{1, 2, 3}, 0},
+		{"3-4-5", []float32{0, 0}, []float32{3, 4}, 5},
+		{"unit axes", []float32{1, 0}, []float32{0, 1}, math.Sqrt2},
+		{"sizes differ", []float32{1, 2}, []float32{1, 2, 3}, math.Inf(1)},
+		{"empty", nil, nil, math.Inf(1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := EmbeddingDistance(tt.a, tt.b)
+			if math.IsInf(tt.want, 1) {
+				if !math.IsInf(got, 1) {
+					t.Errorf("EmbeddingDistance = %v, want +Inf", got)
+				}
+				return
+			}
+			if math.Abs(got-tt.want) > 1e-6 {
+				t.Errorf("EmbeddingDistance = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestHelperProcess is not a test, it is the external detector started by TestProcessDetector
+func TestHelperProcess(t *testing.T) {
+	mode := os.Getenv("FACE_DETECT_HELPER")
+	if mode == "" {
+		return
+	}
+	defer os.Exit(0)
+
+	var request processDetectRequest
+	if err := json.NewDecoder(os.Stdin).Decode(&request); err != nil {
+		fmt.Fprintf(os.Stderr, "decode request: %v", err)
+		os.Exit(2)
+	}
+
+	switch mode {
+	case "faces":
+		data, err := base64.StdEncoding.DecodeString(request.Image)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "decode image: %v", err)
+			os.Exit(2)
+		}
+		img, err := jpeg.Decode(bytes.NewReader(data))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "decode JPEG: %v", err)
+			os.Exit(2)
+		}
+		if img.Bounds().Dx() != request.Width || img.Bounds().Dy() != request.Height {
+			fmt.Fprintf(os.Stderr, "image is %v, request says %dx%d", img.Bounds(), request.Width, request.Height)
+			os.Exit(2)
+		}
+
+		json.NewEncoder(os.Stdout).Encode(processDetectResponse{Faces: []DetectedFace{
+			{X: 0.1, Y: 0.2, Width: 0.3, Height: 0.4, Confidence: 0.9, Embedding: []float32{3, 4}},
+			{X: 0.5, Y: 0.5, Width: 0.1, Height: 0.1, Confidence: 0.5},
+		}})
+	case "error":
+		json.NewEncoder(os.Stdout).Encode(processDetectResponse{Error: "no model loaded"})
+	case "garbage":
+		fmt.Fprint(os.Stdout, "not json")
+	case "crash":
+		fmt.Fprint(os.Stderr, "out of memory")
+		os.Exit(3)
+	}
+}
+
+func TestProcessDetector(t *testing.T) {
+	img := syntheticImage(2000, 1000)
+
+	tests := []struct {
+		mode    string
+		wantErr string
+	}{
+		{mode: "faces"},
+		{mode: "error", wantErr: "no model loaded"},
+		{mode: "garbage", wantErr: "decode"},
+		{mode: "crash", wantErr: "out of memory"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			t.Setenv("FACE_DETECT_HELPER", tt.mode)
+			detector := NewProcessDetector(os.Args[0], "-test.run=^TestHelperProcess$")
+
+			faces, err := detector.Detect(img)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Detect error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Detect: %v", err)
+			}
+
+			if len(faces) != 2 {
+				t.Fatalf("got %d faces, want 2", len(faces))
+			}
+			if faces[0].X != 0.1 || faces[0].Y != 0.2 || faces[0].Width != 0.3 || faces[0].Height != 0.4 || faces[0].Confidence != 0.9 {
+				t.Errorf("face 0 = %+v", faces[0])
+			}
+			// Embeddings come back unit length
+			if len(faces[0].Embedding) != 2 || math.Abs(float64(faces[0].Embedding[0])-0.6) > 1e-6 || math.Abs(float64(faces[0].Embedding[1])-0.8) > 1e-6 {
+				t.Errorf("face 0 embedding = %v, want [0.6 0.8]", faces[0].Embedding)
+			}
+			if len(faces[1].Embedding) != 0 {
+				t.Errorf("face 1 embedding = %v, want none", faces[1].Embedding)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"github.com/disintegration/imaging"
+	"image"
+	"image/color"
+	"io"
+	"os"
+
+	_ "golang.org/x/image/webp"
+)
+
+// ErrUnknownFormat is returned when the magic bytes of a file match no supported format
+var ErrUnknownFormat = errors.New("unknown file format")
+
+var contentTypes = map[string]string{
+	"jpg":  "image/jpeg",
+	"png":  "image/png",
+	"gif":  "image/gif",
+	"webp": "image/webp",
+	"heic": "image/heic",
+	"mp4":  "video/mp4",
+	"mov":  "video/quicktime",
+}
+
+var heifBrands = []string{"heic", "heix", "hevc", "hevx", "heim", "heis", "hevm", "hevs", "mif1", "msf1"}
+
+// mp4Brands are the major brands of the ftyp box of MP4 videos
+var mp4Brands = []string{"isom", "iso2", "iso3", "iso4", "iso5", "iso6", "mp41", "mp42", "avc1", "M4V ", "dash"}
+
+// DetectFormat identifies a file by its magic bytes and returns its format and media type
+func DetectFormat(filePath string) (format string, mediaType string, err error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return "", "", err
+	}
+	defer f.Close()
+
+	header := make([]byte, 16)
+	n, err := io.ReadFull(f, header)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return "", "", err
+	}
+	header = header[:n]
+
+	format = detectFormat(header)
+	switch format {
+	case "":
+		return "", "", ErrUnknownFormat
+	case "mp4", "mov":
+		return format, "video", nil
+	default:
+		return format, "image", nil
+	}
+}
+
+func detectFormat(header []byte) string {
+	switch {
+	case bytes.HasPrefix(header, []byte{0xFF, 0xD8, 0xFF}):
+		return "jpg"
+	case bytes.HasPrefix(header, []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1A, '\n'}):
+		return "png"
+	case bytes.HasPrefix(header, []byte("GIF87a")), bytes.HasPrefix(header, []byte("GIF89a")):
+		return "gif"
+	case len(header) >= 12 && string(header[0:4]) == "RIFF" && string(header[8:12]) == "WEBP":
+		return "webp"
+	case len(header) >= 12 && string(header[4:8]) == "ftyp":
+		brand := string(header[8:12])
+		for _, heif := range heifBrands {
+			if brand == heif {
+				return "heic"
+			}
+		}
+		if brand == "qt  " {
+			return "mov"
+		}
+		for _, mp4 := range mp4Brands {
+			if brand == mp4 {
+				return "mp4"
+			}
+		}
+		// Other ISO media such as 3GP, AVIF or JPEG 2000 are not supported
+		return ""
+	}
+	return ""
+}
+
+// ContentTypeOf returns the MIME type of a format returned by DetectFormat
+func ContentTypeOf(format string) string {
+	if contentType, ok := contentTypes[format]; ok {
+		return contentType
+	}
+	return "application/octet-stream"
+}
+
+// DetectContentType returns the MIME type of a file from its magic bytes
+func DetectContentType(filePath string) string {
+	format, _, err := DetectFormat(filePath)
+	if err != nil {
+		return "application/octet-stream"
+	}
+	return ContentTypeOf(format)
+}
+
+// PlaceholderImage returns a blank frame used as the thumbnail source of assets
+// that cannot be decoded, keeping their aspect ratio
+func PlaceholderImage(width, height int) image.Image {
+	w, h := 540, 540
+	if width > 0 && height > 0 {
+		h = w * height / width
+	}
+	return imaging.New(w, h, color.NRGBA{R: 0x20, G: 0x20, B: 0x20, A: 0xff})
+}
@@ -0,0 +1,116 @@
+package utils
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const earthRadiusKm = 6371.0
+
+// maxCityDistanceKm is how far from a city an asset can be and still be named after it
+const maxCityDistanceKm = 100.0
+
+var loadCitiesOnce sync.Once
+
+// BoundingBox is an area between two latitudes and two longitudes. When MinLongitude
+// is greater than MaxLongitude the box crosses the antimeridian.
+type BoundingBox struct {
+	MinLatitude  float64
+	MinLongitude float64
+	MaxLatitude  float64
+	MaxLongitude float64
+}
+
+// HaversineKm returns the great circle distance between two coordinates in kilometers
+func HaversineKm(lat1, lng1, lat2, lng2 float64) float64 {
+	dLat := (lat2 - lat1) * math.Pi / 180
+	dLng := (lng2 - lng1) * math.Pi / 180
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*math.Pi/180)*math.Cos(lat2*math.Pi/180)*math.Sin(dLng/2)*math.Sin(dLng/2)
+
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(a))
+}
+
+// NearestCity reverse geocodes a coordinate against the cities loaded by GetCities, see City for
+// the fields they need. Cities without coordinates are ignored and nil is returned when no city
+// is near.
+func NearestCity(latitude, longitude float64) *City {
+	loadCitiesOnce.Do(func() {
+		if len(cities) == 0 {
+			GetCities()
+		}
+	})
+
+	var nearest *City
+	nearestDistance := maxCityDistanceKm
+
+	for i := range cities {
+		city := &cities[i]
+		if city.Latitude == 0 && city.Longitude == 0 {
+			continue
+		}
+
+		distance := HaversineKm(latitude, longitude, city.Latitude, city.Longitude)
+		if distance <= nearestDistance {
+			nearest = city
+			nearestDistance = distance
+		}
+	}
+
+	return nearest
+}
+
+// ParseBoundingBox parses "minLng,minLat,maxLng,maxLat", the order used by map clients
+func ParseBoundingBox(value string) (BoundingBox, error) {
+	parts := strings.Split(value, ",")
+	if len(parts) != 4 {
+		return BoundingBox{}, errors.New("bbox must be minLng,minLat,maxLng,maxLat")
+	}
+
+	var numbers [4]float64
+	for i, part := range parts {
+		number, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return BoundingBox{}, errors.New("bbox must contain numbers")
+		}
+		numbers[i] = number
+	}
+
+	box := BoundingBox{
+		MinLongitude: numbers[0],
+		MinLatitude:  numbers[1],
+		MaxLongitude: numbers[2],
+		MaxLatitude:  numbers[3],
+	}
+
+	if box.MinLatitude > box.MaxLatitude || box.MinLatitude < -90 || box.MaxLatitude > 90 ||
+		box.MinLongitude < -180 || box.MaxLongitude > 180 {
+		return BoundingBox{}, errors.New("bbox is out of range")
+	}
+
+	return box, nil
+}
+
+// ParseCoordinate parses "lat,lng"
+func ParseCoordinate(value string) (float64, float64, error) {
+	parts := strings.Split(value, ",")
+	if len(parts) != 2 {
+		return 0, 0, errors.New("coordinate must be lat,lng")
+	}
+
+	latitude, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	if err != nil || latitude < -90 || latitude > 90 {
+		return 0, 0, errors.New("invalid latitude")
+	}
+
+	longitude, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	if err != nil || longitude < -180 || longitude > 180 {
+		return 0, 0, errors.New("invalid longitude")
+	}
+
+	return latitude, longitude, nil
+}
@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+)
+
+// HeifMetadata is the image information stored in the boxes of a HEIF container
+type HeifMetadata struct {
+	PixelWidth  int // display width, rotation applied
+	PixelHeight int // display height, rotation applied
+	Rotation    int // degrees counter clockwise
+}
+
+// ReadHeifMetadata reads the dimensions of the primary image of a HEIF/HEIC file.
+// The HEVC coded image itself is not decoded. EXIF is found by PhotoHasExifData
+// and ReadExifData since they search the whole file.
+func ReadHeifMetadata(filePath string) (*HeifMetadata, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	meta, err := findTopLevelBox(f, "meta")
+	if err != nil {
+		return nil, err
+	}
+	if len(meta) < 4 {
+		return nil, errors.New("invalid meta box")
+	}
+
+	var result HeifMetadata
+
+	// meta is a full box, skip version and flags
+	for _, box := range readBoxes(meta[4:]) {
+		if box.kind != "iprp" {
+			continue
+		}
+		for _, child := range readBoxes(box.data) {
+			if child.kind != "ipco" {
+				continue
+			}
+			for _, property := range readBoxes(child.data) {
+				switch property.kind {
+				case "ispe":
+					// version/flags(4) width(4) height(4), the primary image or grid is the largest
+					if len(property.data) < 12 {
+						continue
+					}
+					width := int(binary.BigEndian.Uint32(property.data[4:8]))
+					height := int(binary.BigEndian.Uint32(property.data[8:12]))
+					if width*height > result.PixelWidth*result.PixelHeight {
+						result.PixelWidth = width
+						result.PixelHeight = height
+					}
+				case "irot":
+					if len(property.data) >= 1 {
+						result.Rotation = int(property.data[0]&0x03) * 90
+					}
+				}
+			}
+		}
+	}
+
+	if result.PixelWidth == 0 || result.PixelHeight == 0 {
+		return nil, errors.New("no image dimensions found")
+	}
+
+	if result.Rotation == 90 || result.Rotation == 270 {
+		result.PixelWidth, result.PixelHeight = result.PixelHeight, result.PixelWidth
+	}
+
+	return &result, nil
+}
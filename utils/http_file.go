@@ -0,0 +1,114 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/mahdi-cpp/PhotoKit/storage"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"time"
+)
+
+// Cache-Control of the served files
+const (
+	// CacheImmutable is for files that never change under their URL, such as thumbnail variants
+	CacheImmutable = "private, max-age=31536000, immutable"
+	// CacheRevalidate lets clients keep a file but ask with its ETag before using it
+	CacheRevalidate = "private, no-cache"
+)
+
+// PresignTTL is how long the download URLs of stores that sign URLs stay valid
+const PresignTTL = 15 * time.Minute
+
+// FileETag returns a strong ETag of a file from its modification time and size
+func FileETag(info os.FileInfo) string {
+	return fmt.Sprintf(`"%x-%x"`, info.ModTime().UnixNano(), info.Size())
+}
+
+// ContentETag returns a strong ETag from the SHA-256 of a file
+func ContentETag(contentHash string) string {
+	return `"` + contentHash + `"`
+}
+
+// ServeFile serves a file with its ETag, Last-Modified and Cache-Control. It answers
+// If-None-Match and If-Modified-Since with 304 and single and multiple byte ranges with 206.
+// An empty etag is made from the modification time and size of the file.
+func ServeFile(c *gin.Context, path string, etag string, cacheControl string) {
+	file, err := os.Open(path)
+	if err != nil {
+		SendError(c, http.StatusNotFound, "File not found")
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil || info.IsDir() {
+		SendError(c, http.StatusNotFound, "File not found")
+		return
+	}
+
+	if etag == "" {
+		etag = FileETag(info)
+	}
+	if c.Writer.Header().Get("Content-Type") == "" {
+		c.Header("Content-Type", DetectContentType(path))
+	}
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", cacheControl)
+
+	http.ServeContent(c.Writer, c.Request, info.Name(), info.ModTime(), file)
+}
+
+// ServeBytes serves a file held in memory like ServeFile
+func ServeBytes(c *gin.Context, contentType string, data []byte, modTime time.Time, etag string, cacheControl string) {
+	c.Header("Content-Type", contentType)
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", cacheControl)
+
+	http.ServeContent(c.Writer, c.Request, "", modTime, bytes.NewReader(data))
+}
+
+// ServeBlob serves a blob like ServeFile, an empty etag is the ETag of the store. Stores that sign
+// URLs send the client to the blob instead, they answer conditional and range requests themselves.
+func ServeBlob(c *gin.Context, store storage.BlobStore, key string, etag string, cacheControl string) {
+	if url, err := store.Presign(key, PresignTTL); err == nil {
+		// The URL expires, clients ask again instead of keeping it
+		c.Header("Cache-Control", "no-store")
+		c.Redirect(http.StatusFound, url)
+		return
+	}
+
+	body, info, err := store.Get(key)
+	if err != nil {
+		if errors.Is(err, storage.ErrBlobNotFound) {
+			SendError(c, http.StatusNotFound, "File not found")
+		} else {
+			SendError(c, http.StatusInternalServerError, "Failed to read file")
+		}
+		return
+	}
+	defer body.Close()
+
+	// Ranges need to seek, blobs of stores that cannot are read into memory
+	content, ok := body.(io.ReadSeeker)
+	if !ok {
+		data, err := io.ReadAll(body)
+		if err != nil {
+			SendError(c, http.StatusInternalServerError, "Failed to read file")
+			return
+		}
+		content = bytes.NewReader(data)
+	}
+
+	if etag == "" {
+		etag = info.ETag
+	}
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", cacheControl)
+
+	http.ServeContent(c.Writer, c.Request, path.Base(key), info.ModTime, content)
+}
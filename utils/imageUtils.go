@@ -7,17 +7,26 @@ import (
 	"github.com/disintegration/imaging"
 	"image"
 	"image/draw"
+	"log"
 	"math"
 	"os"
 )
 
 var root = "var/cloud/"
 
+// City is an entry of var/cloud/data/cities.json, the places NearestCity names assets after.
+// Reverse geocoding needs every entry to carry "latitude" and "longitude" in degrees and the
+// "country" it is in:
+//
+//	{"id": 1, "name": "Tehran", "slug": "tehran", "province_id": 8, "country": "Iran", "latitude": 35.6892, "longitude": 51.389}
 type City struct {
-	Id         int    `json:"id"`
-	Name       string `json:"name"`
-	Slug       string `json:"slug"`
-	ProvinceId int    `json:"province_id"`
+	Id         int     `json:"id"`
+	Name       string  `json:"name"`
+	Slug       string  `json:"slug"`
+	ProvinceId int     `json:"province_id"`
+	Country    string  `json:"country"`
+	Latitude   float64 `json:"latitude"`
+	Longitude  float64 `json:"longitude"`
 }
 
 var cities []City
@@ -82,6 +91,16 @@ func GetCities() {
 		fmt.Println("Error decoding JSON:", err)
 		return
 	}
+
+	located := 0
+	for _, city := range cities {
+		if city.Latitude != 0 || city.Longitude != 0 {
+			located++
+		}
+	}
+	if located == 0 {
+		log.Printf("Error: none of the %d cities of %s has a latitude and longitude, assets will not be named after places", len(cities), file)
+	}
 }
 
 // CropImage crops an image to the specified rectangle.
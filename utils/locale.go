@@ -0,0 +1,117 @@
+package utils
+
+import (
+	"github.com/gin-gonic/gin"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Languages with localized titles, the first one is the default
+var languages = []string{"en", "fa"}
+
+var persianWeekdays = []string{"یکشنبه", "دوشنبه", "سه‌شنبه", "چهارشنبه", "پنجشنبه", "جمعه", "شنبه"}
+
+var jalaliMonths = []string{"فروردین", "اردیبهشت", "خرداد", "تیر", "مرداد", "شهریور", "مهر", "آبان", "آذر", "دی", "بهمن", "اسفند"}
+
+// GetLanguage returns the language of a request from the lang query parameter or the Accept-Language header
+func GetLanguage(c *gin.Context) string {
+	candidates := []string{c.Query("lang")}
+	for _, tag := range strings.Split(c.GetHeader("Accept-Language"), ",") {
+		candidates = append(candidates, strings.SplitN(strings.TrimSpace(tag), ";", 2)[0])
+	}
+
+	for _, candidate := range candidates {
+		primary := strings.ToLower(strings.SplitN(candidate, "-", 2)[0])
+		for _, language := range languages {
+			if primary == language {
+				return language
+			}
+		}
+	}
+	return languages[0]
+}
+
+// DayTitle names a day relative to now: today, yesterday, the weekday during the last week, otherwise the date
+func DayTitle(day, now time.Time, language string) string {
+	day = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	daysAgo := int(today.Sub(day).Hours() / 24)
+
+	if language == "fa" {
+		switch {
+		case daysAgo == 0:
+			return "امروز"
+		case daysAgo == 1:
+			return "دیروز"
+		case daysAgo > 1 && daysAgo < 7:
+			return persianWeekdays[day.Weekday()]
+		}
+
+		jy, jm, jd := GregorianToJalali(day.Year(), int(day.Month()), day.Day())
+		title := PersianDigits(strconv.Itoa(jd)) + " " + jalaliMonths[jm-1]
+		if nowYear, _, _ := GregorianToJalali(today.Year(), int(today.Month()), today.Day()); jy != nowYear {
+			title += " " + PersianDigits(strconv.Itoa(jy))
+		}
+		return title
+	}
+
+	switch {
+	case daysAgo == 0:
+		return "Today"
+	case daysAgo == 1:
+		return "Yesterday"
+	case daysAgo > 1 && daysAgo < 7:
+		return day.Weekday().String()
+	case day.Year() == today.Year():
+		return day.Format("January 2")
+	}
+	return day.Format("January 2, 2006")
+}
+
+// GregorianToJalali converts a Gregorian date to the Persian (Jalali) calendar
+func GregorianToJalali(gy, gm, gd int) (int, int, int) {
+	monthDays := []int{0, 31, 59, 90, 120, 151, 181, 212, 243, 273, 304, 334}
+
+	gy2 := gy
+	if gm > 2 {
+		gy2 = gy + 1
+	}
+	days := 355666 + 365*gy + (gy2+3)/4 - (gy2+99)/100 + (gy2+399)/400 + gd + monthDays[gm-1]
+
+	jy := -1595 + 33*(days/12053)
+	days %= 12053
+	jy += 4 * (days / 1461)
+	days %= 1461
+	if days > 365 {
+		jy += (days - 1) / 365
+		days = (days - 1) % 365
+	}
+
+	if days < 186 {
+		return jy, 1 + days/31, 1 + days%31
+	}
+	return jy, 7 + (days-186)/30, 1 + (days-186)%30
+}
+
+// PersianDigits replaces the ASCII digits of a string with Persian digits
+func PersianDigits(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return '۰' + (r - '0')
+		}
+		return r
+	}, s)
+}
+
+// YearsAgoTitle names a memory from a past year: "1 Year Ago", "3 Years Ago"
+func YearsAgoTitle(years int, language string) string {
+	if language == "fa" {
+		return PersianDigits(strconv.Itoa(years)) + " سال پیش"
+	}
+
+	if years == 1 {
+		return "1 Year Ago"
+	}
+	return strconv.Itoa(years) + " Years Ago"
+}
@@ -0,0 +1,40 @@
+package utils
+
+import (
+	"github.com/disintegration/imaging"
+	"image"
+	"math/bits"
+)
+
+// DHash computes the 64 bit difference hash of an image. Similar images have
+// hashes with a small Hamming distance, whatever their size or JPEG quality.
+func DHash(img image.Image) uint64 {
+	small := imaging.Grayscale(imaging.Resize(img, 9, 8, imaging.Box))
+
+	var hash uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			left := small.Pix[small.PixOffset(x, y)]
+			right := small.Pix[small.PixOffset(x+1, y)]
+			hash <<= 1
+			if left > right {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// FileDHash opens an image file and computes its difference hash
+func FileDHash(imagePath string) (uint64, error) {
+	img, err := imaging.Open(imagePath)
+	if err != nil {
+		return 0, err
+	}
+	return DHash(img), nil
+}
+
+// HammingDistance returns the number of bits that differ between two hashes
+func HammingDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
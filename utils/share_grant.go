@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"crypto/subtle"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ShareGrantTTL is how long a protected share link stays unlocked after its password was checked
+var ShareGrantTTL = time.Hour
+
+// IssueShareGrant signs a grant to a protected share link, so that its thumbnails and originals
+// are served without checking the password again. The grant is bound to the current password
+// hash of the link, a new password ends it.
+func IssueShareGrant(token, passwordHash string) (string, time.Time) {
+	expiresAt := time.Now().Add(ShareGrantTTL)
+	expires := strconv.FormatInt(expiresAt.Unix(), 10)
+	return expires + "." + signToken(shareGrantPayload(token, passwordHash, expires)), expiresAt
+}
+
+// CheckShareGrant checks the signature and expiry of a grant to a share link
+func CheckShareGrant(grant, token, passwordHash string) error {
+	expires, signature, ok := strings.Cut(grant, ".")
+	if !ok {
+		return ErrInvalidToken
+	}
+
+	expected := signToken(shareGrantPayload(token, passwordHash, expires))
+	if subtle.ConstantTimeCompare([]byte(signature), []byte(expected)) != 1 {
+		return ErrInvalidToken
+	}
+
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() >= expiresAt {
+		return ErrInvalidToken
+	}
+
+	return nil
+}
+
+// shareGrantPayload is what a grant signs, the prefix keeps it apart from the access tokens
+// signed with the same key
+func shareGrantPayload(token, passwordHash, expires string) string {
+	return "share-grant\n" + token + "\n" + passwordHash + "\n" + expires
+}
@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// SMSSender delivers a text message to a phone number, an SMS gateway implements it in production
+type SMSSender interface {
+	Send(phoneNumber string, message string) error
+}
+
+// LogSender writes the messages to the log instead of sending them, for development
+type LogSender struct{}
+
+func (LogSender) Send(phoneNumber string, message string) error {
+	log.Printf("SMS to %s: %s", phoneNumber, message)
+	return nil
+}
+
+// FileSender appends the messages to a file instead of sending them, for development and tests
+// that read the codes back
+type FileSender struct {
+	Path string
+
+	mu sync.Mutex
+}
+
+// NewFileSender returns a sender appending to a file, the file is created when missing
+func NewFileSender(path string) *FileSender {
+	return &FileSender{Path: path}
+}
+
+func (s *FileSender) Send(phoneNumber string, message string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.OpenFile(s.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = fmt.Fprintf(file, "%s\t%s\t%s\n", time.Now().Format(time.RFC3339), phoneNumber, message)
+	return err
+}
@@ -0,0 +1,93 @@
+package utils
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Timeline granularities, a bucket key is the date cut to the granularity: "2024", "2024-03" or "2024-03-15"
+const (
+	GranularityYear  = "year"
+	GranularityMonth = "month"
+	GranularityDay   = "day"
+)
+
+var bucketLayouts = map[string]string{
+	GranularityYear:  "2006",
+	GranularityMonth: "2006-01",
+	GranularityDay:   "2006-01-02",
+}
+
+// TimelineBucket is the number of assets taken in one year, month or day
+type TimelineBucket struct {
+	Bucket string `json:"bucket"`
+	Count  int    `json:"count"`
+}
+
+// ValidGranularity reports whether the granularity is year, month or day
+func ValidGranularity(granularity string) bool {
+	_, ok := bucketLayouts[granularity]
+	return ok
+}
+
+// BucketOfDay returns the key of the bucket holding a day key in the YYYY-MM-DD format
+func BucketOfDay(dayKey string, granularity string) string {
+	return dayKey[:len(bucketLayouts[granularity])]
+}
+
+// ParseBucket returns the granularity of a bucket key and the time range it covers, end excluded
+func ParseBucket(bucket string) (string, time.Time, time.Time, error) {
+	for granularity, layout := range bucketLayouts {
+		if len(bucket) != len(layout) {
+			continue
+		}
+
+		start, err := time.Parse(layout, bucket)
+		if err != nil {
+			break
+		}
+
+		switch granularity {
+		case GranularityYear:
+			return granularity, start, start.AddDate(1, 0, 0), nil
+		case GranularityMonth:
+			return granularity, start, start.AddDate(0, 1, 0), nil
+		default:
+			return granularity, start, start.AddDate(0, 0, 1), nil
+		}
+	}
+	return "", time.Time{}, time.Time{}, errors.New("invalid bucket, expected YYYY, YYYY-MM or YYYY-MM-DD")
+}
+
+// EncodeCursor returns an opaque paging cursor pointing after the asset with the given date and ID
+func EncodeCursor(t time.Time, id int) string {
+	raw := strconv.FormatInt(t.UnixNano(), 10) + ":" + strconv.Itoa(id)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeCursor returns the creation date and ID stored in a cursor made by EncodeCursor
+func DecodeCursor(cursor string) (time.Time, int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, 0, errors.New("invalid cursor")
+	}
+
+	parts := strings.SplitN(string(raw), ":", 2)
+	if len(parts) != 2 {
+		return time.Time{}, 0, errors.New("invalid cursor")
+	}
+
+	nanos, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return time.Time{}, 0, errors.New("invalid cursor")
+	}
+	id, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return time.Time{}, 0, errors.New("invalid cursor")
+	}
+
+	return time.Unix(0, nanos).UTC(), id, nil
+}
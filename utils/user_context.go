@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strings"
+)
+
+// userIdKey is the key of the signed-in user in the gin context
+const userIdKey = "userId"
+
+// GetUserID returns the ID of the signed-in user the request is made for
+func GetUserID(c *gin.Context) (int, error) {
+	userId := c.GetInt(userIdKey)
+	if userId <= 0 {
+		return 0, errors.New("invalid user ID")
+	}
+	return userId, nil
+}
+
+// RequireAuth rejects requests without a valid access token and puts the user of the token into
+// the context. The token comes in the Authorization header as a bearer token, or in the
+// access_token query parameter for image and video elements that cannot send headers.
+func RequireAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := c.Query("access_token")
+		if header := c.GetHeader("Authorization"); strings.HasPrefix(header, "Bearer ") {
+			token = strings.TrimPrefix(header, "Bearer ")
+		}
+
+		userId, err := ParseAccessToken(token)
+		if err != nil {
+			c.Header("WWW-Authenticate", "Bearer")
+			c.AbortWithStatusJSON(http.StatusUnauthorized, ErrorResponse{Message: "Unauthorized"})
+			return
+		}
+
+		c.Set(userIdKey, userId)
+		c.Next()
+	}
+}
@@ -0,0 +1,283 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"os"
+	"time"
+)
+
+// maxMoovSize bounds the metadata box read into memory
+const maxMoovSize = 64 << 20
+
+// mp4Epoch is the origin of ISO-BMFF timestamps
+var mp4Epoch = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// VideoMetadata is the container metadata of an MP4/MOV file
+type VideoMetadata struct {
+	Duration     float64 // seconds
+	PixelWidth   int     // display width, rotation applied
+	PixelHeight  int     // display height, rotation applied
+	Rotation     int     // degrees clockwise
+	CreationTime time.Time
+	Cover        []byte // embedded cover art, nil when there is none
+}
+
+type mp4Box struct {
+	kind string
+	data []byte
+}
+
+// ReadVideoMetadata parses the mvhd and tkhd boxes of an ISO-BMFF (MP4/MOV) file
+func ReadVideoMetadata(filePath string) (*VideoMetadata, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	moov, err := findTopLevelBox(f, "moov")
+	if err != nil {
+		return nil, err
+	}
+
+	var meta VideoMetadata
+	for _, box := range readBoxes(moov) {
+		switch box.kind {
+		case "mvhd":
+			parseMvhd(box.data, &meta)
+		case "trak":
+			parseTrak(box.data, &meta)
+		case "udta":
+			if cover := findCover(box.data); cover != nil {
+				meta.Cover = cover
+			}
+		}
+	}
+
+	if meta.PixelWidth == 0 && meta.PixelHeight == 0 {
+		return nil, errors.New("no video track found")
+	}
+
+	return &meta, nil
+}
+
+// findTopLevelBox seeks through the file to a top level box and returns its payload
+func findTopLevelBox(r io.ReadSeeker, kind string) ([]byte, error) {
+	header := make([]byte, 16)
+	for {
+		if _, err := io.ReadFull(r, header[:8]); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return nil, fmt.Errorf("%s box not found", kind)
+			}
+			return nil, err
+		}
+
+		size := int64(binary.BigEndian.Uint32(header[:4]))
+		boxKind := string(header[4:8])
+		headerSize := int64(8)
+
+		switch size {
+		case 1:
+			if _, err := io.ReadFull(r, header[8:16]); err != nil {
+				return nil, err
+			}
+			size = int64(binary.BigEndian.Uint64(header[8:16]))
+			headerSize = 16
+		case 0:
+			// Box extends to the end of the file
+			current, err := r.Seek(0, io.SeekCurrent)
+			if err != nil {
+				return nil, err
+			}
+			end, err := r.Seek(0, io.SeekEnd)
+			if err != nil {
+				return nil, err
+			}
+			if _, err := r.Seek(current, io.SeekStart); err != nil {
+				return nil, err
+			}
+			size = end - current + headerSize
+		}
+
+		if size < headerSize {
+			return nil, errors.New("invalid box size")
+		}
+
+		if boxKind == kind {
+			if size-headerSize > maxMoovSize {
+				return nil, fmt.Errorf("%s box too large", kind)
+			}
+			data := make([]byte, size-headerSize)
+			if _, err := io.ReadFull(r, data); err != nil {
+				return nil, err
+			}
+			return data, nil
+		}
+
+		if _, err := r.Seek(size-headerSize, io.SeekCurrent); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// readBoxes splits a payload into its child boxes
+func readBoxes(data []byte) []mp4Box {
+	var boxes []mp4Box
+	for len(data) >= 8 {
+		size := uint64(binary.BigEndian.Uint32(data[:4]))
+		kind := string(data[4:8])
+		headerSize := uint64(8)
+
+		if size == 1 {
+			if len(data) < 16 {
+				break
+			}
+			size = binary.BigEndian.Uint64(data[8:16])
+			headerSize = 16
+		} else if size == 0 {
+			size = uint64(len(data))
+		}
+
+		if size < headerSize || size > uint64(len(data)) {
+			break
+		}
+
+		boxes = append(boxes, mp4Box{kind: kind, data: data[headerSize:size]})
+		data = data[size:]
+	}
+	return boxes
+}
+
+func parseMvhd(data []byte, meta *VideoMetadata) {
+	if len(data) < 4 {
+		return
+	}
+
+	var created, timescale, duration uint64
+	switch data[0] {
+	case 1:
+		if len(data) < 32 {
+			return
+		}
+		created = binary.BigEndian.Uint64(data[4:12])
+		timescale = uint64(binary.BigEndian.Uint32(data[20:24]))
+		duration = binary.BigEndian.Uint64(data[24:32])
+	default:
+		if len(data) < 20 {
+			return
+		}
+		created = uint64(binary.BigEndian.Uint32(data[4:8]))
+		timescale = uint64(binary.BigEndian.Uint32(data[12:16]))
+		duration = uint64(binary.BigEndian.Uint32(data[16:20]))
+	}
+
+	if timescale > 0 {
+		meta.Duration = float64(duration) / float64(timescale)
+	}
+	if created > 0 {
+		meta.CreationTime = mp4Epoch.Add(time.Duration(created) * time.Second)
+	}
+}
+
+func parseTrak(data []byte, meta *VideoMetadata) {
+	var tkhd []byte
+	isVideo := false
+
+	for _, box := range readBoxes(data) {
+		switch box.kind {
+		case "tkhd":
+			tkhd = box.data
+		case "mdia":
+			for _, child := range readBoxes(box.data) {
+				// hdlr: version/flags(4) pre_defined(4) handler_type(4)
+				if child.kind == "hdlr" && len(child.data) >= 12 && string(child.data[8:12]) == "vide" {
+					isVideo = true
+				}
+			}
+		}
+	}
+
+	// An empty tkhd box has no version byte
+	if !isVideo || len(tkhd) < 1 || meta.PixelWidth != 0 {
+		return
+	}
+
+	// Skip the times, track ID and duration which depend on the version
+	offset := 4 + 20
+	if tkhd[0] == 1 {
+		offset = 4 + 32
+	}
+	// reserved(8) layer(2) alternate_group(2) volume(2) reserved(2)
+	offset += 16
+	if len(tkhd) < offset+36+8 {
+		return
+	}
+
+	matrix := tkhd[offset : offset+36]
+	a := int32(binary.BigEndian.Uint32(matrix[0:4]))
+	b := int32(binary.BigEndian.Uint32(matrix[4:8]))
+
+	width := int(binary.BigEndian.Uint32(tkhd[offset+36:offset+40]) >> 16)
+	height := int(binary.BigEndian.Uint32(tkhd[offset+40:offset+44]) >> 16)
+
+	switch {
+	case a == 0 && b > 0:
+		meta.Rotation = 90
+	case a == 0 && b < 0:
+		meta.Rotation = 270
+	case a < 0:
+		meta.Rotation = 180
+	}
+
+	if meta.Rotation == 90 || meta.Rotation == 270 {
+		width, height = height, width
+	}
+	meta.PixelWidth = width
+	meta.PixelHeight = height
+}
+
+// findCover returns the image of udta/meta/ilst/covr, the iTunes style cover art
+func findCover(udta []byte) []byte {
+	for _, box := range readBoxes(udta) {
+		if box.kind != "meta" {
+			continue
+		}
+
+		// In MP4 meta is a full box, in QuickTime it is not
+		children := readBoxes(box.data)
+		if len(box.data) >= 4 && binary.BigEndian.Uint32(box.data[:4]) == 0 {
+			children = readBoxes(box.data[4:])
+		}
+
+		for _, child := range children {
+			if child.kind != "ilst" {
+				continue
+			}
+			for _, item := range readBoxes(child.data) {
+				if item.kind != "covr" {
+					continue
+				}
+				for _, value := range readBoxes(item.data) {
+					// data: type indicator(4) locale(4) payload
+					if value.kind == "data" && len(value.data) > 8 {
+						return value.data[8:]
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// DecodeCover decodes embedded cover art
+func DecodeCover(cover []byte) (image.Image, error) {
+	img, _, err := image.Decode(bytes.NewReader(cover))
+	return img, err
+}
@@ -0,0 +1,54 @@
+package utils
+
+import (
+	"github.com/disintegration/imaging"
+	"image"
+	"os"
+	"os/exec"
+)
+
+// VideoPoster returns the image used as the thumbnail of a video: the embedded
+// cover art, a frame extracted with ffmpeg when it is installed, or a blank
+// frame with the video's aspect ratio.
+func VideoPoster(filePath string, meta *VideoMetadata) image.Image {
+	if meta.Cover != nil {
+		if img, err := DecodeCover(meta.Cover); err == nil {
+			return img
+		}
+	}
+
+	if img, err := extractFrame(filePath, meta.Duration); err == nil {
+		return img
+	}
+
+	return PlaceholderImage(meta.PixelWidth, meta.PixelHeight)
+}
+
+// extractFrame grabs one frame of the video with ffmpeg, which applies the rotation itself
+func extractFrame(filePath string, duration float64) (image.Image, error) {
+	ffmpeg, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		return nil, err
+	}
+
+	tmp, err := os.CreateTemp("", "poster-*.jpg")
+	if err != nil {
+		return nil, err
+	}
+	tmpPath := tmp.Name()
+	tmp.Close()
+	defer os.Remove(tmpPath)
+
+	// Skip the first second, often black, when the video is long enough
+	seek := "0"
+	if duration > 2 {
+		seek = "1"
+	}
+
+	cmd := exec.Command(ffmpeg, "-loglevel", "error", "-ss", seek, "-i", filePath, "-frames:v", "1", "-y", tmpPath)
+	if err := cmd.Run(); err != nil {
+		return nil, err
+	}
+
+	return imaging.Open(tmpPath)
+}